@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"strings"
+)
+
+// addressVersion is the version byte prefixed to the public key hash of an address, 0x00 like Bitcoin
+// pay-to-pubkey-hash addresses, so every address starts with the character "1"
+const addressVersion byte = 0x00
+
+// addressChecksumLength is the number of bytes of the double SHA-256 checksum appended to an address
+const addressChecksumLength = 4
+
+// base58Alphabet is the Bitcoin Base58 alphabet: the digits and letters without 0, O, I and l,
+// which are easily mistaken for one another
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// EncodeAddress encodes a public key hash as a Base58Check address: the version byte, the hash and the first
+// 4 bytes of the double SHA-256 of both, in Base58. A mistyped character changes the checksum, so
+// ValidateAddress catches typos instead of letting funds go to an address nobody owns
+func EncodeAddress(pubKeyHash []byte) string {
+	payload := append([]byte{addressVersion}, pubKeyHash...)
+	return base58Encode(append(payload, addressChecksum(payload)...))
+}
+
+// ValidateAddress reports whether addr is a Base58Check address as produced by EncodeAddress for a public key hash
+// of addressLength bytes: it must decode as Base58, have the expected length and version and a matching checksum
+func ValidateAddress(addr string) bool {
+	data, ok := base58Decode(addr)
+	if !ok || len(data) != 1+addressLength+addressChecksumLength || data[0] != addressVersion {
+		return false
+	}
+
+	payload, checksum := data[:len(data)-addressChecksumLength], data[len(data)-addressChecksumLength:]
+	return bytes.Equal(checksum, addressChecksum(payload))
+}
+
+// addressChecksum returns the first addressChecksumLength bytes of sha256(sha256(payload))
+func addressChecksum(payload []byte) []byte {
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	return second[:addressChecksumLength]
+}
+
+// base58Encode encodes data in Base58, every leading zero byte becoming a leading "1"
+func base58Encode(data []byte) string {
+	zeros := 0
+	for zeros < len(data) && data[zeros] == 0 {
+		zeros++
+	}
+
+	// digits holds the base 58 digits of data, least significant first; log(256)/log(58) < 1.37
+	digits := make([]byte, 0, len(data)*137/100+1)
+	for _, b := range data[zeros:] {
+		carry := int(b)
+		for i := range digits {
+			carry += int(digits[i]) << 8
+			digits[i] = byte(carry % 58)
+			carry /= 58
+		}
+		for carry > 0 {
+			digits = append(digits, byte(carry%58))
+			carry /= 58
+		}
+	}
+
+	encoded := make([]byte, zeros+len(digits))
+	for i := 0; i < zeros; i++ {
+		encoded[i] = base58Alphabet[0]
+	}
+	for i, digit := range digits {
+		encoded[len(encoded)-1-i] = base58Alphabet[digit]
+	}
+	return string(encoded)
+}
+
+// base58Decode decodes a Base58 string encoded by base58Encode; it returns false if s contains
+// a character outside of base58Alphabet
+func base58Decode(s string) ([]byte, bool) {
+	zeros := 0
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+
+	// decoded holds the decoded bytes, least significant first
+	decoded := make([]byte, 0, len(s))
+	for i := zeros; i < len(s); i++ {
+		digit := strings.IndexByte(base58Alphabet, s[i])
+		if digit < 0 {
+			return nil, false
+		}
+
+		carry := digit
+		for j := range decoded {
+			carry += int(decoded[j]) * 58
+			decoded[j] = byte(carry)
+			carry >>= 8
+		}
+		for carry > 0 {
+			decoded = append(decoded, byte(carry))
+			carry >>= 8
+		}
+	}
+
+	data := make([]byte, zeros+len(decoded))
+	for i, b := range decoded {
+		data[len(data)-1-i] = b
+	}
+	return data, true
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestEncodeAddressVectors(t *testing.T) {
+	tests := []struct {
+		pubKeyHash string
+		want       string
+	}{
+		{"0000000000000000000000000000000000000000", "1111111111111111111114oLvT2"},
+		{"010966776006953d5567439e5e39f86a0d273bee", "16UwLL9Risc3QfPqBUvKofHmBQ7wMtjvM"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			hash, err := hex.DecodeString(tt.pubKeyHash)
+			if err != nil {
+				t.Fatalf("DecodeString(): %v", err)
+			}
+			if got := EncodeAddress(hash); got != tt.want {
+				t.Errorf("EncodeAddress() = %s, want %s", got, tt.want)
+			}
+			if !ValidateAddress(tt.want) {
+				t.Errorf("ValidateAddress(%s) = false", tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateAddress(t *testing.T) {
+	valid := "16UwLL9Risc3QfPqBUvKofHmBQ7wMtjvM"
+	wallet, err := NewWallet()
+	if err != nil {
+		t.Fatalf("NewWallet(): %v", err)
+	}
+	short, _ := hex.DecodeString("010966776006953d5567439e5e39f86a0d273b")
+
+	tests := []struct {
+		name string
+		addr string
+		want bool
+	}{
+		{"valid", valid, true},
+		{"wallet address", wallet.Address(), true},
+		{"one character mistyped", "16UwLL9Risc3QfPqBUvKofHmBQ7wMtjvN", false},
+		{"two characters swapped", "16UwLL9Rsic3QfPqBUvKofHmBQ7wMtjvM", false},
+		{"character dropped", valid[:len(valid)-1], false},
+		{"character added", valid + "1", false},
+		{"hash of the wrong length", EncodeAddress(short), false},
+		{"character outside the alphabet", "16UwLL9Risc3QfPqBUvKofHmBQ7wMtjv0", false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidateAddress(tt.addr); got != tt.want {
+				t.Errorf("ValidateAddress(%q) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,162 +1,1686 @@
 package main
 
 import (
-	"crypto/sha256"
+	"context"
+	"encoding/binary"
 	"encoding/hex"
 	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 )
 
-// Block structure contains the index of the block, timestamp of the block, slice of confirmed transactions, proof-of-work (nonce), hash of the previous block and own hash .
+// defaultDifficulty is the number of leading zero bits a block hash must have by default,
+// the same work as four leading zero hex characters
+const defaultDifficulty = 16
+
+// minDifficulty and maxDifficulty bound the allowed difficulty; a SHA-256 hash has 256 bits
+const (
+	minDifficulty = 1
+	maxDifficulty = 256
+)
+
+// defaultBlockReward is the amount of newly minted base units paid to the miner of every block by default, 50 coins
+const defaultBlockReward = 50 * UnitsPerCoin
+
+// coinbaseSender is the sender of the reward transaction which brings new coins into circulation
+const coinbaseSender = "COINBASE"
+
+// defaultMaxFutureBlockTime is how far ahead of the local clock a block timestamp may be by default
+const defaultMaxFutureBlockTime = 2 * time.Hour
+
+// UnconfirmedBlockIndex is the block index FindTransaction reports for transactions which are still in the mempool
+const UnconfirmedBlockIndex = -1
+
+// maxMemoSize is the largest memo in bytes a transaction may carry
+const maxMemoSize = 256
+
+// defaultMaxIterations is the upper bound of nonce values tried by proofOfWork before giving up
+const defaultMaxIterations = 10_000_000
+
+// BlockHeader contains the index of the block, timestamp of the block, proof-of-work (nonce), hash of the previous block,
+// the Merkle root standing in for the transactions and own hash. The block hash covers the header fields only,
+// so a header can be verified without the block body
+type BlockHeader struct {
+	Index        int    `json:"index"`
+	Timestamp    int64  `json:"timestamp"`
+	Nonce        uint64 `json:"nonce"`      // nonce
+	Difficulty   int    `json:"difficulty"` // number of leading zero bits the block was mined at
+	PreviousHash string `json:"previous_hash"`
+	MerkleRoot   string `json:"merkle_root"` // root of the Merkle tree built over the TXIDs of the block's transactions
+	Hash         string `json:"hash"`
+}
+
+// Block structure contains the header of the block and its body, the slice of confirmed transactions.
+// The header fields are embedded, so they are accessed and encoded as fields of the block
 type Block struct {
-	Index        int
-	Timestamp    int64
-	Transactions []Transaction
-	Nonce        int // nonce
-	PreviousHash string
-	Hash         string
+	BlockHeader
+	Transactions []Transaction `json:"transactions"`
+	Pruned       bool          `json:"pruned,omitempty"` // the transactions were dropped by Prune, only the header remains
 }
 
 // Transaction structure contains the sender, recipient and amount of medium's of exchange unit.
+// Amount and Fee are counted in base units (see UnitsPerCoin). A transaction paying several recipients
+// (see NewMultiTransaction) lists the payments in Outputs; Recipient is then the first of them and Amount their total
 type Transaction struct {
-	Sender    string
-	Recipient string
-	Amount    float64
-	TXID      string // Transaction ID
+	Sender     string     `json:"sender"`
+	Recipient  string     `json:"recipient"`
+	Amount     int64      `json:"amount"`
+	Fee        int64      `json:"fee"`                   // paid by the sender to the miner of the block including the transaction
+	Nonce      uint64     `json:"nonce"`                 // sequence number of the transaction among those sent by Sender, starting at 1
+	Memo       string     `json:"memo,omitempty"`        // free text such as a payment reference, at most maxMemoSize bytes
+	ExpiryTime int64      `json:"expiry_time,omitempty"` // last block timestamp, in the unit of the chain, the transaction may be mined at; zero never expires
+	Inputs     []TXInput  `json:"inputs,omitempty"`      // UTXO model only: outputs of earlier transactions spent by the sender
+	Outputs    []TXOutput `json:"outputs,omitempty"`     // UTXO model only: payment to Recipient and change returned to Sender
+	TXID       string     `json:"txid"`                  // Transaction ID
+	Signature  []byte     `json:"signature,omitempty"`   // ECDSA signature of the TXID by the sender's wallet
+	PublicKey  []byte     `json:"public_key,omitempty"`  // DER encoded public key of the sender's wallet
 }
 
 // Blockchain structure contains the slice of blocks which instantiates the blockchain itself and slice of transaction, which is needed for the temporary pool of unconfirmed transactions - "mempool".
+// Difficulty is the number of leading zero bits required in the hash of the next block. Every additional bit
+// doubles the expected mining time, so values above 24-28 are impractical on ordinary hardware.
+// When TargetBlockTime is set, Difficulty is recalculated every DifficultyAdjustmentInterval blocks (see adjustDifficulty).
+//
+// Locking discipline: mu guards Chain, Transactions and the settings. Methods which are safe for concurrent use
+// acquire mu themselves - the read lock for queries and the write lock for mutations. Methods whose name ends
+// in "Locked" expect the caller to already hold mu (at least the read lock if they only read) and never lock it,
+// so locked methods can be composed without deadlocking on the non-reentrant mutex.
+// MineBlock holds the write lock for the whole mining process, so the mempool cannot change under the proof-of-work.
 type Blockchain struct {
+	mu sync.RWMutex
+
 	Chain        []Block
 	Transactions []Transaction // mempool
+	Difficulty   int
+	BlockReward  int64 // base units minted by the coinbase transaction of every mined block before the first halving
+	// HalvingInterval is the number of mined blocks after which the block reward halves; zero keeps it constant
+	HalvingInterval int
+	// TreasuryAddress receives TreasuryPercent percent of the reward and fees of every mined block (see
+	// coinbaseTransactionLocked), e.g. to fund development; the miner receives the rest. A zero percent disables the split.
+	// Chain validation rejects blocks paying the treasury less, so the split must not change once blocks are mined
+	TreasuryAddress string
+	TreasuryPercent float64
+	// TargetBlockTime is the desired time between two blocks with the resolution of the block timestamps,
+	// one second unless MillisecondTimestamps is set; zero disables difficulty adjustment
+	TargetBlockTime time.Duration
+	// DifficultyAdjustmentInterval is the number of blocks in the window compared against TargetBlockTime
+	DifficultyAdjustmentInterval int
+	// MaxFutureBlockTime is how far ahead of the local clock a block timestamp is accepted
+	MaxFutureBlockTime time.Duration
+	// MaxTxPerBlock limits the number of mempool transactions included in a block, not counting
+	// the coinbase transaction; zero means no limit
+	MaxTxPerBlock int
+	// MaxBlockSize limits the size in bytes of a block (see Block.Size) not counting its coinbase transaction,
+	// so the transactions included by MineBlock fit in it; zero means no limit
+	MaxBlockSize int
+	// MaxMempoolSize limits the number of pending transactions; when the mempool is full a new transaction
+	// evicts the lowest paying one if it pays a higher fee. Zero means no limit
+	MaxMempoolSize int
+	// DustThreshold is the smallest amount in base units a transaction may pay an address other than its sender,
+	// as payments below it cost more block space than they are worth. The mempool rejects transactions with such
+	// a payment with ErrDustAmount and blocks are assembled without them. It is not a validation rule of the chain,
+	// so blocks mined elsewhere are accepted regardless. Zero disables the limit
+	DustThreshold int64
+	// RequireSignatures makes the mempool and chain validation reject unsigned transactions; signed transactions are
+	// always verified. It is off by default so the CLI and demo flows can submit unsigned transfers, which means
+	// anyone can spend from any address: nodes accepting transactions or blocks from untrusted peers must set it
+	RequireSignatures bool
+	// ValidateAddresses makes the mempool reject transactions paying an address which is not a valid
+	// Base58Check address (see ValidateAddress), catching typos before funds are sent to an address nobody owns
+	ValidateAddresses bool
+	// CoinbaseMaturity is the number of blocks which must be mined on top of a block before its coinbase
+	// reward can be spent; genesis allocations are exempt. Zero, the default, makes rewards spendable at once
+	CoinbaseMaturity int
+	// Checkpoint holds the state of the blocks whose transactions were dropped by Prune; nil if nothing was pruned
+	Checkpoint *Checkpoint
+	// Target optionally tightens the proof of work beyond Difficulty: when set, every block hash read as
+	// an integer must also be below it, allowing finer steps than one zero bit (see TargetFromDifficulty).
+	// Blocks mined before the target was set are checked against it as well; nil means Difficulty alone applies
+	Target *big.Int
+	// Hasher computes every block hash, TXID and Merkle tree node of the chain; it is fixed at genesis
+	// and only the built-in SHA256Hasher and DoubleSHA256Hasher are restored by LoadFromFile
+	Hasher Hasher
+	// PoW computes the proof-of-work hash of every block which must meet Difficulty and Target; it is fixed
+	// at genesis, and only the built-in SHA256PoW and ScryptPoW are restored by LoadFromFile
+	PoW PoWAlgorithm
+	// MillisecondTimestamps makes block timestamps Unix milliseconds instead of seconds, so blocks mined within
+	// the same second are still ordered and block times are measured precisely; every block must then be later
+	// than its predecessor (see validateTimestampLocked). It is fixed at genesis;
+	// chains created without it keep their second timestamps and validate as before
+	MillisecondTimestamps bool
+
+	nonces     map[string]uint64    // last transaction nonce used by each sender, in the chain or the mempool
+	coinbase   *Transaction         // reward transaction of the block currently mined by MineBlock
+	useUTXO    bool                 // balances are tracked as unspent transaction outputs (see EnableUTXO)
+	utxo       map[string]TXOutput  // unspent outputs of the chain keyed by outpoint, maintained while useUTXO is set
+	received   map[string]time.Time // time each mempool transaction was received, keyed by TXID
+	onNewBlock []func(Block)        // callbacks registered with OnNewBlock
+	proofCheck func(string) bool    // acceptance test of proof-of-work hashes set with SetProofPredicate
+	peers      peerSet              // nodes connected through ConnectPeer or ServePeers
+	orphans    map[string]orphan    // blocks received before their parent, keyed by hash (see AddBlockFromNetwork)
+	utxoUndo   map[string]utxoUndo  // UTXO changes of the most recent blocks, keyed by block hash (see Reorg)
 }
 
 func main() {
-	bc := createBlockchain()
-	bc.addTransaction("Alice", "Bob", 50)
-	bc.addTransaction("Bob", "Charlie", 25)
+	if err := runCLI(os.Args[1:], os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
 
-	start := time.Now()
-	nonce, candidateTimestamp := bc.proofOfWork()
+// defaultGenesisNonce is the predefined nonce of the genesis block, which is not mined
+const defaultGenesisNonce = 100
 
-	duration := time.Since(start)
-	fmt.Printf("Proof of work (nonce) found in iteration %d (execution time: %s)\n", nonce, duration)
-	previousHash := bc.Chain[len(bc.Chain)-1].Hash
-	bc.addBlock(nonce, candidateTimestamp, previousHash)
+// defaultGenesisTimestamp is the fixed timestamp of the default genesis block (2025-01-01 00:00:00 UTC),
+// so every node creating a default blockchain derives the same genesis hash
+const defaultGenesisTimestamp int64 = 1735689600
 
-	fmt.Println("Blockchain:", bc.Chain)
+// GenesisConfig describes the genesis block of a chain. Two chains created from the same config
+// have identical genesis hashes, which is needed for reproducible test chains and for separate networks
+type GenesisConfig struct {
+	Timestamp   int64            // timestamp of the genesis block, in milliseconds with Milliseconds; zero means the current time, which makes the genesis hash unique
+	Nonce       uint64           // nonce of the genesis block
+	Allocations []Transaction    // pre-funded transactions included in the genesis block; an empty Sender means coinbaseSender
+	Balances    map[string]int64 // pre-funded balances in base units, one coinbase transaction per address appended after Allocations in address order
+	Difficulty  int              // mining difficulty of the chain; zero means defaultDifficulty
+	Hasher      Hasher           // hash function of the chain; nil means SHA256Hasher
+	PoW         PoWAlgorithm     // proof-of-work algorithm of the chain; nil means SHA256PoW
+	UTXO        bool             // track balances with the UTXO model instead of the account model
+	// Milliseconds makes the block timestamps of the chain Unix milliseconds (see Blockchain.MillisecondTimestamps)
+	Milliseconds bool
 }
 
 // createBlockchain initializes and returns a new Blockchain instance
 // with the genesis block already added to the chain
 func createBlockchain() *Blockchain {
+	return createBlockchainWithGenesis(GenesisConfig{
+		Timestamp:  defaultGenesisTimestamp,
+		Nonce:      defaultGenesisNonce,
+		Difficulty: defaultDifficulty,
+	})
+}
+
+// createBlockchainWithGenesis initializes and returns a new Blockchain instance
+// with the genesis block described by cfg already added to the chain
+func createBlockchainWithGenesis(cfg GenesisConfig) *Blockchain {
+	difficulty := cfg.Difficulty
+	if difficulty == 0 {
+		difficulty = defaultDifficulty
+	}
+
 	bc := &Blockchain{
 		Chain:        []Block{},
 		Transactions: []Transaction{},
+		nonces:       map[string]uint64{},
+		received:     map[string]time.Time{},
+		useUTXO:      cfg.UTXO,
+		Difficulty:   difficulty,
+		BlockReward:  defaultBlockReward,
+		Hasher:       hasherOrDefault(cfg.Hasher),
+		PoW:          powOrDefault(cfg.PoW),
+
+		DifficultyAdjustmentInterval: defaultDifficultyAdjustmentInterval,
+		MaxFutureBlockTime:           defaultMaxFutureBlockTime,
+		MillisecondTimestamps:        cfg.Milliseconds,
 	}
 
-	bc.createGenesisBlock() // genesis block
+	bc.createGenesisBlock(cfg) // genesis block
+	bc.rebuildNoncesLocked()
+	bc.rebuildUTXOLocked()
 	return bc
 }
 
 // createGenesisBlock creates the very first block of the blockchain (genesis block),
-// sets its predefined values, calculates its hash, and appends it to the chain
-func (bc *Blockchain) createGenesisBlock() {
+// sets the values given by cfg, calculates its hash, and appends it to the chain.
+// Every allocation gets its TXID calculated, so the genesis hash is determined by cfg alone
+func (bc *Blockchain) createGenesisBlock(cfg GenesisConfig) {
+	allocations := append([]Transaction{}, cfg.Allocations...)
+	addresses := make([]string, 0, len(cfg.Balances))
+	for address := range cfg.Balances {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	for _, address := range addresses {
+		allocations = append(allocations, Transaction{Recipient: address, Amount: cfg.Balances[address]})
+	}
+
+	transactions := make([]Transaction, len(allocations))
+	for i, tx := range allocations {
+		if tx.Sender == "" {
+			tx.Sender = coinbaseSender
+		}
+		tx.TXID = generateTransactionID(bc.hasher(), tx)
+		transactions[i] = tx
+	}
+
+	timestamp := cfg.Timestamp
+	if timestamp == 0 {
+		timestamp = bc.timestampLocked(time.Now())
+	}
+
 	genesisBlock := Block{
-		Index:        0,
-		Timestamp:    time.Now().Unix(),
-		Transactions: []Transaction{},
-		Nonce:        100,
-		PreviousHash: "0",
+		BlockHeader: BlockHeader{
+			Index:        0,
+			Timestamp:    timestamp,
+			Nonce:        cfg.Nonce,
+			Difficulty:   bc.Difficulty,
+			PreviousHash: "0",
+		},
+		Transactions: transactions,
 	}
 
-	genesisBlock.Hash = calculateHash(genesisBlock)
+	genesisBlock.MerkleRoot = computeMerkleRoot(bc.hasher(), genesisBlock.Transactions)
+	genesisBlock.Hash = calculateHash(bc.hasher(), genesisBlock.BlockHeader)
 
 	bc.Chain = append(bc.Chain, genesisBlock)
 }
 
-// addBlock creates a new block using the provided nonce, timestamp, and previous hash,
-// calculates its hash, appends it to the chain, and clears the mempool
-func (bc *Blockchain) addBlock(nonce int, timestamp int64, previousHash string) {
+// addBlock creates a new block from the transactions selected by blockTransactionsLocked using the provided nonce,
+// timestamp, and previous hash, calculates its Merkle root and hash, appends it to the chain, removes the included
+// transactions from the mempool and adjusts the difficulty for the next block if needed. Mempool transactions
+// which can no longer be mined, e.g. because the chain confirmed them meanwhile, are dropped first and logged.
+// Returns ErrInvalidBlock if previousHash is not the hash of the chain tip or the nonce does not satisfy
+// the mining difficulty, and ErrInvalidTimestamp if the timestamp is earlier than the one of the previous block
+// or more than bc.MaxFutureBlockTime ahead of the local clock
+// The OnNewBlock callbacks are invoked with the appended block once the lock is released
+func (bc *Blockchain) addBlock(nonce uint64, timestamp int64, previousHash string) error {
+	bc.mu.Lock()
+	err := bc.addBlockLocked(nonce, timestamp, previousHash)
+	var block Block
+	if err == nil {
+		block, err = bc.latestBlockLocked()
+	}
+	bc.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	bc.notifyNewBlock(block)
+	return nil
+}
+
+// addBlockLocked is addBlock for callers already holding the write lock
+func (bc *Blockchain) addBlockLocked(nonce uint64, timestamp int64, previousHash string) error {
+	lastBlock, err := bc.latestBlockLocked()
+	if err != nil {
+		return err
+	}
+
+	if previousHash != lastBlock.Hash {
+		return fmt.Errorf("%w: previous hash %s does not match tip hash %s", ErrInvalidBlock, previousHash, lastBlock.Hash)
+	}
+
+	if err := bc.validateTimestampLocked(lastBlock, timestamp); err != nil {
+		return err
+	}
+
+	bc.dropRejectedMempoolLocked()
+	transactions := bc.blockTransactionsLocked(timestamp)
 	newBlock := Block{
-		Index:        len(bc.Chain),
-		Timestamp:    timestamp,
-		Transactions: bc.Transactions,
-		Nonce:        nonce,
-		PreviousHash: previousHash,
+		BlockHeader: BlockHeader{
+			Index:        len(bc.Chain),
+			Timestamp:    timestamp,
+			Nonce:        nonce,
+			Difficulty:   bc.Difficulty,
+			PreviousHash: previousHash,
+			MerkleRoot:   computeMerkleRoot(bc.hasher(), transactions),
+		},
+		Transactions: transactions,
+	}
+	newBlock.Hash = calculateHash(bc.hasher(), newBlock.BlockHeader)
+	if !bc.meetsProofLocked(newBlock.BlockHeader) {
+		return fmt.Errorf("%w: nonce %d does not satisfy difficulty %d", ErrInvalidBlock, nonce, newBlock.Difficulty)
+	}
+
+	if bc.useUTXO {
+		if err := bc.connectBlockUTXOLocked(newBlock); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidBlock, err)
+		}
 	}
-	newBlock.Hash = calculateHash(newBlock)
 	bc.Chain = append(bc.Chain, newBlock)
-	bc.Transactions = []Transaction{}
+	bc.removeFromMempoolLocked(transactions)
+
+	bc.adjustDifficulty()
+	return nil
+}
+
+// blockTransactionsLocked returns the transactions of the next block with the given timestamp: the coinbase
+// transaction of the block being mined, if any, followed by the mempool transactions chosen by selectMempoolLocked.
+// Proof-of-work and addBlock both use it, so the mined nonce is valid for the block that is appended
+func (bc *Blockchain) blockTransactionsLocked(timestamp int64) []Transaction {
+	selected := bc.selectMempoolLocked(timestamp)
+
+	transactions := make([]Transaction, 0, len(selected)+1)
+	if bc.coinbase != nil {
+		transactions = append(transactions, *bc.coinbase)
+	}
+
+	return append(transactions, selected...)
+}
+
+// selectMempoolLocked returns the mempool transactions paying the highest fees, up to bc.MaxTxPerBlock of them
+// and as many as fit in bc.MaxBlockSize bytes; a transaction too large for the remaining space is skipped
+// in favour of smaller ones paying less. Transactions expired at the timestamp of the block and those paying
+// dust (see DustThreshold) are skipped as well.
+// They are ordered by descending fee; transactions with equal fees keep their mempool (arrival) order.
+// A transaction its sender can no longer afford after the transactions selected before it, or one spending
+// an output which is already spent, is a double-spend and left out; it stays in the mempool
+// until it becomes affordable or is pruned. A sender's transactions are selected in nonce order, a transaction
+// paying a higher fee than one of a lower nonce of its sender follows it, and one following a transaction
+// which is left out is left out as well. Transactions which can never be mined (see mempoolRejectionsLocked)
+// are left out as well; addBlock and MineBlock drop them from the mempool before assembling a block
+func (bc *Blockchain) selectMempoolLocked(timestamp int64) []Transaction {
+	rejected := bc.mempoolRejectionsLocked()
+	candidates := []Transaction{}
+	for _, tx := range bc.Transactions {
+		if rejected[tx.TXID] == nil && !tx.expiredAt(timestamp) && !bc.isDustLocked(tx) {
+			candidates = append(candidates, tx)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Fee > candidates[j].Fee
+	})
+
+	size := 0
+	if tip, err := bc.latestBlockLocked(); err == nil {
+		// the hashes of the next block have the length of those of the tip
+		size = Block{BlockHeader: BlockHeader{PreviousHash: tip.Hash, MerkleRoot: tip.Hash, Hash: tip.Hash}}.Size()
+	}
+
+	// waiting holds the transactions passed over until those of lower nonces of their sender are selected
+	bySender := map[string][]Transaction{}
+	for _, tx := range candidates {
+		bySender[tx.Sender] = append(bySender[tx.Sender], tx)
+	}
+	for _, txs := range bySender {
+		sort.SliceStable(txs, func(i, j int) bool { return txs[i].Nonce < txs[j].Nonce })
+	}
+	next := map[string]int{}
+	waiting := map[string]bool{}
+	blocked := map[string]bool{}
+
+	available := map[string]int64{}
+	spent := map[string]bool{}
+	selected := []Transaction{}
+	take := func(tx Transaction) bool {
+		txSize := len(tx.serialize())
+		if bc.MaxBlockSize > 0 && size+txSize > bc.MaxBlockSize {
+			return false
+		}
+
+		if bc.useUTXO {
+			if !bc.inputsUnspentLocked(tx, spent) {
+				return false
+			}
+			for _, in := range tx.Inputs {
+				spent[outpoint(in.TXID, in.Output)] = true
+			}
+		} else {
+			if _, ok := available[tx.Sender]; !ok {
+				available[tx.Sender] = bc.balanceLocked(tx.Sender)
+			}
+			if tx.Amount+tx.Fee > available[tx.Sender] {
+				return false
+			}
+			available[tx.Sender] -= tx.Amount + tx.Fee
+		}
+
+		selected = append(selected, tx)
+		size += txSize
+		return true
+	}
+
+	for _, tx := range candidates {
+		sender := tx.Sender
+		if blocked[sender] {
+			continue
+		}
+		if bySender[sender][next[sender]].TXID != tx.TXID {
+			waiting[tx.TXID] = true
+			continue
+		}
+
+		for {
+			if bc.MaxTxPerBlock > 0 && len(selected) == bc.MaxTxPerBlock {
+				return selected
+			}
+			if !take(bySender[sender][next[sender]]) {
+				blocked[sender] = true
+				break
+			}
+			next[sender]++
+			if next[sender] == len(bySender[sender]) || !waiting[bySender[sender][next[sender]].TXID] {
+				break
+			}
+		}
+	}
+
+	return selected
+}
+
+// removeFromMempoolLocked drops the given transactions from the mempool, keeping the order of the remaining ones
+func (bc *Blockchain) removeFromMempoolLocked(transactions []Transaction) {
+	included := make(map[string]bool, len(transactions))
+	for _, tx := range transactions {
+		included[tx.TXID] = true
+	}
+
+	remaining := []Transaction{}
+	for _, tx := range bc.Transactions {
+		if !included[tx.TXID] {
+			remaining = append(remaining, tx)
+		} else {
+			delete(bc.received, tx.TXID)
+		}
+	}
+
+	bc.Transactions = remaining
+}
+
+// validateTimestampLocked checks that a block timestamp following previousBlock is not earlier than
+// the previous block's timestamp, and with MillisecondTimestamps later than it, so the blocks of a millisecond
+// chain are strictly ordered, and not more than bc.MaxFutureBlockTime ahead of the local clock
+func (bc *Blockchain) validateTimestampLocked(previousBlock Block, timestamp int64) error {
+	if timestamp < previousBlock.Timestamp {
+		return fmt.Errorf("%w: %d is earlier than timestamp %d of block %d", ErrInvalidTimestamp, timestamp, previousBlock.Timestamp, previousBlock.Index)
+	}
+	if bc.MillisecondTimestamps && timestamp == previousBlock.Timestamp {
+		return fmt.Errorf("%w: %d is not later than timestamp %d of block %d", ErrInvalidTimestamp, timestamp, previousBlock.Timestamp, previousBlock.Index)
+	}
+
+	if limit := bc.timestampLocked(time.Now().Add(bc.MaxFutureBlockTime)); timestamp > limit {
+		return fmt.Errorf("%w: %d is more than %s in the future", ErrInvalidTimestamp, timestamp, bc.MaxFutureBlockTime)
+	}
+
+	return nil
+}
+
+// MineBlock creates a coinbase transaction paying the block reward at the height of the new block
+// (bc.BlockReward halved every HalvingInterval blocks) plus the fees of the mempool transactions
+// selected for the next block to minerAddress, runs proof-of-work over the coinbase and the selected transactions
+// on all CPUs (see parallelProofOfWorkLocked) and appends the mined block to the chain.
+// If no proof is found or the block is rejected the mempool is left untouched.
+// With a TreasuryPercent the coinbase pays that share to TreasuryAddress instead; an invalid split fails
+// with ErrInvalidTreasury before mining starts.
+// Returns the newly mined block
+func (bc *Blockchain) MineBlock(minerAddress string) (Block, error) {
+	return bc.MineBlockCtx(context.Background(), minerAddress)
+}
+
+// MineBlockCtx is MineBlock which aborts mining with ctx.Err() when ctx is cancelled or times out.
+// The OnNewBlock callbacks are invoked with the mined block once the lock is released
+func (bc *Blockchain) MineBlockCtx(ctx context.Context, minerAddress string) (Block, error) {
+	bc.mu.Lock()
+	block, err := bc.mineBlockLocked(ctx, minerAddress)
+	bc.mu.Unlock()
+
+	if err != nil {
+		return Block{}, err
+	}
+
+	bc.notifyNewBlock(block)
+	return block, nil
+}
+
+// mineBlockLocked is MineBlockCtx for callers already holding the write lock; it does not invoke the callbacks
+func (bc *Blockchain) mineBlockLocked(ctx context.Context, minerAddress string) (Block, error) {
+	bc.dropRejectedMempoolLocked()
+	// the fees, the proof of work and the block select the mempool transactions at the same timestamp,
+	// so a transaction expiring meanwhile cannot leave the coinbase claiming its fee
+	timestamp := bc.nextBlockTimestampLocked()
+	fees := int64(0)
+	for _, tx := range bc.selectMempoolLocked(timestamp) {
+		fees += tx.Fee
+	}
+
+	coinbase, err := bc.coinbaseTransactionLocked(minerAddress, bc.blockRewardLocked(len(bc.Chain))+fees, len(bc.Chain))
+	if err != nil {
+		return Block{}, err
+	}
+	bc.coinbase = &coinbase
+	defer func() { bc.coinbase = nil }()
+
+	nonce, candidateTimestamp, err := bc.parallelProofOfWorkLocked(ctx, timestamp, defaultMaxIterations, 0)
+	if err != nil {
+		return Block{}, err
+	}
+
+	lastBlock, err := bc.latestBlockLocked()
+	if err != nil {
+		return Block{}, err
+	}
+	if err := bc.addBlockLocked(nonce, candidateTimestamp, lastBlock.Hash); err != nil {
+		return Block{}, err
+	}
+
+	return bc.latestBlockLocked()
 }
 
-// addTransaction adds an unconfirmed transaction to the mempool
-// and returns a unique transaction ID generated from its contents
-func (bc *Blockchain) addTransaction(sender, recipient string, amount float64) string {
+// newCoinbaseTransaction creates the reward transaction for the miner of the block at blockIndex;
+// its sender is coinbaseSender since the coins are newly minted and not taken from any address.
+// The block index is used as nonce, so equal rewards to the same miner still get distinct TXIDs
+func newCoinbaseTransaction(h Hasher, minerAddress string, reward int64, blockIndex int) Transaction {
 	tx := Transaction{
+		Sender:    coinbaseSender,
+		Recipient: minerAddress,
+		Amount:    reward,
+		Nonce:     uint64(blockIndex),
+	}
+	tx.TXID = generateTransactionID(h, tx)
+
+	return tx
+}
+
+// addTransaction adds an unsigned unconfirmed transaction of amount base units to the mempool
+// and returns a unique transaction ID generated from its contents (see submitTransaction)
+func (bc *Blockchain) addTransaction(sender, recipient string, amount int64) (string, error) {
+	return bc.addTransactionWithMemo(sender, recipient, amount, "")
+}
+
+// addTransactionWithMemo is addTransaction attaching the memo to the transaction; the memo is covered
+// by the TXID, so it cannot be changed without invalidating the transaction and the block including it
+func (bc *Blockchain) addTransactionWithMemo(sender, recipient string, amount int64, memo string) (string, error) {
+	return bc.submitTransaction(Transaction{
 		Sender:    sender,
 		Recipient: recipient,
 		Amount:    amount,
+		Memo:      memo,
+	})
+}
+
+// NewMultiTransaction creates an unsigned transaction from sender paying every output, ready to be signed or
+// submitted; the outputs are copied. Recipient is set to the first output and Amount to the total of the outputs.
+// The TXID covers every output in order, so the same outputs always yield the same TXID
+func NewMultiTransaction(sender string, outputs []TXOutput) Transaction {
+	tx := Transaction{Sender: sender, Outputs: append([]TXOutput{}, outputs...)}
+	if len(outputs) > 0 {
+		tx.Recipient = outputs[0].Address
+	}
+	for _, out := range outputs {
+		tx.Amount += out.Amount
+	}
+	return tx
+}
+
+// addMultiTransaction adds an unsigned unconfirmed transaction paying every output to the mempool
+// and returns its transaction ID (see NewMultiTransaction and submitTransaction)
+func (bc *Blockchain) addMultiTransaction(sender string, outputs []TXOutput) (string, error) {
+	return bc.submitTransaction(NewMultiTransaction(sender, outputs))
+}
+
+// submitTransaction adds a possibly signed unconfirmed transaction to the mempool
+// and returns its transaction ID, which is always recalculated from the transaction's contents.
+// A transaction failing Validate, like one with a blank sender or recipient, with the sender as recipient,
+// with an amount which is not positive, with a memo longer than maxMemoSize bytes or sent by coinbaseSender,
+// is rejected with ErrInvalidTransaction.
+// Every transaction must carry the next nonce of its sender (see NextNonce), otherwise it is rejected with
+// ErrInvalidNonce; an unsigned transaction without a nonce is assigned the next one automatically.
+// A signed transaction is rejected with ErrInvalidSignature if VerifyTransaction fails; unsigned transactions
+// are rejected the same way when bc.RequireSignatures is set.
+// When bc.ValidateAddresses is set, a transaction paying an address failing ValidateAddress is rejected with ErrInvalidAddress.
+// A transaction paying less than bc.DustThreshold to an address is rejected with ErrDustAmount.
+// A transaction whose TXID is already in the mempool or in the chain is rejected with ErrDuplicateTransaction,
+// one whose ExpiryTime has passed with ErrInvalidTransaction.
+// The transaction is rejected with ErrInsufficientFunds if the amount exceeds the sender's confirmed balance
+// minus everything the sender already has pending in the mempool.
+// The fee counts towards the spent amount.
+// With the UTXO model an unsigned transaction without inputs gets its inputs and outputs selected automatically,
+// and the inputs must spend unspent outputs of the sender which no mempool transaction spends yet.
+// A full mempool (see MaxMempoolSize) evicts its cheapest transaction for a better paying one or rejects tx with ErrMempoolFull
+func (bc *Blockchain) submitTransaction(tx Transaction) (string, error) {
+	// the fields are checked before the UTXO model spends outputs for the transaction, the TXID once it is known
+	if err := validateTransactionFields(tx); err != nil {
+		return "", err
+	}
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	return bc.submitTransactionLocked(tx)
+}
+
+// AddTransactions submits txs to the mempool in order under a single lock, each as by submitTransaction,
+// so a batch can partially succeed: the TXID of the i-th transaction is its i-th string and its rejection
+// the i-th error, exactly one of which is set. Earlier transactions of the batch count towards the pending
+// amount and nonce of their sender when the later ones are checked
+func (bc *Blockchain) AddTransactions(txs []Transaction) ([]string, []error) {
+	txids := make([]string, len(txs))
+	errs := make([]error, len(txs))
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	for i, tx := range txs {
+		if errs[i] = validateTransactionFields(tx); errs[i] != nil {
+			continue
+		}
+		txids[i], errs[i] = bc.submitTransactionLocked(tx)
+	}
+
+	return txids, errs
+}
+
+// submitTransactionLocked is submitTransaction for callers already holding the write lock,
+// once the transaction passed validateTransactionFields
+func (bc *Blockchain) submitTransactionLocked(tx Transaction) (string, error) {
+	if bc.ValidateAddresses {
+		for _, out := range tx.payments() {
+			if !ValidateAddress(out.Address) {
+				return "", fmt.Errorf("%w: %s", ErrInvalidAddress, out.Address)
+			}
+		}
+	}
+
+	if bc.isDustLocked(tx) {
+		return "", fmt.Errorf("%w: payments must be at least %s", ErrDustAmount, formatAmount(bc.DustThreshold))
+	}
+
+	if tx.Nonce == 0 && len(tx.Signature) == 0 {
+		tx.Nonce = bc.nextNonceLocked(tx.Sender)
+	}
+	if bc.useUTXO && len(tx.Inputs) == 0 && len(tx.Signature) == 0 {
+		if err := bc.fillUTXOTransactionLocked(&tx); err != nil {
+			return "", err
+		}
+	}
+	tx.TXID = generateTransactionID(bc.hasher(), tx)
+	if err := validateTransaction(bc.hasher(), tx); err != nil {
+		return "", err
+	}
+	if now := bc.timestampLocked(time.Now()); tx.expiredAt(now) {
+		return "", fmt.Errorf("%w: transaction %s expired at %d, now is %d", ErrInvalidTransaction, tx.TXID, tx.ExpiryTime, now)
+	}
+
+	if bc.hasTransactionLocked(tx.TXID) {
+		return "", fmt.Errorf("%w: %s", ErrDuplicateTransaction, tx.TXID)
+	}
+
+	if (len(tx.Signature) > 0 || bc.RequireSignatures) && !verifyTransaction(bc.hasher(), tx) {
+		return "", fmt.Errorf("%w: transaction %s", ErrInvalidSignature, tx.TXID)
+	}
+
+	if tx.Nonce != bc.nextNonceLocked(tx.Sender) {
+		return "", fmt.Errorf("%w: got %d, expected %d for %s", ErrInvalidNonce, tx.Nonce, bc.nextNonceLocked(tx.Sender), tx.Sender)
+	}
+
+	if bc.useUTXO {
+		if err := bc.validateUTXOInputsLocked(tx); err != nil {
+			return "", err
+		}
+	} else {
+		available := bc.balanceLocked(tx.Sender) - bc.pendingOutgoingLocked(tx.Sender)
+		if tx.Amount+tx.Fee > available {
+			return "", fmt.Errorf("%w: %s has %s available, needs %s", ErrInsufficientFunds, tx.Sender, formatAmount(available), formatAmount(tx.Amount+tx.Fee))
+		}
+	}
+
+	if err := bc.makeRoomLocked(tx); err != nil {
+		return "", err
 	}
-	tx.TXID = generateTransactionID(tx)
 
 	bc.Transactions = append(bc.Transactions, tx)
+	bc.received[tx.TXID] = time.Now()
+	bc.nonces[tx.Sender] = tx.Nonce
+
+	return tx.TXID, nil
+}
+
+// expiredAt reports whether the transaction has an ExpiryTime before timestamp, so a block with that timestamp
+// cannot include it
+func (tx Transaction) expiredAt(timestamp int64) bool {
+	return tx.ExpiryTime != 0 && timestamp > tx.ExpiryTime
+}
 
-	return tx.TXID
+// validateTransactionFields rejects transactions which are meaningless regardless of the chain state.
+// Every transaction which does not come from MineBlock passes it, so it rejects coinbaseSender as sender:
+// only the coinbase transaction of a block may mint coins
+func validateTransactionFields(tx Transaction) error {
+	switch {
+	case strings.TrimSpace(tx.Sender) == "":
+		return fmt.Errorf("%w: sender must not be blank", ErrInvalidTransaction)
+	case tx.Sender == coinbaseSender:
+		return fmt.Errorf("%w: sender %s is reserved for the block reward", ErrInvalidTransaction, coinbaseSender)
+	case strings.TrimSpace(tx.Recipient) == "":
+		return fmt.Errorf("%w: recipient must not be blank", ErrInvalidTransaction)
+	case tx.Sender == tx.Recipient:
+		return fmt.Errorf("%w: sender and recipient are both %s", ErrInvalidTransaction, tx.Sender)
+	case tx.Amount <= 0:
+		return fmt.Errorf("%w: amount must be positive, got %s", ErrInvalidTransaction, formatAmount(tx.Amount))
+	case tx.Fee < 0:
+		return fmt.Errorf("%w: fee must not be negative, got %s", ErrInvalidTransaction, formatAmount(tx.Fee))
+	case tx.ExpiryTime < 0:
+		return fmt.Errorf("%w: expiry time must not be negative, got %d", ErrInvalidTransaction, tx.ExpiryTime)
+	case tx.Amount > math.MaxInt64-tx.Fee:
+		return fmt.Errorf("%w: amount plus fee overflows", ErrInvalidTransaction)
+	case !utf8.ValidString(tx.Sender) || !utf8.ValidString(tx.Recipient) || !utf8.ValidString(tx.Memo):
+		// JSON encoding replaces invalid UTF-8, so the transaction would no longer match its TXID once saved or sent
+		return fmt.Errorf("%w: sender, recipient and memo must be valid UTF-8", ErrInvalidTransaction)
+	case len(tx.Memo) > maxMemoSize:
+		return fmt.Errorf("%w: memo of %d bytes exceeds the limit of %d bytes", ErrInvalidTransaction, len(tx.Memo), maxMemoSize)
+	case len(tx.Outputs) > 0 && tx.Outputs[0].Address != tx.Recipient:
+		return fmt.Errorf("%w: recipient %s is not the address of the first output", ErrInvalidTransaction, tx.Recipient)
+	}
+
+	paid := int64(0)
+	for i, out := range tx.Outputs {
+		switch {
+		case strings.TrimSpace(out.Address) == "":
+			return fmt.Errorf("%w: output %d: address must not be blank", ErrInvalidTransaction, i)
+		case !utf8.ValidString(out.Address):
+			return fmt.Errorf("%w: output %d: address must be valid UTF-8", ErrInvalidTransaction, i)
+		case out.Amount <= 0:
+			return fmt.Errorf("%w: output %d: amount must be positive, got %s", ErrInvalidTransaction, i, formatAmount(out.Amount))
+		case out.Amount > math.MaxInt64-paid:
+			return fmt.Errorf("%w: output amounts overflow", ErrInvalidTransaction)
+		}
+		if out.Address != tx.Sender {
+			paid += out.Amount
+		}
+	}
+	if len(tx.Outputs) > 0 && paid != tx.Amount {
+		return fmt.Errorf("%w: amount %s does not match the %s paid by the outputs", ErrInvalidTransaction, formatAmount(tx.Amount), formatAmount(paid))
+	}
+
+	return nil
+}
+
+// Validate checks that the transaction is well-formed: sender and recipient are set and differ,
+// the amount is positive, the fee is not negative, the memo fits maxMemoSize, explicit outputs are positive,
+// start with the recipient and pay Amount to addresses other than the sender, and the TXID matches
+// the transaction's contents hashed with SHA256Hasher. Returns an error wrapping ErrInvalidTransaction otherwise
+func (tx Transaction) Validate() error {
+	return validateTransaction(SHA256Hasher{}, tx)
+}
+
+// validateTransaction is Validate for the TXIDs of a chain hashing with h
+func validateTransaction(h Hasher, tx Transaction) error {
+	if err := validateTransactionFields(tx); err != nil {
+		return err
+	}
+
+	if tx.TXID != generateTransactionID(h, tx) {
+		return fmt.Errorf("%w: TXID %s does not match the transaction contents", ErrInvalidTransaction, tx.TXID)
+	}
+
+	return nil
+}
+
+// NextNonce returns the nonce the next transaction sent by the address must carry
+func (bc *Blockchain) NextNonce(address string) uint64 {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return bc.nextNonceLocked(address)
+}
+
+// nextNonceLocked is NextNonce for callers already holding the lock
+func (bc *Blockchain) nextNonceLocked(address string) uint64 {
+	return bc.nonces[address] + 1
+}
+
+// rebuildNoncesLocked recalculates the last nonce of every sender from the chain and the mempool,
+// which is needed whenever either of them is replaced as a whole
+func (bc *Blockchain) rebuildNoncesLocked() {
+	bc.nonces = bc.confirmedNoncesLocked()
+	for _, tx := range bc.Transactions {
+		if tx.Nonce > bc.nonces[tx.Sender] {
+			bc.nonces[tx.Sender] = tx.Nonce
+		}
+	}
+}
+
+// confirmedNoncesLocked returns the last nonce of every sender confirmed by the chain, including the pruned blocks
+func (bc *Blockchain) confirmedNoncesLocked() map[string]uint64 {
+	nonces := bc.Checkpoint.clone().Nonces
+	for _, block := range bc.Chain {
+		for _, tx := range block.Transactions {
+			if tx.Sender != coinbaseSender && tx.Nonce > nonces[tx.Sender] {
+				nonces[tx.Sender] = tx.Nonce
+			}
+		}
+	}
+
+	return nonces
+}
+
+// hasTransactionLocked reports whether a transaction with the given TXID is pending in the mempool or confirmed in the chain
+func (bc *Blockchain) hasTransactionLocked(txid string) bool {
+	for _, tx := range bc.Transactions {
+		if tx.TXID == txid {
+			return true
+		}
+	}
+
+	for _, block := range bc.Chain {
+		for _, tx := range block.Transactions {
+			if tx.TXID == txid {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// pendingOutgoingLocked sums the amounts and fees of all mempool transactions sent by the address
+func (bc *Blockchain) pendingOutgoingLocked(address string) int64 {
+	total := int64(0)
+	for _, tx := range bc.Transactions {
+		if tx.Sender == address {
+			total += tx.Amount + tx.Fee
+		}
+	}
+
+	return total
+}
+
+// generateTransactionID creates a hash with h from a transaction's sender, recipient,
+// amount, fee, nonce, memo, expiry time and UTXO inputs and outputs to uniquely identify the transaction and prevent duplication or tampering.
+// Like serializeForHash it length-prefixes every string and counts the inputs and outputs, so adjacent fields
+// cannot run into each other (e.g. sender "ab" paying "c" and sender "a" paying "bc" get different TXIDs)
+func generateTransactionID(h Hasher, tx Transaction) string {
+	return h.Hash(tx.serializeForID())
+}
+
+// serializeForID encodes the fields of the transaction covered by its TXID, see generateTransactionID
+func (tx Transaction) serializeForID() []byte {
+	data := appendLengthPrefixed(nil, tx.Sender)
+	data = appendLengthPrefixed(data, tx.Recipient)
+	data = appendLengthPrefixed(data, formatAmount(tx.Amount))
+	data = appendLengthPrefixed(data, formatAmount(tx.Fee))
+	data = binary.BigEndian.AppendUint64(data, tx.Nonce)
+	data = appendLengthPrefixed(data, tx.Memo)
+	data = binary.BigEndian.AppendUint64(data, uint64(tx.ExpiryTime))
+
+	data = binary.BigEndian.AppendUint32(data, uint32(len(tx.Inputs)))
+	for _, in := range tx.Inputs {
+		data = appendLengthPrefixed(data, in.TXID)
+		data = binary.BigEndian.AppendUint64(data, uint64(in.Output))
+	}
+	data = binary.BigEndian.AppendUint32(data, uint32(len(tx.Outputs)))
+	for _, out := range tx.Outputs {
+		data = appendLengthPrefixed(data, out.Address)
+		data = appendLengthPrefixed(data, formatAmount(out.Amount))
+	}
+
+	return data
 }
 
-// generateTransactionID creates a SHA-256 hash from a transaction's sender, recipient,
-// and amount to uniquely identify the transaction and prevent duplication or tampering
-func generateTransactionID(tx Transaction) string {
-	data := fmt.Sprintf("%s%s%f", tx.Sender, tx.Recipient, tx.Amount)
-	hash := sha256.Sum256([]byte(data))
-	return hex.EncodeToString(hash[:])
+// validateDifficulty checks that the difficulty is within the range supported by a SHA-256 hash
+func validateDifficulty(difficulty int) error {
+	if difficulty < minDifficulty || difficulty > maxDifficulty {
+		return fmt.Errorf("%w: got %d", ErrInvalidDifficulty, difficulty)
+	}
+	return nil
 }
 
-// isProofValid verifies whether the hash generated from a block candidate with a given nonce
-// satisfies the mining difficulty condition (e.g., hash starts with "0000")
-func isProofValid(lastBlock Block, nonce int, transactions []Transaction, candidateTimestamp int64) bool {
-	tempBlock := Block{
+// isProofValidLocked verifies whether the proof-of-work hash (see PoWAlgorithm) generated from a block candidate
+// with a given nonce and Merkle root of its transactions satisfies the mining difficulty condition: the hash read as an integer
+// is below the target of bc.Difficulty (it starts with bc.Difficulty zero bits) and below bc.Target if set,
+// or the predicate set with SetProofPredicate accepts it. An out of range difficulty never yields a valid proof
+func (bc *Blockchain) isProofValidLocked(lastBlock Block, nonce uint64, merkleRoot string, candidateTimestamp int64) bool {
+	if validateDifficulty(bc.Difficulty) != nil {
+		return false
+	}
+
+	candidate := BlockHeader{
 		Index:        lastBlock.Index + 1,
 		Timestamp:    candidateTimestamp,
-		Transactions: transactions,
 		Nonce:        nonce, // nonce
+		Difficulty:   bc.Difficulty,
 		PreviousHash: lastBlock.Hash,
+		MerkleRoot:   merkleRoot,
+	}
+
+	guessHash := bc.proofHashLocked(candidate)
+	if bc.proofCheck != nil {
+		return bc.proofCheck(guessHash)
+	}
+	return hashBelowTarget(guessHash, bc.targetLocked(bc.Difficulty))
+}
+
+// meetsDifficulty reports whether a hex hash starts with the number of zero bits given by difficulty,
+// which must be within the allowed range
+func meetsDifficulty(hash string, difficulty int) bool {
+	if validateDifficulty(difficulty) != nil {
+		return false
+	}
+	return hasLeadingZeroBits(hash, difficulty) // mining difficulty target
+}
+
+// hasLeadingZeroBits decodes a hex hash and reports whether its first bits bits are all zero.
+// A hash which is not valid hex or shorter than bits never qualifies
+func hasLeadingZeroBits(hash string, bits int) bool {
+	digest, err := hex.DecodeString(hash)
+	if err != nil || bits < 0 || bits > len(digest)*8 {
+		return false
+	}
+
+	for _, b := range digest[:bits/8] {
+		if b != 0 {
+			return false
+		}
+	}
+	if rest := bits % 8; rest > 0 {
+		return digest[bits/8]>>(8-rest) == 0
 	}
 
-	guessHash := calculateHash(tempBlock)
-	return guessHash[:4] == "0000" // mining difficulty target
+	return true
 }
 
 // proofOfWork iterates over increasing nonce values, generating a hash each time,
-// until it finds a hash that satisfies the predefined mining difficulty (e.g. starts with "0000").
+// until it finds a hash of the next block (see blockTransactionsLocked) that satisfies the configured mining difficulty (e.g. starts with 16 zero bits, "0000" in hex).
+// At most maxIterations nonce values are tried, starting at 0; the nonce is an unsigned 64 bit integer on every
+// platform and never exceeds maxIterations, so it cannot wrap around and retry nonces. If none of them is valid,
+// ErrProofNotFound is returned and the nonce space of the candidate timestamp counts as exhausted: the next
+// attempt takes a new timestamp, which changes the header and so every hash, once the clock has advanced.
 // Returns the valid nonce and the timestamp when the proof was found
-func (bc *Blockchain) proofOfWork() (int, int64) {
-	lastBlock := bc.Chain[len(bc.Chain)-1]
+func (bc *Blockchain) proofOfWork(maxIterations uint64) (uint64, int64, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return bc.proofOfWorkLocked(context.Background(), maxIterations)
+}
 
-	candidateTimestamp := time.Now().Unix()
-	nonce := 0
-	for !isProofValid(lastBlock, nonce, bc.Transactions, candidateTimestamp) {
-		nonce++
+// ProofOfWorkCtx is proofOfWork with at most defaultMaxIterations nonces which stops early
+// when ctx is cancelled or times out, returning ctx.Err()
+func (bc *Blockchain) ProofOfWorkCtx(ctx context.Context) (uint64, int64, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return bc.proofOfWorkLocked(ctx, defaultMaxIterations)
+}
+
+// proofOfWorkLocked is ProofOfWorkCtx with a custom iteration limit for callers already holding the lock
+func (bc *Blockchain) proofOfWorkLocked(ctx context.Context, maxIterations uint64) (uint64, int64, error) {
+	if err := validateDifficulty(bc.Difficulty); err != nil {
+		return 0, 0, err
+	}
+
+	lastBlock, err := bc.latestBlockLocked()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	candidateTimestamp := bc.nextBlockTimestampLocked()
+	merkleRoot := computeMerkleRoot(bc.hasher(), bc.blockTransactionsLocked(candidateTimestamp))
+	for nonce := uint64(0); nonce < maxIterations; nonce++ {
+		select {
+		case <-ctx.Done():
+			return 0, 0, ctx.Err()
+		default:
+		}
+
+		if bc.isProofValidLocked(lastBlock, nonce, merkleRoot, candidateTimestamp) {
+			return nonce, candidateTimestamp, nil
+		}
 	}
 
-	return nonce, candidateTimestamp
+	return 0, 0, ErrProofNotFound
 }
 
-// calculateHash generates the SHA-256 hash of a block by concatenating its index, timestamp, nonce,
-// previous block's hash, number of transactions, and details of each transaction (sender, recipient, amount).
+// calculateHash generates the hash of a block header with h over its serializeForHash encoding, which covers
+// its index, timestamp, nonce, difficulty, previous block's hash and the Merkle root committing to every transaction of the block.
 // Returns the hexadecimal string representation of the resulting hash.
-func calculateHash(block Block) string {
+func calculateHash(h Hasher, header BlockHeader) string {
+	return h.Hash(header.serializeForHash())
+}
+
+// serializeForHash encodes the hashed fields of the header in a fixed binary layout: index, timestamp, nonce
+// and difficulty as 8 byte big-endian integers, followed by the previous hash and the Merkle root, each
+// prefixed with its length as a 4 byte big-endian integer. Every field has an unambiguous boundary,
+// so distinct headers never share an encoding. Hash itself is not part of it; Block inherits the method
+func (header BlockHeader) serializeForHash() []byte {
+	buf := make([]byte, 0, 4*8+2*4+len(header.PreviousHash)+len(header.MerkleRoot))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(header.Index))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(header.Timestamp))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(header.Nonce))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(header.Difficulty))
+	buf = appendLengthPrefixed(buf, header.PreviousHash)
+	buf = appendLengthPrefixed(buf, header.MerkleRoot)
+	return buf
+}
+
+// appendLengthPrefixed appends s to buf preceded by its length as a 4 byte big-endian integer
+func appendLengthPrefixed(buf []byte, s string) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(s)))
+	return append(buf, s...)
+}
+
+// IsChainValid checks that the genesis block has index 0, previous hash "0" and a stored hash and Merkle root
+// matching its contents (see validateGenesis), then walks the chain starting from the first block after genesis and verifies that
+// every transaction's TXID matches its contents, that no transaction spends more than its sender owns at that point
+// of the chain, that under the UTXO model every input spends a mature unspent output of the sender once
+// (see replayBlockUTXOLocked), that no TXID is confirmed twice, that the nonces of every sender increase
+// strictly along the chain, that every signed transaction, and with bc.RequireSignatures every transaction,
+// passes VerifyTransaction, that a single coinbase transaction opens every block and mints no more than the block reward plus
+// the fees of the block (see validateCoinbaseLocked), that every block's Merkle root matches its transactions, that its stored hash matches its recalculated hash,
+// that it links to the hash of the previous block, that its timestamp is acceptable (see addBlock)
+// and that its nonce satisfies the mining difficulty.
+// Without difficulty adjustment every block must be mined at least at the current difficulty,
+// with adjustment its difficulty must match the one derived from the preceding blocks.
+// Returns false and an error naming the offending block index on the first inconsistency
+func (bc *Blockchain) IsChainValid() (bool, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if err := bc.validateChainLocked(bc.Chain); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// VerifyBlockPoW spot-checks the block at index without walking the chain: it recalculates the hash
+// from the stored header, including its previous hash and nonce, and checks that it matches the stored
+// hash and that the proof-of-work hash of the header satisfies the difficulty the block records and bc.Target, if set. The genesis block is not mined,
+// so only its hash is checked. Returns ErrBlockNotFound if index is out of range, and false with an error
+// wrapping ErrInvalidBlock which names the failed check otherwise
+func (bc *Blockchain) VerifyBlockPoW(index int) (bool, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if index < 0 || index >= len(bc.Chain) {
+		return false, fmt.Errorf("%w: index %d", ErrBlockNotFound, index)
+	}
+
+	block := bc.Chain[index]
+	if calculateHash(bc.hasher(), block.BlockHeader) != block.Hash {
+		return false, fmt.Errorf("%w: block %d: stored hash does not match calculated hash", ErrInvalidBlock, block.Index)
+	}
+	if index > 0 && !bc.meetsProofLocked(block.BlockHeader) {
+		return false, fmt.Errorf("%w: block %d: invalid proof of work", ErrInvalidBlock, block.Index)
+	}
 
-	hashInput := fmt.Sprintf("%d%d%d%s%d",
-		block.Index, block.Timestamp, block.Nonce, block.PreviousHash,
-		len(block.Transactions))
+	return true, nil
+}
+
+// validateChainLocked applies the IsChainValid rules to any chain using the difficulty of bc
+// and returns the first inconsistency found
+func (bc *Blockchain) validateChainLocked(chain []Block) error {
+	if len(chain) > 0 {
+		if err := validateGenesis(bc.hasher(), chain[0]); err != nil {
+			return err
+		}
+	}
+
+	replay := bc.newChainReplayLocked(chain)
+	for i := 1; i < len(chain); i++ {
+		if err := bc.validateBlockLocked(chain, i, replay); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
 
+// validateGenesis checks that block is a genesis block of a chain hashing with h: its index is 0, its previous
+// hash is "0" and its stored hash matches its header, as do its Merkle root and the TXIDs of its allocations
+// unless the block was pruned; the Merkle root only commits to the TXIDs, so an allocation changed under its TXID
+// would go unnoticed otherwise. Returns an error wrapping ErrInvalidChain and ErrInvalidGenesis otherwise
+func validateGenesis(h Hasher, block Block) error {
+	switch {
+	case block.Index != 0:
+		return fmt.Errorf("%w: %w: index is %d, not 0", ErrInvalidChain, ErrInvalidGenesis, block.Index)
+	case block.PreviousHash != "0":
+		return fmt.Errorf("%w: %w: previous hash is %q, not \"0\"", ErrInvalidChain, ErrInvalidGenesis, block.PreviousHash)
+	case calculateHash(h, block.BlockHeader) != block.Hash:
+		return fmt.Errorf("%w: %w: stored hash does not match calculated hash", ErrInvalidChain, ErrInvalidGenesis)
+	case !block.Pruned && computeMerkleRoot(h, block.Transactions) != block.MerkleRoot:
+		return fmt.Errorf("%w: %w: merkle root does not match transactions", ErrInvalidChain, ErrInvalidGenesis)
+	}
 	for _, tx := range block.Transactions {
-		hashInput += tx.Sender + tx.Recipient + fmt.Sprintf("%f", tx.Amount)
+		if tx.TXID != generateTransactionID(h, tx) {
+			return fmt.Errorf("%w: %w: allocation %s does not match its contents", ErrInvalidChain, ErrInvalidGenesis, tx.TXID)
+		}
+	}
+
+	return nil
+}
+
+// chainReplay holds the balances replayed block by block while a chain is validated, to catch
+// transactions spending more than their sender owns. Coinbase rewards are held in immature,
+// keyed by block index, until CoinbaseMaturity blocks were mined on top of them.
+// The TXIDs and the last nonce of every sender are replayed to reject transactions confirmed again.
+// Under the UTXO model the unspent outputs are replayed too, to check the inputs of every transaction
+type chainReplay struct {
+	balances map[string]int64
+	immature map[int]map[string]int64
+	txids    map[string]bool
+	nonces   map[string]uint64
+	utxo     map[string]TXOutput // nil without the UTXO model
+	coinbase map[string]int      // index of the block which created each replayed coinbase output
+}
+
+// newChainReplayLocked starts the replay of chain: the state up to the checkpoint is taken from the checkpoint,
+// otherwise the genesis allocations, which are trusted, are the starting balances
+func (bc *Blockchain) newChainReplayLocked(chain []Block) *chainReplay {
+	checkpoint := bc.Checkpoint.clone()
+	replay := &chainReplay{
+		balances: map[string]int64{},
+		immature: map[int]map[string]int64{},
+		txids:    map[string]bool{},
+		nonces:   checkpoint.Nonces,
+	}
+	if bc.Checkpoint != nil {
+		replay.balances = checkpoint.Balances
+	} else if len(chain) > 0 {
+		for _, tx := range chain[0].Transactions {
+			replay.txids[tx.TXID] = true
+			if tx.Sender != coinbaseSender {
+				replay.balances[tx.Sender] -= tx.Amount + tx.Fee
+				replay.nonces[tx.Sender] = max(replay.nonces[tx.Sender], tx.Nonce)
+			}
+			for _, out := range tx.payments() {
+				replay.balances[out.Address] += out.Amount
+			}
+		}
+	}
+
+	if bc.useUTXO {
+		replay.utxo = bc.Checkpoint.clone().UTXO
+		replay.coinbase = map[string]int{}
+		if bc.Checkpoint == nil && len(chain) > 0 {
+			// like rebuildUTXOLocked, a genesis block which does not apply leaves the set empty
+			_, _ = applyBlockToUTXO(replay.utxo, chain[0])
+		}
+	}
+
+	return replay
+}
+
+// validateBlockLocked applies the IsChainValid rules to chain[i], given that the blocks before it are valid
+// and were replayed into replay, and replays its transactions
+func (bc *Blockchain) validateBlockLocked(chain []Block, i int, replay *chainReplay) error {
+	block := chain[i]
+	previousBlock := chain[i-1]
+
+	if block.Pruned {
+		// the transactions of a pruned block are gone, only its header links can be verified
+		if bc.Checkpoint == nil || block.Index > bc.Checkpoint.Height {
+			return fmt.Errorf("%w: block %d: pruned block above the checkpoint", ErrInvalidChain, block.Index)
+		}
+	} else {
+		for _, tx := range block.Transactions {
+			if tx.TXID != generateTransactionID(bc.hasher(), tx) {
+				return fmt.Errorf("%w: block %d: transaction %s does not match its contents", ErrInvalidChain, block.Index, tx.TXID)
+			}
+			if tx.expiredAt(block.Timestamp) {
+				return fmt.Errorf("%w: block %d: transaction %s expired at %d", ErrInvalidChain, block.Index, tx.TXID, tx.ExpiryTime)
+			}
+			if tx.Sender != coinbaseSender && (len(tx.Signature) > 0 || bc.RequireSignatures) && !verifyTransaction(bc.hasher(), tx) {
+				return fmt.Errorf("%w: block %d: %w: transaction %s", ErrInvalidChain, block.Index, ErrInvalidSignature, tx.TXID)
+			}
+			if replay.txids[tx.TXID] {
+				return fmt.Errorf("%w: block %d: %w: %s is confirmed twice", ErrInvalidChain, block.Index, ErrDuplicateTransaction, tx.TXID)
+			}
+			replay.txids[tx.TXID] = true
+		}
+		if err := bc.validateCoinbaseLocked(block); err != nil {
+			return err
+		}
+
+		if bc.MaxTxPerBlock > 0 && len(block.Transactions) > bc.MaxTxPerBlock+1 {
+			return fmt.Errorf("%w: block %d: %d transactions exceed the limit of %d plus coinbase", ErrInvalidChain, block.Index, len(block.Transactions), bc.MaxTxPerBlock)
+		}
+
+		if size := block.sizeWithoutCoinbase(); bc.MaxBlockSize > 0 && size > bc.MaxBlockSize {
+			return fmt.Errorf("%w: block %d: %d bytes without coinbase exceed the limit of %d", ErrInvalidChain, block.Index, size, bc.MaxBlockSize)
+		}
+
+		if computeMerkleRoot(bc.hasher(), block.Transactions) != block.MerkleRoot {
+			return fmt.Errorf("%w: block %d: merkle root does not match transactions", ErrInvalidChain, block.Index)
+		}
+
+		if bc.Checkpoint == nil || block.Index > bc.Checkpoint.Height {
+			if replay.utxo != nil {
+				if err := bc.replayBlockUTXOLocked(block, replay); err != nil {
+					return err
+				}
+			}
+			for address, amount := range replay.immature[i-1-bc.CoinbaseMaturity] {
+				replay.balances[address] += amount
+			}
+			for _, tx := range block.Transactions {
+				if tx.Sender == coinbaseSender && bc.CoinbaseMaturity > 0 {
+					if replay.immature[i] == nil {
+						replay.immature[i] = map[string]int64{}
+					}
+					for _, out := range tx.payments() {
+						replay.immature[i][out.Address] += out.Amount
+					}
+					continue
+				}
+				if tx.Sender != coinbaseSender {
+					if last := replay.nonces[tx.Sender]; tx.Nonce <= last {
+						return fmt.Errorf("%w: block %d: %w: transaction %s has nonce %d, %s already used %d", ErrInvalidChain, block.Index,
+							ErrInvalidNonce, tx.TXID, tx.Nonce, tx.Sender, last)
+					}
+					replay.nonces[tx.Sender] = tx.Nonce
+					if tx.Amount+tx.Fee > replay.balances[tx.Sender] {
+						return fmt.Errorf("%w: block %d: transaction %s overdraws %s", ErrInvalidChain, block.Index, tx.TXID, tx.Sender)
+					}
+					replay.balances[tx.Sender] -= tx.Amount + tx.Fee
+				}
+				for _, out := range tx.payments() {
+					replay.balances[out.Address] += out.Amount
+				}
+			}
+		}
+	}
+
+	if calculateHash(bc.hasher(), block.BlockHeader) != block.Hash {
+		return fmt.Errorf("%w: block %d: stored hash does not match calculated hash", ErrInvalidChain, block.Index)
+	}
+
+	if block.Index != previousBlock.Index+1 {
+		return fmt.Errorf("%w: block %d: index does not follow block %d", ErrInvalidChain, block.Index, previousBlock.Index)
+	}
+
+	if block.PreviousHash != previousBlock.Hash {
+		return fmt.Errorf("%w: block %d: previous hash does not match hash of block %d", ErrInvalidChain, block.Index, previousBlock.Index)
+	}
+
+	if err := bc.validateTimestampLocked(previousBlock, block.Timestamp); err != nil {
+		return fmt.Errorf("%w: block %d: %w", ErrInvalidChain, block.Index, err)
+	}
+
+	if bc.TargetBlockTime > 0 {
+		if expected := bc.nextDifficulty(chain[:i]); block.Difficulty != expected {
+			return fmt.Errorf("%w: block %d: difficulty %d does not match expected difficulty %d", ErrInvalidChain, block.Index, block.Difficulty, expected)
+		}
+	} else if block.Difficulty < bc.Difficulty {
+		return fmt.Errorf("%w: block %d: difficulty %d is below required difficulty %d", ErrInvalidChain, block.Index, block.Difficulty, bc.Difficulty)
+	}
+
+	if !bc.meetsProofLocked(block.BlockHeader) {
+		return fmt.Errorf("%w: block %d: invalid proof of work", ErrInvalidChain, block.Index)
+	}
+
+	return nil
+}
+
+// ReplaceChain replaces the local chain with the incoming one if the incoming chain is strictly longer,
+// starts from the same genesis block and passes the IsChainValid rules. The blocks the chains have in common
+// are kept and the ones above them are swapped like by Reorg, so mempool transactions which are confirmed by
+// the new chain are dropped from the mempool and those of replaced blocks which it does not confirm return to it.
+// Returns whether the chain was replaced; a chain of another network, starting from a different genesis block,
+// is rejected with an error wrapping ErrGenesisMismatch and ErrInvalidChain whatever its length,
+// any other chain which is not longer is ignored without an error
+func (bc *Blockchain) ReplaceChain(incoming []Block) (bool, error) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if len(incoming) == 0 {
+		return false, nil
+	}
+
+	genesis := incoming[0]
+	if genesis.Hash != bc.Chain[0].Hash || calculateHash(bc.hasher(), genesis.BlockHeader) != genesis.Hash {
+		return false, fmt.Errorf("%w: %w: incoming chain starts from %s, local chain from %s", ErrInvalidChain, ErrGenesisMismatch,
+			shortHash(genesis.Hash), shortHash(bc.Chain[0].Hash))
+	}
+
+	if len(incoming) <= len(bc.Chain) {
+		return false, nil
+	}
+
+	if bc.Checkpoint != nil && incoming[bc.Checkpoint.Height].Hash != bc.Chain[bc.Checkpoint.Height].Hash {
+		return false, fmt.Errorf("%w: incoming chain forks below the checkpoint at block %d", ErrInvalidChain, bc.Checkpoint.Height)
+	}
+
+	fork := 1
+	for fork < len(bc.Chain) && incoming[fork].Hash == bc.Chain[fork].Hash {
+		fork++
+	}
+	if err := bc.reorgLocked(fork-1, incoming[fork:]); err != nil {
+		return false, fmt.Errorf("incoming chain is invalid: %w", err)
+	}
+
+	return true, nil
+}
+
+// AppendBlock appends a block mined elsewhere, e.g. received from a peer, which must extend the tip and pass
+// the IsChainValid rules; its transactions are removed from the mempool. A block which is already part of the chain
+// is a no-op: AppendBlock returns false without an error, so blocks resent during a sync are harmless.
+// Returns true once the block was appended, ErrInvalidBlock if it does not extend the tip and an error wrapping
+// ErrInvalidChain if it fails validation. Like blocks adopted through ReplaceChain it is not reported to OnNewBlock.
+// Orphan blocks pooled by AddBlockFromNetwork which now extend the tip are appended after it
+func (bc *Blockchain) AppendBlock(block Block) (bool, error) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	appended, err := bc.appendBlockLocked(block)
+	if appended {
+		bc.connectOrphansLocked(block.Hash)
+	}
+	return appended, err
+}
+
+// SubmitMinedBlock accepts a block mined outside the node, e.g. by an external miner, with its nonce included.
+// The block must extend the current tip (index tip+1 and PreviousHash the tip's hash), and it is checked like
+// by AppendBlock, so its transactions must be valid, its coinbase must mint no more than the reward plus the fees
+// (see validateCoinbaseLocked) and its proof of work must satisfy the difficulty and target.
+// Its transactions are removed from the mempool and the block is reported to OnNewBlock like a block mined by
+// MineBlock once the lock is released. Returns ErrStaleBlock for a block built on an earlier tip, including one
+// already in the chain, ErrInvalidBlock for a block which does not build on the chain at all and an error
+// wrapping ErrInvalidChain if it fails validation
+func (bc *Blockchain) SubmitMinedBlock(block Block) error {
+	bc.mu.Lock()
+	tip := bc.Chain[len(bc.Chain)-1]
+	var err error
+	if block.Index <= tip.Index || (block.PreviousHash != tip.Hash && bc.hasBlockLocked(block.PreviousHash)) {
+		err = fmt.Errorf("%w: block %d builds on %s, the tip is block %d %s", ErrStaleBlock,
+			block.Index, shortHash(block.PreviousHash), tip.Index, shortHash(tip.Hash))
+	} else {
+		_, err = bc.appendBlockLocked(block)
 	}
+	bc.mu.Unlock()
 
-	hash := sha256.Sum256([]byte(hashInput))
+	if err != nil {
+		return err
+	}
+
+	bc.notifyNewBlock(block.clone())
+	return nil
+}
+
+// appendBlockLocked is AppendBlock for callers already holding the write lock, without connecting orphan blocks
+func (bc *Blockchain) appendBlockLocked(block Block) (bool, error) {
+	if bc.hasBlockLocked(block.Hash) {
+		return false, nil
+	}
+
+	tip := bc.Chain[len(bc.Chain)-1]
+	if block.Index != tip.Index+1 || block.PreviousHash != tip.Hash {
+		return false, fmt.Errorf("%w: block %d with previous hash %s does not extend tip %d %s", ErrInvalidBlock,
+			block.Index, shortHash(block.PreviousHash), tip.Index, shortHash(tip.Hash))
+	}
+
+	chain := append(bc.Chain[:len(bc.Chain):len(bc.Chain)], block.clone())
+	if err := bc.validateChainLocked(chain); err != nil {
+		return false, fmt.Errorf("block %d is invalid: %w", block.Index, err)
+	}
+
+	if bc.useUTXO {
+		if err := bc.connectBlockUTXOLocked(block); err != nil {
+			return false, fmt.Errorf("block %d is invalid: %w", block.Index, err)
+		}
+	}
+	bc.Chain = chain
+	bc.removeFromMempoolLocked(block.Transactions)
+	bc.rebuildNoncesLocked()
+	bc.adjustDifficulty()
+
+	return true, nil
+}
+
+// GetBalance calculates the balance of an address by iterating over all confirmed transactions in the chain,
+// subtracting the amount and fee of every transaction sent by the address and adding every payment it received,
+// summed per output for transactions paying several recipients.
+// Fees reach the miner through the coinbase transaction. Coinbase rewards count only once they are
+// CoinbaseMaturity blocks deep. With the UTXO model the balance is the sum of the spendable unspent outputs
+// owned by the address instead.
+// The coinbase sender mints new coins, so it is never debited
+func (bc *Blockchain) GetBalance(address string) int64 {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return bc.balanceLocked(address)
+}
+
+// balanceLocked is GetBalance for callers already holding the lock
+func (bc *Blockchain) balanceLocked(address string) int64 {
+	if bc.useUTXO {
+		return bc.utxoBalanceLocked(address)
+	}
+
+	return bc.accountBalanceLocked(address, len(bc.Chain)-1)
+}
+
+// BalanceAt returns the balance the address had once the block at height was added, considering only the blocks
+// up to and including it; coinbase rewards count if they were CoinbaseMaturity blocks deep at that height.
+// Balances at the tip match GetBalance under both models, as UTXO change outputs return to the sender.
+// Returns ErrBlockNotFound if height is out of range or the transactions up to it were dropped by Prune
+func (bc *Blockchain) BalanceAt(address string, height int) (int64, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if height < 0 || height >= len(bc.Chain) {
+		return 0, fmt.Errorf("%w: index %d", ErrBlockNotFound, height)
+	}
+	if bc.Checkpoint != nil && height < bc.Checkpoint.Height {
+		return 0, fmt.Errorf("%w: transactions up to index %d were pruned", ErrBlockNotFound, bc.Checkpoint.Height)
+	}
+
+	return bc.accountBalanceLocked(address, height), nil
+}
+
+// accountBalanceLocked returns the account-model balance of the address after the block at height,
+// starting from the checkpoint balance if the chain was pruned
+func (bc *Blockchain) accountBalanceLocked(address string, height int) int64 {
+	balance := int64(0)
+	if bc.Checkpoint != nil {
+		balance = bc.Checkpoint.Balances[address]
+	}
+	for _, block := range bc.Chain[:height+1] {
+		for _, tx := range block.Transactions {
+			if tx.Sender == address && tx.Sender != coinbaseSender {
+				balance -= tx.Amount + tx.Fee
+			}
+			if tx.Sender == coinbaseSender && !bc.coinbaseMatureAtLocked(block.Index, height) {
+				continue
+			}
+			for _, out := range tx.payments() {
+				if out.Address == address {
+					balance += out.Amount
+				}
+			}
+		}
+	}
+
+	return balance
+}
+
+// GetChain returns a copy of all blocks of the chain, from genesis to tip
+func (bc *Blockchain) GetChain() []Block {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	chain := make([]Block, len(bc.Chain))
+	for i, block := range bc.Chain {
+		chain[i] = block.clone()
+	}
+
+	return chain
+}
+
+// GetLatestBlock returns a copy of the tip of the chain, or ErrEmptyChain if the chain holds no blocks
+func (bc *Blockchain) GetLatestBlock() (Block, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return bc.latestBlockLocked()
+}
+
+// Tip returns the height and hash of the tip of the chain, read under a single lock, so both belong to
+// the same block even while blocks are mined or the chain is replaced. A peer comparing them with its own tip
+// learns whether it has to request blocks without copying any. A chain without blocks yields -1 and ""
+func (bc *Blockchain) Tip() (height int, hash string) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if len(bc.Chain) == 0 {
+		return -1, ""
+	}
+	tip := bc.Chain[len(bc.Chain)-1]
+	return tip.Index, tip.Hash
+}
+
+// latestBlockLocked is GetLatestBlock for callers already holding the lock
+func (bc *Blockchain) latestBlockLocked() (Block, error) {
+	if len(bc.Chain) == 0 {
+		return Block{}, ErrEmptyChain
+	}
+
+	return bc.Chain[len(bc.Chain)-1].clone(), nil
+}
+
+// GetBlocksPage returns copies of at most limit blocks ordered from the tip towards genesis, skipping
+// the offset most recent blocks, together with the total number of blocks. An offset past the genesis block
+// yields an empty page; a negative offset or limit is rejected with ErrInvalidPage
+func (bc *Blockchain) GetBlocksPage(offset, limit int) ([]Block, int, error) {
+	if offset < 0 || limit < 0 {
+		return nil, 0, fmt.Errorf("%w: offset %d and limit %d must not be negative", ErrInvalidPage, offset, limit)
+	}
+
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	total := len(bc.Chain)
+	page := []Block{}
+	for i := total - 1 - offset; i >= 0 && len(page) < limit; i-- {
+		page = append(page, bc.Chain[i].clone())
+	}
+
+	return page, total, nil
+}
+
+// GetBlockByIndex returns a copy of the block at index i, or ErrBlockNotFound if i is out of range
+func (bc *Blockchain) GetBlockByIndex(i int) (Block, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if i < 0 || i >= len(bc.Chain) {
+		return Block{}, fmt.Errorf("%w: index %d", ErrBlockNotFound, i)
+	}
+
+	return bc.Chain[i].clone(), nil
+}
+
+// HasBlock reports whether a block with the given hash is part of the chain, pruned blocks included
+func (bc *Blockchain) HasBlock(hash string) bool {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return bc.hasBlockLocked(hash)
+}
+
+// hasBlockLocked is HasBlock for callers already holding the lock
+func (bc *Blockchain) hasBlockLocked(hash string) bool {
+	for _, block := range bc.Chain {
+		if block.Hash == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// GetBlockByHash scans the chain for the block with the given hash and returns a copy of it,
+// or ErrBlockNotFound if no block has that hash
+func (bc *Blockchain) GetBlockByHash(hash string) (Block, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	for _, block := range bc.Chain {
+		if block.Hash == hash {
+			return block.clone(), nil
+		}
+	}
+
+	return Block{}, fmt.Errorf("%w: hash %s", ErrBlockNotFound, hash)
+}
+
+// FindBlocksByHashPrefix returns copies of all blocks whose hash starts with prefix, ignoring case,
+// in chain order, for a search by the first few characters of a hash; no match yields an empty slice.
+// Returns ErrInvalidHashPrefix if the prefix is empty or contains a character which is not a hex digit
+func (bc *Blockchain) FindBlocksByHashPrefix(prefix string) ([]Block, error) {
+	if prefix == "" {
+		return nil, fmt.Errorf("%w: prefix is empty", ErrInvalidHashPrefix)
+	}
+	for _, r := range prefix {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return nil, fmt.Errorf("%w: %q is not a hex digit", ErrInvalidHashPrefix, r)
+		}
+	}
+	prefix = strings.ToLower(prefix)
+
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	blocks := []Block{}
+	for _, block := range bc.Chain {
+		if strings.HasPrefix(strings.ToLower(block.Hash), prefix) {
+			blocks = append(blocks, block.clone())
+		}
+	}
+
+	return blocks, nil
+}
+
+// clone returns a deep copy of the block which shares no slices with the original
+func (b Block) clone() Block {
+	if b.Transactions != nil {
+		transactions := make([]Transaction, len(b.Transactions))
+		for i, tx := range b.Transactions {
+			transactions[i] = tx.clone()
+		}
+		b.Transactions = transactions
+	}
+
+	return b
+}
+
+// clone returns a deep copy of the transaction which shares no slices with the original
+func (tx Transaction) clone() Transaction {
+	if tx.Signature != nil {
+		tx.Signature = append([]byte{}, tx.Signature...)
+	}
+	if tx.PublicKey != nil {
+		tx.PublicKey = append([]byte{}, tx.PublicKey...)
+	}
+	if tx.Inputs != nil {
+		tx.Inputs = append([]TXInput{}, tx.Inputs...)
+	}
+	if tx.Outputs != nil {
+		tx.Outputs = append([]TXOutput{}, tx.Outputs...)
+	}
+
+	return tx
+}
+
+// FindTransaction searches the chain and then the mempool for the transaction with the given TXID.
+// Returns the transaction and the index of the block containing it, or UnconfirmedBlockIndex
+// if it is still pending in the mempool; ErrTransactionNotFound if it is in neither
+func (bc *Blockchain) FindTransaction(txid string) (Transaction, int, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return bc.findTransactionLocked(txid)
+}
+
+// findTransactionLocked is FindTransaction for callers holding the lock
+func (bc *Blockchain) findTransactionLocked(txid string) (Transaction, int, error) {
+	for _, block := range bc.Chain {
+		for _, tx := range block.Transactions {
+			if tx.TXID == txid {
+				return tx.clone(), block.Index, nil
+			}
+		}
+	}
+
+	for _, tx := range bc.Transactions {
+		if tx.TXID == txid {
+			return tx.clone(), UnconfirmedBlockIndex, nil
+		}
+	}
 
-	return hex.EncodeToString(hash[:])
+	return Transaction{}, 0, fmt.Errorf("%w: %s", ErrTransactionNotFound, txid)
 }
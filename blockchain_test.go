@@ -0,0 +1,990 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestBlockchain returns a chain of difficulty 1, so blocks are mined at once, whose genesis block
+// allocates the given balances
+func newTestBlockchain(t *testing.T, balances map[string]int64) *Blockchain {
+	t.Helper()
+	return createBlockchainWithGenesis(GenesisConfig{
+		Timestamp:  defaultGenesisTimestamp,
+		Difficulty: 1,
+		Balances:   balances,
+	})
+}
+
+// mineTestBlock mines a block paying the reward to miner and fails the test on an error
+func mineTestBlock(t *testing.T, bc *Blockchain, miner string) Block {
+	t.Helper()
+	block, err := bc.MineBlock(miner)
+	if err != nil {
+		t.Fatalf("MineBlock(%q): %v", miner, err)
+	}
+	return block
+}
+
+// sealTestBlock builds the block following the tip of bc with the given transactions, as an external miner
+// would, and searches a nonce meeting the difficulty of bc
+func sealTestBlock(t *testing.T, bc *Blockchain, transactions []Transaction) Block {
+	t.Helper()
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	tip := bc.Chain[len(bc.Chain)-1]
+	block := Block{
+		BlockHeader: BlockHeader{
+			Index:        tip.Index + 1,
+			Timestamp:    bc.nextBlockTimestampLocked(),
+			Difficulty:   bc.Difficulty,
+			PreviousHash: tip.Hash,
+			MerkleRoot:   computeMerkleRoot(bc.hasher(), transactions),
+		},
+		Transactions: transactions,
+	}
+	for !bc.meetsProofLocked(block.BlockHeader) {
+		block.Nonce++
+	}
+	block.Hash = calculateHash(bc.hasher(), block.BlockHeader)
+
+	return block
+}
+
+// testCoinbase returns the coinbase transaction of the next block of bc paying amount to miner
+func testCoinbase(bc *Blockchain, miner string, amount int64) Transaction {
+	return newCoinbaseTransaction(bc.hasher(), miner, amount, len(bc.Chain))
+}
+
+func TestProofOfWorkErrors(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		difficulty    int
+		empty         bool
+		maxIterations uint64
+		wantErr       error
+	}{
+		{"found", context.Background(), 1, false, defaultMaxIterations, nil},
+		{"iteration limit", context.Background(), maxDifficulty, false, 100, ErrProofNotFound},
+		{"no iterations", context.Background(), 1, false, 0, ErrProofNotFound},
+		{"difficulty too low", context.Background(), minDifficulty - 1, false, defaultMaxIterations, ErrInvalidDifficulty},
+		{"difficulty too high", context.Background(), maxDifficulty + 1, false, defaultMaxIterations, ErrInvalidDifficulty},
+		{"empty chain", context.Background(), 1, true, defaultMaxIterations, ErrEmptyChain},
+		{"cancelled", cancelled, maxDifficulty, false, defaultMaxIterations, context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := newTestBlockchain(t, nil)
+			bc.Difficulty = tt.difficulty
+			if tt.empty {
+				bc.Chain = nil
+			}
+
+			bc.mu.RLock()
+			nonce, timestamp, err := bc.proofOfWorkLocked(tt.ctx, tt.maxIterations)
+			bc.mu.RUnlock()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("proofOfWorkLocked() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if nonce != 0 || timestamp != 0 {
+					t.Errorf("proofOfWorkLocked() = %d, %d with an error, want zeros", nonce, timestamp)
+				}
+				return
+			}
+			if nonce >= tt.maxIterations || timestamp == 0 {
+				t.Errorf("proofOfWorkLocked() = %d, %d, want a nonce below %d and a timestamp", nonce, timestamp, tt.maxIterations)
+			}
+		})
+	}
+}
+
+func TestValidateTransactionFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		tx      Transaction
+		wantErr bool
+	}{
+		{"valid", Transaction{Sender: "alice", Recipient: "bob", Amount: 1}, false},
+		{"blank sender", Transaction{Sender: " ", Recipient: "bob", Amount: 1}, true},
+		{"coinbase sender", Transaction{Sender: coinbaseSender, Recipient: "bob", Amount: 1}, true},
+		{"blank recipient", Transaction{Sender: "alice", Amount: 1}, true},
+		{"sender as recipient", Transaction{Sender: "alice", Recipient: "alice", Amount: 1}, true},
+		{"zero amount", Transaction{Sender: "alice", Recipient: "bob"}, true},
+		{"negative fee", Transaction{Sender: "alice", Recipient: "bob", Amount: 1, Fee: -1}, true},
+		{"negative expiry", Transaction{Sender: "alice", Recipient: "bob", Amount: 1, ExpiryTime: -1}, true},
+		{"invalid utf-8 memo", Transaction{Sender: "alice", Recipient: "bob", Amount: 1, Memo: "\x80"}, true},
+		{"outputs not matching amount", Transaction{Sender: "alice", Recipient: "bob", Amount: 2, Outputs: []TXOutput{{Address: "bob", Amount: 1}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTransactionFields(tt.tx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateTransactionFields() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidTransaction) {
+				t.Errorf("error %v does not wrap ErrInvalidTransaction", err)
+			}
+		})
+	}
+}
+
+func TestSubmitTransactionRejectsCoinbaseSender(t *testing.T) {
+	bc := newTestBlockchain(t, nil)
+
+	_, err := bc.submitTransaction(Transaction{Sender: coinbaseSender, Recipient: "mallory", Amount: 1_000_000_000_000_000})
+	if !errors.Is(err, ErrInvalidTransaction) {
+		t.Fatalf("submitTransaction() error = %v, want ErrInvalidTransaction", err)
+	}
+
+	_, errs := bc.AddTransactions([]Transaction{{Sender: coinbaseSender, Recipient: "mallory", Amount: 1}})
+	if !errors.Is(errs[0], ErrInvalidTransaction) {
+		t.Fatalf("AddTransactions() error = %v, want ErrInvalidTransaction", errs[0])
+	}
+
+	mineTestBlock(t, bc, "miner")
+	if balance := bc.GetBalance("mallory"); balance != 0 {
+		t.Errorf("mallory has %d units, want 0", balance)
+	}
+}
+
+func TestSubmitMinedBlock(t *testing.T) {
+	tests := []struct {
+		name    string
+		block   func(bc *Blockchain) Block
+		wantErr error
+	}{
+		{
+			name: "valid",
+			block: func(bc *Blockchain) Block {
+				return sealTestBlock(t, bc, []Transaction{testCoinbase(bc, "miner", bc.BlockReward)})
+			},
+		},
+		{
+			name: "stale",
+			block: func(bc *Blockchain) Block {
+				stale := sealTestBlock(t, bc, []Transaction{testCoinbase(bc, "miner", bc.BlockReward)})
+				mineTestBlock(t, bc, "other")
+				return stale
+			},
+			wantErr: ErrStaleBlock,
+		},
+		{
+			name: "no coinbase",
+			block: func(bc *Blockchain) Block {
+				return sealTestBlock(t, bc, []Transaction{})
+			},
+			wantErr: ErrInvalidChain,
+		},
+		{
+			name: "coinbase above reward",
+			block: func(bc *Blockchain) Block {
+				return sealTestBlock(t, bc, []Transaction{testCoinbase(bc, "miner", bc.BlockReward+1)})
+			},
+			wantErr: ErrInvalidChain,
+		},
+		{
+			name: "two coinbases",
+			block: func(bc *Blockchain) Block {
+				first := testCoinbase(bc, "mallory", 1<<60)
+				second := newCoinbaseTransaction(bc.hasher(), "mallory", 1<<60, len(bc.Chain)+1)
+				return sealTestBlock(t, bc, []Transaction{first, second})
+			},
+			wantErr: ErrInvalidChain,
+		},
+		{
+			name: "coinbase not first",
+			block: func(bc *Blockchain) Block {
+				transfer := Transaction{Sender: "alice", Recipient: "bob", Amount: 10, Nonce: 1}
+				transfer.TXID = generateTransactionID(bc.hasher(), transfer)
+				return sealTestBlock(t, bc, []Transaction{transfer, testCoinbase(bc, "miner", bc.BlockReward)})
+			},
+			wantErr: ErrInvalidChain,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := newTestBlockchain(t, map[string]int64{"alice": 100})
+			block := tt.block(bc)
+			height, _ := bc.Tip()
+
+			err := bc.SubmitMinedBlock(block)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil) != (err == nil) {
+				t.Fatalf("SubmitMinedBlock() error = %v, want %v", err, tt.wantErr)
+			}
+			if newHeight, _ := bc.Tip(); (newHeight == height+1) != (err == nil) {
+				t.Errorf("tip height went from %d to %d", height, newHeight)
+			}
+			if balance := bc.GetBalance("mallory"); balance != 0 {
+				t.Errorf("mallory has %d units, want 0", balance)
+			}
+		})
+	}
+}
+
+func TestBlockNoncesMustIncrease(t *testing.T) {
+	tests := []struct {
+		name    string
+		nonce   uint64
+		wantErr bool
+	}{
+		{"next nonce", 2, false},
+		{"gap", 5, false},
+		{"reused nonce", 1, true},
+		{"zero nonce", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := newTestBlockchain(t, map[string]int64{"alice": 100})
+			if _, err := bc.submitTransaction(Transaction{Sender: "alice", Recipient: "bob", Amount: 10}); err != nil {
+				t.Fatalf("submitTransaction(): %v", err)
+			}
+			mineTestBlock(t, bc, "miner")
+
+			tx := Transaction{Sender: "alice", Recipient: "bob", Amount: 20, Nonce: tt.nonce}
+			tx.TXID = generateTransactionID(bc.hasher(), tx)
+			err := bc.SubmitMinedBlock(sealTestBlock(t, bc, []Transaction{testCoinbase(bc, "miner", bc.BlockReward), tx}))
+			if tt.wantErr && (!errors.Is(err, ErrInvalidChain) || !errors.Is(err, ErrInvalidNonce)) {
+				t.Fatalf("SubmitMinedBlock() error = %v, want ErrInvalidChain and ErrInvalidNonce", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("SubmitMinedBlock() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestMinedBlockKeepsNonceOrder(t *testing.T) {
+	bc := newTestBlockchain(t, map[string]int64{"alice": 100})
+	for _, fee := range []int64{1, 5, 3} {
+		if _, err := bc.submitTransaction(Transaction{Sender: "alice", Recipient: "bob", Amount: 10, Fee: fee}); err != nil {
+			t.Fatalf("submitTransaction(): %v", err)
+		}
+	}
+
+	block := mineTestBlock(t, bc, "miner")
+	if len(block.Transactions) != 4 {
+		t.Fatalf("mined %d transactions, want the coinbase and 3 transfers", len(block.Transactions))
+	}
+	for i, tx := range block.Transactions[1:] {
+		if tx.Nonce != uint64(i+1) {
+			t.Errorf("transaction %d has nonce %d, want %d", i+1, tx.Nonce, i+1)
+		}
+	}
+	if valid, err := bc.IsChainValid(); !valid {
+		t.Errorf("IsChainValid(): %v", err)
+	}
+}
+
+func TestMempoolRejectsConfirmedNonces(t *testing.T) {
+	bc := newTestBlockchain(t, map[string]int64{"alice": 100})
+	if _, err := bc.submitTransaction(Transaction{Sender: "alice", Recipient: "bob", Amount: 10}); err != nil {
+		t.Fatalf("submitTransaction(): %v", err)
+	}
+	pending := bc.Mempool()[0]
+
+	// a peer confirms a different transaction with the same nonce
+	tx := Transaction{Sender: "alice", Recipient: "carol", Amount: 20, Nonce: pending.Nonce}
+	tx.TXID = generateTransactionID(bc.hasher(), tx)
+	if err := bc.SubmitMinedBlock(sealTestBlock(t, bc, []Transaction{testCoinbase(bc, "other", bc.BlockReward), tx})); err != nil {
+		t.Fatalf("SubmitMinedBlock(): %v", err)
+	}
+
+	bc.mu.RLock()
+	err := bc.mempoolRejectionsLocked()[pending.TXID]
+	bc.mu.RUnlock()
+	if !errors.Is(err, ErrInvalidNonce) {
+		t.Errorf("rejection of the pending transaction = %v, want ErrInvalidNonce", err)
+	}
+}
+
+func TestChainRejectsConfirmedTransactionAgain(t *testing.T) {
+	tests := []struct {
+		name   string
+		replay func(bc *Blockchain) Transaction
+	}{
+		{"transfer", func(bc *Blockchain) Transaction { return bc.Chain[1].Transactions[1] }},
+		{"genesis allocation", func(bc *Blockchain) Transaction { return bc.Chain[0].Transactions[0] }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := newTestBlockchain(t, map[string]int64{"alice": 100})
+			if _, err := bc.submitTransaction(Transaction{Sender: "alice", Recipient: "bob", Amount: 10}); err != nil {
+				t.Fatalf("submitTransaction(): %v", err)
+			}
+			mineTestBlock(t, bc, "miner")
+
+			block := sealTestBlock(t, bc, []Transaction{testCoinbase(bc, "miner", bc.BlockReward), tt.replay(bc)})
+			if err := bc.SubmitMinedBlock(block); !errors.Is(err, ErrInvalidChain) || !errors.Is(err, ErrDuplicateTransaction) {
+				t.Fatalf("SubmitMinedBlock() error = %v, want ErrInvalidChain and ErrDuplicateTransaction", err)
+			}
+		})
+	}
+}
+
+func TestConcurrentTransactionsBalancesAndMining(t *testing.T) {
+	bc := newTestBlockchain(t, map[string]int64{"alice": 1000})
+	const writers, perWriter = 4, 25
+
+	var wg sync.WaitGroup
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perWriter {
+				if _, err := bc.addTransaction("alice", "bob", 1); err != nil {
+					t.Errorf("addTransaction(): %v", err)
+				}
+			}
+		}()
+	}
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			last := int64(0)
+			for range 100 {
+				// bob is only ever paid, so a consistent reader never sees that balance shrink
+				balance := bc.GetBalance("bob")
+				if balance < last || balance > writers*perWriter {
+					t.Errorf("GetBalance(bob) = %d after %d", balance, last)
+				}
+				last = balance
+				_ = bc.Mempool()
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 5 {
+			if _, err := bc.MineBlock("miner"); err != nil {
+				t.Errorf("MineBlock(): %v", err)
+			}
+		}
+	}()
+	wg.Wait()
+
+	for bc.PendingCount() > 0 {
+		mineTestBlock(t, bc, "miner")
+	}
+	if alice, bob := bc.GetBalance("alice"), bc.GetBalance("bob"); alice != 1000-writers*perWriter || bob != writers*perWriter {
+		t.Errorf("balances alice = %d, bob = %d, want %d and %d", alice, bob, 1000-writers*perWriter, writers*perWriter)
+	}
+	if valid, err := bc.IsChainValid(); !valid {
+		t.Errorf("IsChainValid() = false: %v", err)
+	}
+}
+
+func TestTipIsConsistentUnderConcurrentMining(t *testing.T) {
+	bc := newTestBlockchain(t, nil)
+	const blocks = 20
+
+	type tip struct {
+		height int
+		hash   string
+	}
+	tips := make(chan tip, 4*1000)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			last := 0
+			for range 1000 {
+				height, hash := bc.Tip()
+				if height < last {
+					t.Errorf("Tip() height went from %d back to %d", last, height)
+				}
+				last = height
+				tips <- tip{height, hash}
+			}
+		}()
+	}
+	go func() {
+		defer close(done)
+		for range blocks {
+			if _, err := bc.MineBlock("miner"); err != nil {
+				t.Errorf("MineBlock(): %v", err)
+			}
+		}
+	}()
+	wg.Wait()
+	<-done
+	close(tips)
+
+	chain := bc.GetChain()
+	for tip := range tips {
+		if tip.height >= len(chain) || chain[tip.height].Hash != tip.hash {
+			t.Fatalf("Tip() = %d, %s, which is not block %d of the chain", tip.height, tip.hash, tip.height)
+		}
+	}
+	if height, hash := bc.Tip(); height != blocks || hash != chain[blocks].Hash {
+		t.Errorf("Tip() = %d, %s, want %d, %s", height, hash, blocks, chain[blocks].Hash)
+	}
+}
+
+func TestMinedHashesMeetDifficulty(t *testing.T) {
+	for _, difficulty := range []int{1, 3} {
+		t.Run(fmt.Sprintf("difficulty %d", difficulty), func(t *testing.T) {
+			bc := createBlockchainWithGenesis(GenesisConfig{Timestamp: defaultGenesisTimestamp, Difficulty: difficulty})
+			for range 3 {
+				block := mineTestBlock(t, bc, "miner")
+				if block.Difficulty != difficulty || !hasLeadingZeroBits(block.Hash, difficulty) {
+					t.Errorf("block %d of difficulty %d has hash %s, want %d leading zero bits", block.Index, block.Difficulty, block.Hash, difficulty)
+				}
+			}
+		})
+	}
+}
+
+func TestGetBlockByIndexAndHash(t *testing.T) {
+	bc := newTestBlockchain(t, map[string]int64{"alice": 1000})
+	if _, err := bc.addTransaction("alice", "bob", 10); err != nil {
+		t.Fatalf("addTransaction(): %v", err)
+	}
+	mined := mineTestBlock(t, bc, "miner")
+
+	tests := []struct {
+		name    string
+		lookup  func() (Block, error)
+		want    Block
+		wantErr error
+	}{
+		{"index found", func() (Block, error) { return bc.GetBlockByIndex(1) }, mined, nil},
+		{"index negative", func() (Block, error) { return bc.GetBlockByIndex(-1) }, Block{}, ErrBlockNotFound},
+		{"index past tip", func() (Block, error) { return bc.GetBlockByIndex(2) }, Block{}, ErrBlockNotFound},
+		{"hash found", func() (Block, error) { return bc.GetBlockByHash(mined.Hash) }, mined, nil},
+		{"hash not found", func() (Block, error) { return bc.GetBlockByHash("unknown") }, Block{}, ErrBlockNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block, err := tt.lookup()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("lookup error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(block, tt.want) {
+				t.Errorf("lookup = %+v, want %+v", block, tt.want)
+			}
+		})
+	}
+
+	// the blocks returned are copies
+	block, _ := bc.GetBlockByIndex(1)
+	block.Transactions[1].Amount = 1
+	if bc.GetChain()[1].Transactions[1].Amount != 10 {
+		t.Errorf("modifying a returned block changed the chain")
+	}
+}
+
+func TestReplaceChain(t *testing.T) {
+	tests := []struct {
+		name         string
+		blocks       int // blocks the incoming chain mines on top of the shared genesis
+		tamper       bool
+		wantReplaced bool
+		wantErr      error
+	}{
+		{"longer valid", 3, false, true, nil},
+		{"longer invalid", 3, true, false, ErrInvalidChain},
+		{"same length", 2, false, false, nil},
+		{"shorter", 1, false, false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := newTestBlockchain(t, map[string]int64{"alice": 1000})
+			mineTestBlock(t, bc, "local")
+			mineTestBlock(t, bc, "local")
+			before := bc.GetChain()
+
+			incoming := newTestBlockchain(t, map[string]int64{"alice": 1000})
+			for range tt.blocks {
+				mineTestBlock(t, incoming, "remote")
+			}
+			chain := incoming.GetChain()
+			if tt.tamper {
+				chain[2].Transactions[0].Amount++
+			}
+
+			replaced, err := bc.ReplaceChain(chain)
+			if replaced != tt.wantReplaced || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReplaceChain() = %v, %v, want %v, %v", replaced, err, tt.wantReplaced, tt.wantErr)
+			}
+			want := before
+			if tt.wantReplaced {
+				want = incoming.GetChain()
+			}
+			if !reflect.DeepEqual(bc.GetChain(), want) {
+				t.Errorf("chain after ReplaceChain() is not the expected one")
+			}
+		})
+	}
+}
+
+func TestIdenticalTransfersGetDistinctTXIDs(t *testing.T) {
+	bc := newTestBlockchain(t, map[string]int64{"alice": 1000})
+
+	first, err := bc.addTransaction("alice", "bob", 10)
+	if err != nil {
+		t.Fatalf("addTransaction(): %v", err)
+	}
+	second, err := bc.addTransaction("alice", "bob", 10)
+	if err != nil {
+		t.Fatalf("addTransaction() of the same transfer again: %v", err)
+	}
+	mineTestBlock(t, bc, "miner")
+	third, err := bc.addTransaction("alice", "bob", 10)
+	if err != nil {
+		t.Fatalf("addTransaction() after mining: %v", err)
+	}
+
+	if first == second || second == third || first == third {
+		t.Errorf("identical transfers got TXIDs %s, %s and %s, want distinct ones", first, second, third)
+	}
+	mineTestBlock(t, bc, "miner")
+	if got := bc.GetBalance("bob"); got != 30 {
+		t.Errorf("GetBalance(bob) = %d, want 30", got)
+	}
+}
+
+func TestProofOfWorkCtxTimesOut(t *testing.T) {
+	bc := newTestBlockchain(t, nil)
+	bc.Difficulty = maxDifficulty
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, _, err := bc.ProofOfWorkCtx(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ProofOfWorkCtx() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("ProofOfWorkCtx() returned %s after the 10ms deadline", elapsed)
+	}
+
+	if _, err := bc.MineBlockCtx(ctx, "miner"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("MineBlockCtx() error = %v, want context.DeadlineExceeded", err)
+	}
+	if height, _ := bc.Tip(); height != 0 {
+		t.Errorf("Tip() height = %d after cancelled mining, want 0", height)
+	}
+}
+
+func TestGetBlocksPage(t *testing.T) {
+	bc := newTestBlockchain(t, nil)
+	for range 4 {
+		mineTestBlock(t, bc, "miner")
+	}
+
+	tests := []struct {
+		name        string
+		offset      int
+		limit       int
+		wantIndices []int
+		wantErr     error
+	}{
+		{"first page", 0, 2, []int{4, 3}, nil},
+		{"second page", 2, 2, []int{2, 1}, nil},
+		{"last page is short", 4, 2, []int{0}, nil},
+		{"offset past genesis", 5, 2, []int{}, nil},
+		{"limit zero", 0, 0, []int{}, nil},
+		{"negative offset", -5, 3, nil, ErrInvalidPage},
+		{"negative limit", 0, -1, nil, ErrInvalidPage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, total, err := bc.GetBlocksPage(tt.offset, tt.limit)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetBlocksPage() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			indices := []int{}
+			for _, block := range page {
+				indices = append(indices, block.Index)
+			}
+			if !reflect.DeepEqual(indices, tt.wantIndices) || total != 5 {
+				t.Errorf("GetBlocksPage() = blocks %v of %d, want %v of 5", indices, total, tt.wantIndices)
+			}
+		})
+	}
+}
+
+func TestHasLeadingZeroBits(t *testing.T) {
+	pad := func(prefix string) string { return prefix + strings.Repeat("f", 64-len(prefix)) }
+
+	tests := []struct {
+		hash string
+		bits int
+		want bool
+	}{
+		{pad("07"), 5, true},
+		{pad("07"), 6, false},
+		{pad("08"), 5, false},
+		{pad("007f"), 9, true},
+		{pad("007f"), 10, false},
+		{pad("0080"), 9, false},
+		{pad("000f"), 12, true},
+		{pad("000f"), 13, false},
+		{pad("0010"), 12, false},
+		{pad(""), 0, true},
+		{strings.Repeat("0", 64), 256, true},
+		{strings.Repeat("0", 64), 257, false},
+		{pad("0"), -1, false},
+		{"zz" + strings.Repeat("0", 62), 1, false},
+	}
+
+	for _, tt := range tests {
+		if got := hasLeadingZeroBits(tt.hash, tt.bits); got != tt.want {
+			t.Errorf("hasLeadingZeroBits(%s, %d) = %v, want %v", tt.hash[:6], tt.bits, got, tt.want)
+		}
+	}
+}
+
+func TestSerializeForHashLayout(t *testing.T) {
+	header := BlockHeader{Index: 1, Timestamp: 2, Nonce: 3, Difficulty: 4, PreviousHash: "ab", MerkleRoot: "c", Hash: "ignored"}
+	want := "0000000000000001" + "0000000000000002" + "0000000000000003" + "0000000000000004" +
+		"00000002" + "6162" + "00000001" + "63"
+	if got := fmt.Sprintf("%x", header.serializeForHash()); got != want {
+		t.Errorf("serializeForHash() = %s, want %s", got, want)
+	}
+	if a, b := header.serializeForHash(), header.serializeForHash(); !reflect.DeepEqual(a, b) {
+		t.Errorf("serializeForHash() is not stable: %x, then %x", a, b)
+	}
+}
+
+func TestSerializeForHashFieldBoundaries(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b BlockHeader
+	}{
+		{"previous hash and Merkle root", BlockHeader{PreviousHash: "ab", MerkleRoot: "c"}, BlockHeader{PreviousHash: "a", MerkleRoot: "bc"}},
+		{"empty previous hash", BlockHeader{PreviousHash: "", MerkleRoot: "abc"}, BlockHeader{PreviousHash: "abc", MerkleRoot: ""}},
+		{"timestamp and nonce", BlockHeader{Timestamp: 1, Nonce: 0}, BlockHeader{Timestamp: 0, Nonce: 1}},
+		{"nonce and difficulty", BlockHeader{Nonce: 1, Difficulty: 23}, BlockHeader{Nonce: 12, Difficulty: 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if a, b := tt.a.serializeForHash(), tt.b.serializeForHash(); reflect.DeepEqual(a, b) {
+				t.Errorf("both headers serialize to %x", a)
+			}
+			if calculateHash(SHA256Hasher{}, tt.a) == calculateHash(SHA256Hasher{}, tt.b) {
+				t.Error("both headers have the same hash")
+			}
+		})
+	}
+}
+
+func TestHashInputsOfFormerlyConcatenatedFieldsDiffer(t *testing.T) {
+	// without separators index 12 and nonce 3 read "12" + "3", the same as index 1 and nonce 23
+	a := BlockHeader{Index: 12, Timestamp: defaultGenesisTimestamp, Nonce: 3, Difficulty: 1, PreviousHash: "0", MerkleRoot: "root"}
+	b := BlockHeader{Index: 1, Timestamp: defaultGenesisTimestamp, Nonce: 23, Difficulty: 1, PreviousHash: "0", MerkleRoot: "root"}
+	if reflect.DeepEqual(a.serializeForHash(), b.serializeForHash()) {
+		t.Errorf("index 12/nonce 3 and index 1/nonce 23 share the hash input %x", a.serializeForHash())
+	}
+	if calculateHash(SHA256Hasher{}, a) == calculateHash(SHA256Hasher{}, b) {
+		t.Error("index 12/nonce 3 and index 1/nonce 23 have the same hash")
+	}
+}
+
+func TestSerializeForIDFieldBoundaries(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Transaction
+	}{
+		{"sender and recipient", Transaction{Sender: "ab", Recipient: "c", Amount: 1}, Transaction{Sender: "a", Recipient: "bc", Amount: 1}},
+		{"amount and fee", Transaction{Sender: "a", Recipient: "b", Amount: 12, Fee: 3}, Transaction{Sender: "a", Recipient: "b", Amount: 1, Fee: 23}},
+		{"recipient and memo", Transaction{Sender: "a", Recipient: "bc", Amount: 1}, Transaction{Sender: "a", Recipient: "b", Amount: 1, Memo: "c"}},
+		{"output address and amount", Transaction{Sender: "a", Recipient: "b1", Amount: 2, Outputs: []TXOutput{{Address: "b1", Amount: 2}}},
+			Transaction{Sender: "a", Recipient: "b", Amount: 12, Outputs: []TXOutput{{Address: "b", Amount: 12}}}},
+		{"inputs and outputs", Transaction{Sender: "a", Recipient: "b", Amount: 1, Inputs: []TXInput{{TXID: "x"}}},
+			Transaction{Sender: "a", Recipient: "b", Amount: 1, Outputs: []TXOutput{{Address: "x"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if a, b := tt.a.serializeForID(), tt.b.serializeForID(); reflect.DeepEqual(a, b) {
+				t.Errorf("both transactions serialize to %x", a)
+			}
+			if generateTransactionID(SHA256Hasher{}, tt.a) == generateTransactionID(SHA256Hasher{}, tt.b) {
+				t.Error("both transactions have the same TXID")
+			}
+		})
+	}
+}
+
+func TestVerifyBlockPoW(t *testing.T) {
+	bc := createBlockchainWithGenesis(GenesisConfig{Timestamp: defaultGenesisTimestamp, Difficulty: 8})
+	mineTestBlock(t, bc, "miner")
+	mined := bc.GetChain()[1]
+
+	tests := []struct {
+		name    string
+		index   int
+		tamper  func(b *Block)
+		wantErr error
+		// wantMsg is a substring of the error naming the failed check
+		wantMsg string
+	}{
+		{"mined block", 1, nil, nil, ""},
+		{"genesis block", 0, nil, nil, ""},
+		{"tampered nonce", 1, func(b *Block) { b.Nonce++ }, ErrInvalidBlock, "stored hash does not match"},
+		{"tampered nonce with recalculated hash", 1, func(b *Block) {
+			// a nonce whose hash misses the difficulty, with the stored hash updated to match it
+			for b.Nonce = mined.Nonce + 1; bc.meetsProofLocked(b.BlockHeader); b.Nonce++ {
+			}
+			b.Hash = calculateHash(bc.hasher(), b.BlockHeader)
+		}, ErrInvalidBlock, "invalid proof of work"},
+		{"negative index", -1, nil, ErrBlockNotFound, "index -1"},
+		{"index past the tip", 2, nil, ErrBlockNotFound, "index 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc.mu.Lock()
+			bc.Chain[1] = mined.clone()
+			if tt.tamper != nil {
+				tt.tamper(&bc.Chain[1])
+			}
+			bc.mu.Unlock()
+
+			valid, err := bc.VerifyBlockPoW(tt.index)
+			if valid != (tt.wantErr == nil) || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyBlockPoW(%d) = %v, %v, want error %v", tt.index, valid, err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("error = %q, want it to mention %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestMultiTransactionPaysEveryOutput(t *testing.T) {
+	outputs := []TXOutput{{Address: "bob", Amount: 10}, {Address: "carol", Amount: 20}, {Address: "dave", Amount: 30}}
+
+	for _, utxo := range []bool{false, true} {
+		t.Run(map[bool]string{false: "account", true: "utxo"}[utxo], func(t *testing.T) {
+			bc := newTestBlockchain(t, map[string]int64{"alice": 100})
+			if utxo {
+				bc.EnableUTXO()
+			}
+			if _, err := bc.addMultiTransaction("alice", outputs); err != nil {
+				t.Fatalf("addMultiTransaction(): %v", err)
+			}
+			mineTestBlock(t, bc, "miner")
+
+			for address, want := range map[string]int64{"alice": 40, "bob": 10, "carol": 20, "dave": 30} {
+				if got := bc.GetBalance(address); got != want {
+					t.Errorf("%s has %d units, want %d", address, got, want)
+				}
+			}
+			if valid, err := bc.IsChainValid(); !valid {
+				t.Errorf("IsChainValid() = false: %v", err)
+			}
+		})
+	}
+}
+
+func TestMultiTransactionID(t *testing.T) {
+	outputs := []TXOutput{{Address: "bob", Amount: 10}, {Address: "carol", Amount: 20}, {Address: "dave", Amount: 30}}
+	tx := NewMultiTransaction("alice", outputs)
+	if tx.Recipient != "bob" || tx.Amount != 60 || len(tx.Outputs) != 3 {
+		t.Fatalf("NewMultiTransaction() = recipient %s, amount %d, %d outputs, want bob, 60 and 3", tx.Recipient, tx.Amount, len(tx.Outputs))
+	}
+	outputs[0].Amount = 99
+	if tx.Outputs[0].Amount != 10 {
+		t.Error("NewMultiTransaction() does not copy the outputs")
+	}
+	outputs[0].Amount = 10
+
+	txid := generateTransactionID(SHA256Hasher{}, tx)
+	tests := []struct {
+		name     string
+		outputs  []TXOutput
+		wantSame bool
+	}{
+		{"same outputs", []TXOutput{{Address: "bob", Amount: 10}, {Address: "carol", Amount: 20}, {Address: "dave", Amount: 30}}, true},
+		{"reordered outputs", []TXOutput{{Address: "carol", Amount: 20}, {Address: "bob", Amount: 10}, {Address: "dave", Amount: 30}}, false},
+		{"changed amount", []TXOutput{{Address: "bob", Amount: 10}, {Address: "carol", Amount: 21}, {Address: "dave", Amount: 29}}, false},
+		{"dropped output", []TXOutput{{Address: "bob", Amount: 10}, {Address: "carol", Amount: 50}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := generateTransactionID(SHA256Hasher{}, NewMultiTransaction("alice", tt.outputs))
+			if (got == txid) != tt.wantSame {
+				t.Errorf("TXID %s, TXID of the original outputs %s, want same %v", got, txid, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestTamperedGenesisIsRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(b *Block)
+		// wantMsg is a substring of the error naming the failed check
+		wantMsg string
+	}{
+		{"index", func(b *Block) { b.Index = 1 }, "index is 1"},
+		{"previous hash", func(b *Block) { b.PreviousHash = strings.Repeat("0", 64) }, "previous hash is"},
+		{"timestamp without a recalculated hash", func(b *Block) { b.Timestamp++ }, "stored hash does not match"},
+		{"Merkle root", func(b *Block) { b.Transactions[0].TXID = strings.Repeat("0", 64) }, "merkle root does not match"},
+		{"allocation under its TXID", func(b *Block) { b.Transactions[0].Amount = 1 << 40 }, "allocation"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := newTestBlockchain(t, map[string]int64{"alice": 100})
+			mineTestBlock(t, bc, "miner")
+			blocks := bc.GetChain()
+			tt.tamper(&blocks[0])
+			if !strings.Contains(tt.name, "without") {
+				blocks[0].Hash = calculateHash(bc.hasher(), blocks[0].BlockHeader)
+			}
+
+			bc.mu.Lock()
+			bc.Chain[0] = blocks[0].clone()
+			bc.mu.Unlock()
+			paths := map[string]func() error{
+				"IsChainValid": func() error {
+					_, err := bc.IsChainValid()
+					return err
+				},
+				"RebuildFromBlocks": func() error {
+					_, err := RebuildFromBlocks(blocks, RebuildConfig{})
+					return err
+				},
+			}
+			for path, validate := range paths {
+				err := validate()
+				if !errors.Is(err, ErrInvalidGenesis) || !errors.Is(err, ErrInvalidChain) {
+					t.Errorf("%s() error = %v, want ErrInvalidGenesis and ErrInvalidChain", path, err)
+				} else if !strings.Contains(err.Error(), tt.wantMsg) {
+					t.Errorf("%s() error = %q, want it to mention %q", path, err, tt.wantMsg)
+				}
+			}
+		})
+	}
+}
+
+func TestBalanceAt(t *testing.T) {
+	for _, utxo := range []bool{false, true} {
+		t.Run(map[bool]string{false: "account", true: "utxo"}[utxo], func(t *testing.T) {
+			bc := newTestBlockchain(t, map[string]int64{"alice": 100})
+			if utxo {
+				bc.EnableUTXO()
+			}
+			for _, amount := range []int64{10, 20, 30} {
+				if _, err := bc.addTransaction("alice", "bob", amount); err != nil {
+					t.Fatalf("addTransaction(): %v", err)
+				}
+				mineTestBlock(t, bc, "miner")
+			}
+
+			tests := []struct {
+				address string
+				height  int
+				want    int64
+				wantErr error
+			}{
+				{"alice", 0, 100, nil},
+				{"alice", 1, 90, nil},
+				{"bob", 2, 30, nil},
+				{"alice", 3, 40, nil},
+				{"bob", 3, 60, nil},
+				{"miner", 0, 0, nil},
+				{"miner", 3, 3 * bc.BlockReward, nil},
+				{"alice", -1, 0, ErrBlockNotFound},
+				{"alice", 4, 0, ErrBlockNotFound},
+			}
+			for _, tt := range tests {
+				got, err := bc.BalanceAt(tt.address, tt.height)
+				if !errors.Is(err, tt.wantErr) || got != tt.want {
+					t.Errorf("BalanceAt(%q, %d) = %d, %v, want %d, %v", tt.address, tt.height, got, err, tt.want, tt.wantErr)
+				}
+			}
+			for _, address := range []string{"alice", "bob", "miner"} {
+				if at, _ := bc.BalanceAt(address, 3); at != bc.GetBalance(address) {
+					t.Errorf("BalanceAt(%q) at the tip = %d, GetBalance() = %d", address, at, bc.GetBalance(address))
+				}
+			}
+		})
+	}
+}
+
+func TestBalanceAtPrunedHeight(t *testing.T) {
+	bc := newTestBlockchain(t, map[string]int64{"alice": 100})
+	for range 3 {
+		mineTestBlock(t, bc, "miner")
+	}
+	if err := bc.Prune(1); err != nil {
+		t.Fatalf("Prune(): %v", err)
+	}
+
+	if _, err := bc.BalanceAt("alice", 0); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("BalanceAt() below the checkpoint error = %v, want ErrBlockNotFound", err)
+	}
+	if got, err := bc.BalanceAt("alice", 3); err != nil || got != 100 {
+		t.Errorf("BalanceAt() at the tip = %d, %v, want 100", got, err)
+	}
+}
+
+func TestAppendBlockTwiceIsNoOp(t *testing.T) {
+	balances := map[string]int64{"alice": 100}
+	miner, bc := newTestBlockchain(t, balances), newTestBlockchain(t, balances)
+	if _, err := miner.addTransaction("alice", "bob", 10); err != nil {
+		t.Fatalf("addTransaction(): %v", err)
+	}
+	first := mineTestBlock(t, miner, "miner")
+	second := mineTestBlock(t, miner, "miner")
+	notified := 0
+	bc.OnNewBlock(func(Block) { notified++ })
+
+	for _, block := range []Block{first, second} {
+		if appended, err := bc.AppendBlock(block); !appended || err != nil {
+			t.Fatalf("AppendBlock(%d) = %v, %v, want true", block.Index, appended, err)
+		}
+	}
+	chain := bc.GetChain()
+
+	for _, block := range []Block{second, first, chain[0]} {
+		if appended, err := bc.AppendBlock(block); appended || err != nil {
+			t.Errorf("AppendBlock(%d) again = %v, %v, want false without an error", block.Index, appended, err)
+		}
+	}
+	if !reflect.DeepEqual(bc.GetChain(), chain) {
+		t.Error("appending known blocks again changed the chain")
+	}
+	if balance := bc.GetBalance("bob"); balance != 10 {
+		t.Errorf("bob has %d units, want 10", balance)
+	}
+	if notified != 0 {
+		t.Errorf("OnNewBlock was called %d times, want 0", notified)
+	}
+	if valid, err := bc.IsChainValid(); !valid {
+		t.Errorf("IsChainValid() = false: %v", err)
+	}
+}
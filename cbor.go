@@ -0,0 +1,51 @@
+//go:build cbor
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// CBOR encoding of a blockchain, a more compact alternative to the JSON written by SaveToFile.
+// It is only built with the cbor build tag (go build -tags cbor), so the default build does not
+// depend on the CBOR library.
+
+// MarshalCBOR encodes the chain, the mempool and the settings persisted by SaveToFile as CBOR.
+// Strings and integers are encoded exactly, so every hash, TXID and Merkle root survives a round trip
+func (bc *Blockchain) MarshalCBOR() ([]byte, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	data, err := cbor.Marshal(bc.fileLocked())
+	if err != nil {
+		return nil, fmt.Errorf("marshal blockchain: %w", err)
+	}
+	return data, nil
+}
+
+// UnmarshalCBOR decodes a blockchain encoded by MarshalCBOR into bc and validates it like LoadFromFile,
+// including its mempool. Data which fails to decode or validate leaves bc unchanged. Callbacks and peers of bc are kept
+func (bc *Blockchain) UnmarshalCBOR(data []byte) error {
+	var file blockchainFile
+	if err := cbor.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("unmarshal blockchain: %w", err)
+	}
+	if len(file.Chain) == 0 {
+		return fmt.Errorf("%w: encoded blockchain contains no blocks", ErrInvalidChain)
+	}
+
+	decoded := &Blockchain{}
+	decoded.applyFileLocked(file)
+	if err := decoded.validateChainLocked(decoded.Chain); err != nil {
+		return fmt.Errorf("encoded blockchain is corrupted: %w", err)
+	}
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	bc.applyFileLocked(file)
+	bc.revalidateMempoolLocked()
+	return nil
+}
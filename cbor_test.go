@@ -0,0 +1,115 @@
+//go:build cbor
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// cborTestBlockchain returns a chain of a few mined blocks with transactions, memos and a pending one
+func cborTestBlockchain(t *testing.T) *Blockchain {
+	t.Helper()
+	bc := newTestBlockchain(t, map[string]int64{"alice": 1000, "bob": 1000})
+	for i := range 5 {
+		if _, err := bc.addTransactionWithMemo("alice", "bob", int64(i+1), "rent"); err != nil {
+			t.Fatalf("addTransactionWithMemo(): %v", err)
+		}
+		mineTestBlock(t, bc, "miner")
+	}
+	if _, err := bc.addTransaction("bob", "alice", 7); err != nil {
+		t.Fatalf("addTransaction(): %v", err)
+	}
+	return bc
+}
+
+func TestCBORRoundTrip(t *testing.T) {
+	bc := cborTestBlockchain(t)
+	data, err := bc.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("MarshalCBOR(): %v", err)
+	}
+
+	decoded := &Blockchain{}
+	if err := decoded.UnmarshalCBOR(data); err != nil {
+		t.Fatalf("UnmarshalCBOR(): %v", err)
+	}
+	if !reflect.DeepEqual(decoded.GetChain(), bc.GetChain()) {
+		t.Errorf("decoded chain differs from the encoded one")
+	}
+	if !reflect.DeepEqual(decoded.Mempool(), bc.Mempool()) {
+		t.Errorf("decoded mempool = %+v, want %+v", decoded.Mempool(), bc.Mempool())
+	}
+	if valid, err := decoded.IsChainValid(); !valid {
+		t.Errorf("decoded IsChainValid() = false: %v", err)
+	}
+	if height, hash := decoded.Tip(); height != 5 || hash != bc.GetChain()[5].Hash {
+		t.Errorf("decoded Tip() = %d, %s", height, hash)
+	}
+}
+
+func TestCBORIsSmallerThanJSON(t *testing.T) {
+	bc := cborTestBlockchain(t)
+	encoded, err := bc.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("MarshalCBOR(): %v", err)
+	}
+
+	bc.mu.RLock()
+	file := bc.fileLocked()
+	bc.mu.RUnlock()
+	compact, err := json.Marshal(file)
+	if err != nil {
+		t.Fatalf("json.Marshal(): %v", err)
+	}
+
+	t.Logf("CBOR %d bytes, JSON %d bytes", len(encoded), len(compact))
+	if len(encoded) >= len(compact) {
+		t.Errorf("CBOR encoding is %d bytes, not smaller than the %d bytes of compact JSON", len(encoded), len(compact))
+	}
+}
+
+func TestUnmarshalCBORRejectsInvalidData(t *testing.T) {
+	valid, err := cborTestBlockchain(t).MarshalCBOR()
+	if err != nil {
+		t.Fatalf("MarshalCBOR(): %v", err)
+	}
+	tampered := cborTestBlockchain(t)
+	tampered.Chain[2].Transactions[1].Amount++
+	corrupted, err := tampered.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("MarshalCBOR(): %v", err)
+	}
+	empty, err := (&Blockchain{}).MarshalCBOR()
+	if err != nil {
+		t.Fatalf("MarshalCBOR(): %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{"truncated", valid[:len(valid)/2], nil},
+		{"not cbor", []byte("{}"), nil},
+		{"no blocks", empty, ErrInvalidChain},
+		{"tampered transaction", corrupted, ErrInvalidChain},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := newTestBlockchain(t, nil)
+			before := bc.GetChain()
+
+			err := bc.UnmarshalCBOR(tt.data)
+			if err == nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UnmarshalCBOR() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(bc.GetChain(), before) {
+				t.Errorf("UnmarshalCBOR() changed the chain despite failing")
+			}
+		})
+	}
+}
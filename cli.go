@@ -0,0 +1,324 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"blockchain/nodegrpc"
+)
+
+// defaultChainFile is the file the CLI commands persist the blockchain to unless -file is given
+const defaultChainFile = "blockchain.json"
+
+// cliUsage describes the available subcommands
+const cliUsage = `usage: blockchain <command> [flags]
+
+commands:
+  createblockchain                                       create a new blockchain file
+  addtransaction -from -to -amount [-fee] [-memo] [-ttl] add a transaction to the mempool
+  mine -miner                                            mine the mempool into a new block
+  printchain [-json]                                     print all blocks of the chain
+  getbalance -address                                    print the balance of an address
+  serve -addr [-peer-addr] [-peers] [-grpc-addr]         serve the HTTP API and sync with peers
+  demo                                                   run an in-memory demo
+
+every command except demo accepts -file (default "blockchain.json")`
+
+// runCLI dispatches the subcommand named by the first argument to its handler,
+// writing the command output to out
+func runCLI(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprintln(out, cliUsage)
+		return errors.New("missing command")
+	}
+
+	commands := map[string]func([]string, io.Writer) error{
+		"createblockchain": cmdCreateBlockchain,
+		"addtransaction":   cmdAddTransaction,
+		"mine":             cmdMine,
+		"printchain":       cmdPrintChain,
+		"getbalance":       cmdGetBalance,
+		"serve":            cmdServe,
+		"demo":             cmdDemo,
+	}
+
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintln(out, cliUsage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+
+	return command(args[1:], out)
+}
+
+// newFlagSet creates the flag set of a subcommand which reports parse errors instead of exiting
+func newFlagSet(name string, out io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(out)
+	return fs
+}
+
+// cmdCreateBlockchain creates a new blockchain and saves it to the chain file, refusing to overwrite an existing one
+func cmdCreateBlockchain(args []string, out io.Writer) error {
+	fs := newFlagSet("createblockchain", out)
+	file := fs.String("file", defaultChainFile, "blockchain file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(*file); err == nil {
+		return fmt.Errorf("blockchain file %s already exists", *file)
+	}
+
+	bc := createBlockchain()
+	if err := bc.SaveToFile(*file); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Created blockchain with genesis block %s in %s\n", bc.Chain[0].Hash, *file)
+	return nil
+}
+
+// cmdAddTransaction adds an unsigned transaction to the mempool of the chain file
+func cmdAddTransaction(args []string, out io.Writer) error {
+	fs := newFlagSet("addtransaction", out)
+	file := fs.String("file", defaultChainFile, "blockchain file")
+	from := fs.String("from", "", "sender address")
+	to := fs.String("to", "", "recipient address")
+	amount := fs.Float64("amount", 0, "amount to transfer in coins")
+	fee := fs.Float64("fee", 0, "fee paid to the miner in coins")
+	memo := fs.String("memo", "", "memo attached to the transaction, such as a payment reference")
+	ttl := fs.Duration("ttl", 0, "time after which the transaction expires if it was not mined, e.g. 1h; zero never expires")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *from == "" || *to == "" {
+		return errors.New("addtransaction requires -from and -to")
+	}
+
+	amountUnits, err := parseCoins(*amount)
+	if err != nil {
+		return err
+	}
+	feeUnits, err := parseCoins(*fee)
+	if err != nil {
+		return err
+	}
+
+	bc, err := LoadFromFile(*file)
+	if err != nil {
+		return err
+	}
+
+	expiry := int64(0)
+	if *ttl > 0 {
+		expiry = bc.timestampLocked(time.Now().Add(*ttl))
+	}
+
+	txid, err := bc.submitTransaction(Transaction{
+		Sender:     *from,
+		Recipient:  *to,
+		Amount:     amountUnits,
+		Fee:        feeUnits,
+		Memo:       *memo,
+		ExpiryTime: expiry,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := bc.SaveToFile(*file); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Added transaction %s\n", txid)
+	return nil
+}
+
+// cmdMine mines the mempool of the chain file into a new block paying the reward to the miner address
+func cmdMine(args []string, out io.Writer) error {
+	fs := newFlagSet("mine", out)
+	file := fs.String("file", defaultChainFile, "blockchain file")
+	miner := fs.String("miner", "", "address receiving the mining reward")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *miner == "" {
+		return errors.New("mine requires -miner")
+	}
+
+	bc, err := LoadFromFile(*file)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	block, err := bc.MineBlock(*miner)
+	if err != nil {
+		return err
+	}
+
+	if err := bc.SaveToFile(*file); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Mined block %d with nonce %d in %s: %s\n", block.Index, block.Nonce, time.Since(start), block.Hash)
+	return nil
+}
+
+// cmdPrintChain prints every block of the chain file in a human-readable form, or as indented JSON with -json
+func cmdPrintChain(args []string, out io.Writer) error {
+	fs := newFlagSet("printchain", out)
+	file := fs.String("file", defaultChainFile, "blockchain file")
+	asJSON := fs.Bool("json", false, "print the blocks as indented JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	bc, err := LoadFromFile(*file)
+	if err != nil {
+		return err
+	}
+
+	if !*asJSON {
+		fmt.Fprint(out, bc)
+		return nil
+	}
+
+	data, err := json.MarshalIndent(bc.GetChain(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, string(data))
+	return nil
+}
+
+// cmdGetBalance prints the confirmed balance of an address in the chain file
+func cmdGetBalance(args []string, out io.Writer) error {
+	fs := newFlagSet("getbalance", out)
+	file := fs.String("file", defaultChainFile, "blockchain file")
+	address := fs.String("address", "", "address to look up")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *address == "" {
+		return errors.New("getbalance requires -address")
+	}
+
+	bc, err := LoadFromFile(*file)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Balance of %s: %s\n", *address, formatAmount(bc.GetBalance(*address)))
+	return nil
+}
+
+// cmdServe serves the HTTP API for the chain file, or for a new in-memory blockchain if the file does not exist.
+// With -peer-addr it also accepts peer connections, and it connects to every address of the comma separated -peers.
+// With -grpc-addr it also serves the gRPC Node service (see proto/node.proto).
+// Changes made through the API or received from peers are not written back to the file
+func cmdServe(args []string, out io.Writer) error {
+	fs := newFlagSet("serve", out)
+	file := fs.String("file", defaultChainFile, "blockchain file")
+	addr := fs.String("addr", ":8080", "address to listen on")
+	peerAddr := fs.String("peer-addr", "", "address to accept peer connections on, none if empty")
+	peers := fs.String("peers", "", "comma separated addresses of peers to connect to")
+	grpcAddr := fs.String("grpc-addr", "", "address to serve the gRPC Node service on, none if empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	bc, err := LoadFromFile(*file)
+	if errors.Is(err, os.ErrNotExist) {
+		bc = createBlockchain()
+	} else if err != nil {
+		return err
+	}
+
+	if *peerAddr != "" {
+		ln, err := net.Listen("tcp", *peerAddr)
+		if err != nil {
+			return fmt.Errorf("listen for peers: %w", err)
+		}
+		defer ln.Close()
+
+		fmt.Fprintf(out, "Accepting peers on %s\n", *peerAddr)
+		go func() {
+			if err := bc.ServePeers(ln); err != nil && !errors.Is(err, net.ErrClosed) {
+				log.Printf("serving peers failed: %v", err)
+			}
+		}()
+	}
+	if *grpcAddr != "" {
+		ln, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			return fmt.Errorf("listen for gRPC: %w", err)
+		}
+		server := nodegrpc.NewServer(grpcBackend{bc: bc})
+		defer server.Stop()
+
+		fmt.Fprintf(out, "Serving gRPC on %s\n", *grpcAddr)
+		go func() {
+			if err := server.Serve(ln); err != nil {
+				log.Printf("serving gRPC failed: %v", err)
+			}
+		}()
+	}
+	for _, peer := range strings.Split(*peers, ",") {
+		if peer = strings.TrimSpace(peer); peer == "" {
+			continue
+		}
+		if err := bc.ConnectPeer(peer); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Connected to peer %s\n", peer)
+	}
+
+	fmt.Fprintf(out, "Serving HTTP API on %s\n", *addr)
+	return StartServer(bc, *addr)
+}
+
+// cmdDemo mines a reward to Alice, lets her pay Bob and Charlie and mines those transactions into a second block
+func cmdDemo(args []string, out io.Writer) error {
+	fs := newFlagSet("demo", out)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	bc := createBlockchain()
+	if _, err := bc.MineBlock("Alice"); err != nil {
+		return fmt.Errorf("mining failed: %w", err)
+	}
+
+	if _, err := bc.addTransaction("Alice", "Bob", 30*UnitsPerCoin); err != nil {
+		return fmt.Errorf("adding transaction failed: %w", err)
+	}
+	if _, err := bc.addTransaction("Alice", "Charlie", 15*UnitsPerCoin); err != nil {
+		return fmt.Errorf("adding transaction failed: %w", err)
+	}
+
+	start := time.Now()
+	block, err := bc.MineBlock("Miner")
+	if err != nil {
+		return fmt.Errorf("mining failed: %w", err)
+	}
+
+	duration := time.Since(start)
+	fmt.Fprintf(out, "Proof of work (nonce) found in iteration %d (execution time: %s)\n", block.Nonce, duration)
+
+	fmt.Fprint(out, "Blockchain:\n", bc)
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunCLI(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "chain.json")
+
+	// the steps run in order against the same chain file
+	steps := []struct {
+		name       string
+		args       []string
+		wantErr    string
+		wantOutput string
+	}{
+		{"missing command", nil, "missing command", "usage:"},
+		{"unknown command", []string{"frobnicate"}, `unknown command "frobnicate"`, "usage:"},
+		{"no chain file yet", []string{"getbalance", "-file", file, "-address", "alice"}, "read blockchain file", ""},
+		{"create", []string{"createblockchain", "-file", file}, "", "Created blockchain with genesis block"},
+		{"create again", []string{"createblockchain", "-file", file}, "already exists", ""},
+		{"mine without miner", []string{"mine", "-file", file}, "requires -miner", ""},
+		{"mine", []string{"mine", "-file", file, "-miner", "alice"}, "", "Mined block 1"},
+		{"balance of miner", []string{"getbalance", "-file", file, "-address", "alice"}, "", "Balance of alice: 50.00000000"},
+		{"transaction without recipient", []string{"addtransaction", "-file", file, "-from", "alice", "-amount", "1"}, "requires -from and -to", ""},
+		{"unfunded transaction", []string{"addtransaction", "-file", file, "-from", "bob", "-to", "alice", "-amount", "1"}, "insufficient funds", ""},
+		{"transaction", []string{"addtransaction", "-file", file, "-from", "alice", "-to", "bob", "-amount", "1.5", "-memo", "lunch"}, "", "Added transaction"},
+		{"unconfirmed balance", []string{"getbalance", "-file", file, "-address", "bob"}, "", "Balance of bob: 0.00000000"},
+		{"mine transaction", []string{"mine", "-file", file, "-miner", "carol"}, "", "Mined block 2"},
+		{"confirmed balance", []string{"getbalance", "-file", file, "-address", "bob"}, "", "Balance of bob: 1.50000000"},
+		{"unknown flag", []string{"printchain", "-file", file, "-yaml"}, "flag provided but not defined", ""},
+	}
+
+	for _, step := range steps {
+		var out strings.Builder
+		err := runCLI(step.args, &out)
+		switch {
+		case step.wantErr == "" && err != nil:
+			t.Fatalf("%s: runCLI(%q): %v", step.name, step.args, err)
+		case step.wantErr != "" && (err == nil || !strings.Contains(err.Error(), step.wantErr)):
+			t.Fatalf("%s: runCLI(%q) error = %v, want an error containing %q", step.name, step.args, err, step.wantErr)
+		}
+		if !strings.Contains(out.String(), step.wantOutput) {
+			t.Errorf("%s: runCLI(%q) wrote %q, want output containing %q", step.name, step.args, out.String(), step.wantOutput)
+		}
+	}
+
+	var out strings.Builder
+	if err := runCLI([]string{"printchain", "-file", file, "-json"}, &out); err != nil {
+		t.Fatalf("runCLI(printchain -json): %v", err)
+	}
+	var chain []Block
+	if err := json.Unmarshal([]byte(out.String()), &chain); err != nil {
+		t.Fatalf("printchain -json output is not a chain: %v", err)
+	}
+	if len(chain) != 3 || chain[2].Transactions[1].Memo != "lunch" {
+		t.Errorf("printchain -json printed %d blocks, want 3 with the memo of the transaction", len(chain))
+	}
+}
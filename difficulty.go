@@ -0,0 +1,44 @@
+package main
+
+// defaultDifficultyAdjustmentInterval is the default number of blocks between two difficulty recalculations
+const defaultDifficultyAdjustmentInterval = 10
+
+// adjustDifficulty recalculates bc.Difficulty for the next block from the timestamps of the chain (see nextDifficulty).
+// It is called from addBlock after every appended block and does nothing while TargetBlockTime is not set
+func (bc *Blockchain) adjustDifficulty() {
+	if bc.TargetBlockTime <= 0 {
+		return
+	}
+
+	bc.Difficulty = bc.nextDifficulty(bc.Chain)
+}
+
+// nextDifficulty returns the difficulty required for the block following the given chain.
+// Every DifficultyAdjustmentInterval blocks the time span of the last window is compared to the expected span of
+// DifficultyAdjustmentInterval * TargetBlockTime: the difficulty is raised by one when the window was mined faster
+// than expected and lowered by one when it was mined slower, so it only stays unchanged for a window right on time.
+// Every difficulty bit doubles or halves the expected mining time, so the difficulty settles by alternating between
+// the two values closest to the target rather than hitting it exactly.
+// The genesis block timestamp is fixed rather than mined, so a window never starts at the genesis block.
+// Between adjustments the difficulty of the last block carries over
+func (bc *Blockchain) nextDifficulty(chain []Block) int {
+	tip := chain[len(chain)-1]
+	interval := bc.DifficultyAdjustmentInterval
+	if interval <= 0 || tip.Index%interval != 0 || len(chain) <= interval+1 {
+		return tip.Difficulty
+	}
+
+	windowStart := chain[len(chain)-1-interval]
+	actual := tip.Timestamp - windowStart.Timestamp
+	expected := int64(interval) * int64(bc.TargetBlockTime/bc.timestampUnitLocked())
+
+	difficulty := tip.Difficulty
+	switch {
+	case actual < expected:
+		difficulty++
+	case actual > expected:
+		difficulty--
+	}
+
+	return min(max(difficulty, minDifficulty), maxDifficulty)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// difficultyTestChain returns a genesis block followed by blocks mined spacing seconds apart at difficulty
+func difficultyTestChain(blocks int, spacing int64, difficulty int) []Block {
+	chain := []Block{{BlockHeader: BlockHeader{Index: 0, Timestamp: defaultGenesisTimestamp, Difficulty: difficulty}}}
+	for i := 1; i <= blocks; i++ {
+		chain = append(chain, Block{BlockHeader: BlockHeader{
+			Index:      i,
+			Timestamp:  defaultGenesisTimestamp + 1000 + int64(i)*spacing,
+			Difficulty: difficulty,
+		}})
+	}
+	return chain
+}
+
+func TestNextDifficulty(t *testing.T) {
+	tests := []struct {
+		name       string
+		blocks     int
+		spacing    int64 // seconds between blocks, against a target of 10
+		difficulty int
+		want       int
+	}{
+		{"fast window raises", 20, 2, 8, 9},
+		{"window at 60% of the target raises", 20, 6, 8, 9},
+		{"window on time keeps", 20, 10, 8, 8},
+		{"slow window lowers", 20, 15, 8, 7},
+		{"very slow window lowers by one only", 20, 100, 8, 7},
+		{"between adjustments keeps", 21, 2, 8, 8},
+		{"first window past genesis keeps", 10, 2, 8, 8},
+		{"lowering stops at the minimum", 20, 100, minDifficulty, minDifficulty},
+		{"raising stops at the maximum", 20, 2, maxDifficulty, maxDifficulty},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := &Blockchain{TargetBlockTime: 10 * time.Second, DifficultyAdjustmentInterval: 10}
+			if got := bc.nextDifficulty(difficultyTestChain(tt.blocks, tt.spacing, tt.difficulty)); got != tt.want {
+				t.Errorf("nextDifficulty() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiningFastBlocksRaisesDifficulty(t *testing.T) {
+	bc := newTestBlockchain(t, nil)
+	bc.TargetBlockTime = time.Hour
+	bc.DifficultyAdjustmentInterval = 2
+
+	for range 4 {
+		mineTestBlock(t, bc, "miner")
+	}
+	if got := bc.CurrentDifficulty(); got != 2 {
+		t.Errorf("CurrentDifficulty() = %d after a window mined far faster than the target, want 2", got)
+	}
+	if valid, err := bc.IsChainValid(); !valid {
+		t.Errorf("IsChainValid() = false: %v", err)
+	}
+}
@@ -0,0 +1,17 @@
+package main
+
+// isDustLocked reports whether the transaction pays an address other than its sender less than bc.DustThreshold.
+// Change returned to the sender is not a payment and coinbase transactions are exempt, so a small reward share
+// never keeps a block from being mined
+func (bc *Blockchain) isDustLocked(tx Transaction) bool {
+	if bc.DustThreshold <= 0 || tx.Sender == coinbaseSender {
+		return false
+	}
+
+	for _, out := range tx.payments() {
+		if out.Amount < bc.DustThreshold {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDustThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int64
+		utxo      bool
+		outputs   []TXOutput
+		wantErr   error
+	}{
+		{"just below", 100, false, []TXOutput{{Address: "bob", Amount: 99}}, ErrDustAmount},
+		{"at the threshold", 100, false, []TXOutput{{Address: "bob", Amount: 100}}, nil},
+		{"just above", 100, false, []TXOutput{{Address: "bob", Amount: 101}}, nil},
+		{"one dust output of several", 100, false, []TXOutput{{Address: "bob", Amount: 500}, {Address: "carol", Amount: 99}}, ErrDustAmount},
+		{"small change under the UTXO model", 100, true, []TXOutput{{Address: "bob", Amount: 999}}, nil},
+		{"disabled", 0, false, []TXOutput{{Address: "bob", Amount: 1}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := newTestBlockchain(t, map[string]int64{"alice": 1000})
+			if tt.utxo {
+				bc.EnableUTXO()
+			}
+			bc.DustThreshold = tt.threshold
+
+			_, err := bc.addMultiTransaction("alice", tt.outputs)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("addMultiTransaction() error = %v, want %v", err, tt.wantErr)
+			}
+			if want := map[bool]int{true: 1, false: 0}[tt.wantErr == nil]; bc.PendingCount() != want {
+				t.Errorf("mempool holds %d transactions, want %d", bc.PendingCount(), want)
+			}
+		})
+	}
+}
+
+func TestDustIsLeftOutOfMinedBlocks(t *testing.T) {
+	bc := newTestBlockchain(t, map[string]int64{"alice": 1000})
+	dust, err := bc.addTransaction("alice", "bob", 50)
+	if err != nil {
+		t.Fatalf("addTransaction(): %v", err)
+	}
+	// the threshold is raised after the transaction was accepted, so it is dust by now
+	bc.DustThreshold = 100
+
+	block := mineTestBlock(t, bc, "miner")
+	for _, tx := range block.Transactions {
+		if tx.TXID == dust {
+			t.Errorf("mined block confirms the dust transaction %s", dust)
+		}
+	}
+	if balance := bc.GetBalance("bob"); balance != 0 {
+		t.Errorf("bob has %d units, want 0", balance)
+	}
+
+	// the threshold is a mempool policy, a block mined elsewhere paying dust is accepted
+	other := newTestBlockchain(t, map[string]int64{"alice": 1000})
+	if _, err := other.addTransaction("alice", "bob", 50); err != nil {
+		t.Fatalf("addTransaction(): %v", err)
+	}
+	peer := mineTestBlock(t, other, "other")
+	strict := newTestBlockchain(t, map[string]int64{"alice": 1000})
+	strict.DustThreshold = 100
+	if appended, err := strict.AppendBlock(peer); !appended || err != nil {
+		t.Errorf("AppendBlock() of a block paying dust = %v, %v, want it appended", appended, err)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors returned by the blockchain. They are wrapped with details using fmt.Errorf and %w,
+// so callers match them with errors.Is
+
+// ErrProofNotFound is returned by proofOfWork when no valid nonce was found within the iteration limit
+var ErrProofNotFound = errors.New("proof of work not found within iteration limit")
+
+// ErrInvalidDifficulty is returned when the blockchain difficulty is outside of the allowed range
+var ErrInvalidDifficulty = fmt.Errorf("difficulty must be between %d and %d", minDifficulty, maxDifficulty)
+
+// ErrInsufficientFunds is returned by addTransaction when the sender cannot afford the transferred amount
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// ErrEmptyChain is returned when the chain holds no blocks, not even the genesis block
+var ErrEmptyChain = errors.New("chain is empty")
+
+// ErrBlockNotFound is returned when a requested block does not exist in the chain
+var ErrBlockNotFound = errors.New("block not found")
+
+// ErrTransactionNotFound is returned when a requested transaction is neither in the chain nor in the mempool
+var ErrTransactionNotFound = errors.New("transaction not found")
+
+// ErrUnconfirmedTransaction is returned by Confirmations for a transaction still pending in the mempool
+var ErrUnconfirmedTransaction = errors.New("transaction is not confirmed")
+
+// ErrDuplicateTransaction is returned when a transaction with the same TXID is already pending or confirmed
+var ErrDuplicateTransaction = errors.New("duplicate transaction")
+
+// ErrInvalidNonce is returned when a transaction's nonce is not the next nonce of its sender
+var ErrInvalidNonce = errors.New("invalid transaction nonce")
+
+// ErrInvalidBlock is returned when a block does not link to the chain tip, does not satisfy the proof-of-work
+// or, for an imported block, does not match its own hash
+var ErrInvalidBlock = errors.New("invalid block")
+
+// ErrInvalidTimestamp is returned when a block timestamp precedes the previous block or lies too far in the future
+var ErrInvalidTimestamp = errors.New("invalid block timestamp")
+
+// ErrInvalidTransaction is returned when a transaction is malformed, e.g. it has a blank sender or recipient,
+// sends to itself, moves a non-positive amount, pays a negative fee or carries an over-long memo
+var ErrInvalidTransaction = errors.New("invalid transaction")
+
+// ErrInvalidChain is returned when a chain fails validation, e.g. by IsChainValid, ReplaceChain or LoadFromFile
+var ErrInvalidChain = errors.New("invalid chain")
+
+// ErrGenesisMismatch is returned when a chain or a peer starts from a different genesis block than the local chain,
+// i.e. belongs to another network
+var ErrGenesisMismatch = errors.New("genesis block mismatch")
+
+// ErrInvalidGenesis is returned when the first block of a chain is not a well-formed genesis block:
+// its index is not 0, its previous hash is not "0" or its stored hash or Merkle root does not match
+var ErrInvalidGenesis = errors.New("invalid genesis block")
+
+// ErrInvalidHashPrefix is returned by FindBlocksByHashPrefix when the prefix is empty or not hexadecimal
+var ErrInvalidHashPrefix = errors.New("invalid hash prefix")
+
+// ErrInvalidPrune is returned by Prune when it would not keep at least the tip block and the immature coinbase rewards
+var ErrInvalidPrune = errors.New("invalid prune depth")
+
+// ErrInvalidTreasury is returned by MineBlock, and wrapped by chain validation errors, when TreasuryPercent is outside [0, 100] or set without a TreasuryAddress
+var ErrInvalidTreasury = errors.New("invalid treasury split")
+
+// ErrInvalidAddress is returned when a transaction pays an address failing ValidateAddress
+// while address validation is enabled (see Blockchain.ValidateAddresses)
+var ErrInvalidAddress = errors.New("invalid address")
+
+// ErrInvalidSignature is returned when a transaction's signature does not validate against its TXID and sender
+var ErrInvalidSignature = errors.New("invalid transaction signature")
+
+// ErrFeeTooLow is returned by ReplaceTransaction when the new fee does not exceed the fee of the replaced transaction
+var ErrFeeTooLow = errors.New("replacement fee too low")
+
+// ErrMempoolFull is returned when the mempool holds MaxMempoolSize transactions and the new transaction
+// does not pay a higher fee than the transaction which would be evicted for it
+var ErrMempoolFull = errors.New("mempool is full")
+
+// ErrInvalidSnapshot is returned by Restore when a snapshot has an unsupported version or its nonces
+// or UTXO set do not match the ones derived from its chain
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+// ErrInvalidRange is returned by TransactionsInRange when the lower bound of the amount range exceeds the upper one
+var ErrInvalidRange = errors.New("invalid amount range")
+
+// ErrInvalidPage is returned by GetBlocksPage for a negative offset or limit
+var ErrInvalidPage = errors.New("invalid block page")
+
+// ErrStaleBlock is returned by SubmitMinedBlock when the block was built on a block below the current tip
+var ErrStaleBlock = errors.New("stale block")
+
+// ErrDustAmount is returned when a transaction pays an address less than Blockchain.DustThreshold
+var ErrDustAmount = errors.New("dust amount")
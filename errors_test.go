@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSentinelErrors(t *testing.T) {
+	// funded returns a chain with a mined block on top of a genesis block funding alice
+	funded := func(t *testing.T) *Blockchain {
+		t.Helper()
+		bc := newTestBlockchain(t, map[string]int64{"alice": 1000})
+		mineTestBlock(t, bc, "miner")
+		return bc
+	}
+	submit := func(bc *Blockchain, tx Transaction) error {
+		_, err := bc.submitTransaction(tx)
+		return err
+	}
+
+	tests := []struct {
+		name    string
+		trigger func(t *testing.T) error
+		want    error
+	}{
+		{"ErrProofNotFound", func(t *testing.T) error {
+			bc := funded(t)
+			bc.Difficulty = maxDifficulty
+			_, _, err := bc.proofOfWork(10)
+			return err
+		}, ErrProofNotFound},
+		{"ErrInvalidDifficulty", func(t *testing.T) error {
+			bc := funded(t)
+			bc.Difficulty = 0
+			_, err := bc.MineBlock("miner")
+			return err
+		}, ErrInvalidDifficulty},
+		{"ErrInsufficientFunds", func(t *testing.T) error {
+			return submit(funded(t), Transaction{Sender: "alice", Recipient: "bob", Amount: 1001})
+		}, ErrInsufficientFunds},
+		{"ErrEmptyChain", func(t *testing.T) error {
+			_, err := (&Blockchain{}).GetLatestBlock()
+			return err
+		}, ErrEmptyChain},
+		{"ErrBlockNotFound", func(t *testing.T) error {
+			_, err := funded(t).GetBlockByIndex(2)
+			return err
+		}, ErrBlockNotFound},
+		{"ErrTransactionNotFound", func(t *testing.T) error {
+			_, err := funded(t).Confirmations("unknown")
+			return err
+		}, ErrTransactionNotFound},
+		{"ErrUnconfirmedTransaction", func(t *testing.T) error {
+			bc := funded(t)
+			txid, err := bc.addTransaction("alice", "bob", 10)
+			if err != nil {
+				t.Fatalf("addTransaction(): %v", err)
+			}
+			_, err = bc.Confirmations(txid)
+			return err
+		}, ErrUnconfirmedTransaction},
+		{"ErrDuplicateTransaction", func(t *testing.T) error {
+			bc := funded(t)
+			tx := Transaction{Sender: "alice", Recipient: "bob", Amount: 10, Nonce: 1}
+			if err := submit(bc, tx); err != nil {
+				t.Fatalf("submitTransaction(): %v", err)
+			}
+			return submit(bc, tx)
+		}, ErrDuplicateTransaction},
+		{"ErrInvalidNonce", func(t *testing.T) error {
+			return submit(funded(t), Transaction{Sender: "alice", Recipient: "bob", Amount: 10, Nonce: 5})
+		}, ErrInvalidNonce},
+		{"ErrInvalidBlock", func(t *testing.T) error {
+			_, err := funded(t).ImportBlock([]byte(`{"index": 1, "hash": "00"}`))
+			return err
+		}, ErrInvalidBlock},
+		{"ErrInvalidTimestamp", func(t *testing.T) error {
+			bc := funded(t)
+			bc.mu.Lock()
+			defer bc.mu.Unlock()
+			return bc.validateTimestampLocked(bc.Chain[1], bc.Chain[1].Timestamp-1)
+		}, ErrInvalidTimestamp},
+		{"ErrInvalidTransaction", func(t *testing.T) error {
+			return submit(funded(t), Transaction{Sender: "alice", Recipient: "bob", Amount: 0})
+		}, ErrInvalidTransaction},
+		{"ErrInvalidChain", func(t *testing.T) error {
+			bc := funded(t)
+			bc.Chain[1].Nonce++
+			_, err := bc.IsChainValid()
+			return err
+		}, ErrInvalidChain},
+		{"ErrGenesisMismatch", func(t *testing.T) error {
+			other := createBlockchainWithGenesis(GenesisConfig{Timestamp: defaultGenesisTimestamp + 1, Difficulty: 1})
+			mineTestBlock(t, other, "miner")
+			mineTestBlock(t, other, "miner")
+			_, err := funded(t).ReplaceChain(other.GetChain())
+			return err
+		}, ErrGenesisMismatch},
+		{"ErrInvalidGenesis", func(t *testing.T) error {
+			bc := funded(t)
+			bc.Chain[0].PreviousHash = "1"
+			_, err := bc.IsChainValid()
+			return err
+		}, ErrInvalidGenesis},
+		{"ErrInvalidHashPrefix", func(t *testing.T) error {
+			_, err := funded(t).FindBlocksByHashPrefix("xyz")
+			return err
+		}, ErrInvalidHashPrefix},
+		{"ErrInvalidPrune", func(t *testing.T) error {
+			return funded(t).Prune(0)
+		}, ErrInvalidPrune},
+		{"ErrInvalidTreasury", func(t *testing.T) error {
+			bc := funded(t)
+			bc.TreasuryPercent = 150
+			_, err := bc.MineBlock("miner")
+			return err
+		}, ErrInvalidTreasury},
+		{"ErrInvalidAddress", func(t *testing.T) error {
+			bc := funded(t)
+			bc.ValidateAddresses = true
+			return submit(bc, Transaction{Sender: "alice", Recipient: "not an address", Amount: 10})
+		}, ErrInvalidAddress},
+		{"ErrInvalidSignature", func(t *testing.T) error {
+			bc := funded(t)
+			bc.RequireSignatures = true
+			return submit(bc, Transaction{Sender: "alice", Recipient: "bob", Amount: 10})
+		}, ErrInvalidSignature},
+		{"ErrFeeTooLow", func(t *testing.T) error {
+			bc := funded(t)
+			txid, err := bc.submitTransaction(Transaction{Sender: "alice", Recipient: "bob", Amount: 10, Fee: 5})
+			if err != nil {
+				t.Fatalf("submitTransaction(): %v", err)
+			}
+			_, err = bc.ReplaceTransaction(txid, 5)
+			return err
+		}, ErrFeeTooLow},
+		{"ErrMempoolFull", func(t *testing.T) error {
+			bc := newTestBlockchain(t, map[string]int64{"alice": 1000, "bob": 1000})
+			bc.MaxMempoolSize = 1
+			if err := submit(bc, Transaction{Sender: "alice", Recipient: "carol", Amount: 10}); err != nil {
+				t.Fatalf("submitTransaction(): %v", err)
+			}
+			return submit(bc, Transaction{Sender: "bob", Recipient: "carol", Amount: 10})
+		}, ErrMempoolFull},
+		{"ErrInvalidSnapshot", func(t *testing.T) error {
+			_, err := Restore([]byte(`{"version": 99}`))
+			return err
+		}, ErrInvalidSnapshot},
+		{"ErrInvalidRange", func(t *testing.T) error {
+			_, err := funded(t).TransactionsInRange(10, 1)
+			return err
+		}, ErrInvalidRange},
+		{"ErrInvalidPage", func(t *testing.T) error {
+			_, _, err := funded(t).GetBlocksPage(-5, 3)
+			return err
+		}, ErrInvalidPage},
+		{"ErrStaleBlock", func(t *testing.T) error {
+			bc := funded(t)
+			return bc.SubmitMinedBlock(bc.GetChain()[1])
+		}, ErrStaleBlock},
+		{"ErrDustAmount", func(t *testing.T) error {
+			bc := funded(t)
+			bc.DustThreshold = 100
+			return submit(bc, Transaction{Sender: "alice", Recipient: "bob", Amount: 10})
+		}, ErrDustAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.trigger(t)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want errors.Is %v", err, tt.want)
+			}
+		})
+	}
+}
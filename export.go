@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// MarshalJSON encodes the block with its regular fields plus a "time" field holding the timestamp
+// as RFC 3339 in UTC, so exported blocks can be read without converting Unix timestamps, and a "size" field
+// holding Size. The extra fields are ignored when decoding, so a decoded block keeps the exact fields its hash covers
+func (b Block) MarshalJSON() ([]byte, error) {
+	type plainBlock Block // drops the methods of Block, so encoding it does not recurse into MarshalJSON
+	return json.Marshal(struct {
+		plainBlock
+		Time string `json:"time"`
+		Size int    `json:"size"`
+	}{
+		plainBlock: plainBlock(b),
+		Time:       blockTime(b.Timestamp).UTC().Format(time.RFC3339Nano),
+		Size:       b.Size(),
+	})
+}
+
+// ExportBlock encodes the block at index as JSON for sharing or manual syncing,
+// or returns ErrBlockNotFound if index is out of range
+func (bc *Blockchain) ExportBlock(index int) ([]byte, error) {
+	block, err := bc.GetBlockByIndex(index)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(block)
+}
+
+// ImportBlock decodes a block encoded by ExportBlock and checks that its TXIDs, Merkle root and hash
+// match its contents under the hash function of bc. The block is not added to the chain
+func (bc *Blockchain) ImportBlock(data []byte) (Block, error) {
+	var block Block
+	if err := json.Unmarshal(data, &block); err != nil {
+		return Block{}, fmt.Errorf("decoding block: %w", err)
+	}
+
+	h := bc.hasher()
+	for _, tx := range block.Transactions {
+		if tx.TXID != generateTransactionID(h, tx) {
+			return Block{}, fmt.Errorf("%w: transaction %s does not match its contents", ErrInvalidBlock, tx.TXID)
+		}
+	}
+	if computeMerkleRoot(h, block.Transactions) != block.MerkleRoot {
+		return Block{}, fmt.Errorf("%w: merkle root does not match transactions", ErrInvalidBlock)
+	}
+	if calculateHash(h, block.BlockHeader) != block.Hash {
+		return Block{}, fmt.Errorf("%w: stored hash does not match calculated hash", ErrInvalidBlock)
+	}
+
+	return block, nil
+}
+
+// ExportHeaders returns the header of every block from genesis to tip, the compact form of the chain a light
+// client needs to follow it: with the Merkle roots and a MerkleProof it verifies single transactions without
+// downloading any block body. Pruned blocks are exported like any other, as their headers are complete
+func (bc *Blockchain) ExportHeaders() []BlockHeader {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	headers := make([]BlockHeader, len(bc.Chain))
+	for i, block := range bc.Chain {
+		headers[i] = block.BlockHeader
+	}
+
+	return headers
+}
+
+// VerifyHeaderChain checks a sequence of headers exported by ExportHeaders as a light client would, assuming the
+// default SHA256Hasher and SHA256PoW: the first header must be a genesis header (see validateGenesis, except for
+// the Merkle root, which needs the transactions), and every following header must match its own hash, follow the
+// index, hash and timestamp of its predecessor and satisfy the proof of work of the difficulty it records.
+// The difficulty a full node requires is not known to a light client, so among several valid header chains
+// the one with the most work should be trusted. Returns an error wrapping ErrInvalidChain naming the first invalid header
+func VerifyHeaderChain(headers []BlockHeader) error {
+	return verifyHeaderChain(SHA256Hasher{}, SHA256PoW{}, headers)
+}
+
+// verifyHeaderChain is VerifyHeaderChain for chains hashing with h and proving work with pow
+func verifyHeaderChain(h Hasher, pow PoWAlgorithm, headers []BlockHeader) error {
+	if len(headers) == 0 {
+		return fmt.Errorf("%w: no headers to verify", ErrInvalidChain)
+	}
+	if err := validateGenesis(h, Block{BlockHeader: headers[0], Pruned: true}); err != nil {
+		return err
+	}
+
+	for i := 1; i < len(headers); i++ {
+		header, previous := headers[i], headers[i-1]
+		switch {
+		case calculateHash(h, header) != header.Hash:
+			return fmt.Errorf("%w: header %d: stored hash does not match calculated hash", ErrInvalidChain, header.Index)
+		case header.Index != previous.Index+1:
+			return fmt.Errorf("%w: header %d: index does not follow header %d", ErrInvalidChain, header.Index, previous.Index)
+		case header.PreviousHash != previous.Hash:
+			return fmt.Errorf("%w: header %d: previous hash does not match hash of header %d", ErrInvalidChain, header.Index, previous.Index)
+		case header.Timestamp < previous.Timestamp:
+			return fmt.Errorf("%w: header %d: %w: %d is earlier than timestamp %d of header %d", ErrInvalidChain, header.Index, ErrInvalidTimestamp, header.Timestamp, previous.Timestamp, previous.Index)
+		case validateDifficulty(header.Difficulty) != nil:
+			return fmt.Errorf("%w: header %d: %w", ErrInvalidChain, header.Index, validateDifficulty(header.Difficulty))
+		case !meetsDifficulty(pow.ProofHash(h, header.serializeForHash()), header.Difficulty):
+			return fmt.Errorf("%w: header %d: invalid proof of work", ErrInvalidChain, header.Index)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExportImportBlockRoundTrip(t *testing.T) {
+	bc := newTestBlockchain(t, map[string]int64{"alice": 1000, "bob": 1000})
+	for i := range 25 {
+		sender := []string{"alice", "bob"}[i%2]
+		if _, err := bc.addTransactionWithMemo(sender, "carol", int64(i+1), "memo with \"quotes\" and ünïcode"); err != nil {
+			t.Fatalf("addTransactionWithMemo(): %v", err)
+		}
+	}
+	mineTestBlock(t, bc, "miner")
+
+	for _, index := range []int{0, 1} {
+		exported, err := bc.ExportBlock(index)
+		if err != nil {
+			t.Fatalf("ExportBlock(%d): %v", index, err)
+		}
+		imported, err := bc.ImportBlock(exported)
+		if err != nil {
+			t.Fatalf("ImportBlock() of block %d: %v", index, err)
+		}
+
+		original, _ := bc.GetBlockByIndex(index)
+		if !reflect.DeepEqual(imported, original) {
+			t.Errorf("block %d changed in the round trip", index)
+		}
+		again, err := json.Marshal(imported)
+		if err != nil {
+			t.Fatalf("json.Marshal(): %v", err)
+		}
+		if string(again) != string(exported) {
+			t.Errorf("block %d exports differently after the round trip:\n%s\n%s", index, exported, again)
+		}
+	}
+
+	if _, err := bc.ExportBlock(2); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("ExportBlock(2) error = %v, want ErrBlockNotFound", err)
+	}
+}
+
+func TestImportBlockRejectsTamperedBlocks(t *testing.T) {
+	bc := newTestBlockchain(t, map[string]int64{"alice": 1000})
+	if _, err := bc.addTransaction("alice", "bob", 10); err != nil {
+		t.Fatalf("addTransaction(): %v", err)
+	}
+	block := mineTestBlock(t, bc, "miner")
+
+	tests := []struct {
+		name    string
+		tamper  func(b *Block)
+		wantErr error
+	}{
+		{"transaction amount", func(b *Block) { b.Transactions[1].Amount = 11 }, ErrInvalidBlock},
+		{"transaction with recomputed TXID", func(b *Block) {
+			b.Transactions[1].Amount = 11
+			b.Transactions[1].TXID = generateTransactionID(bc.hasher(), b.Transactions[1])
+		}, ErrInvalidBlock},
+		{"dropped transaction", func(b *Block) { b.Transactions = b.Transactions[:1] }, ErrInvalidBlock},
+		{"nonce", func(b *Block) { b.Nonce++ }, ErrInvalidBlock},
+		{"hash", func(b *Block) { b.Hash = strings.Repeat("0", 64) }, ErrInvalidBlock},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tampered := block.clone()
+			tt.tamper(&tampered)
+			data, err := json.Marshal(tampered)
+			if err != nil {
+				t.Fatalf("json.Marshal(): %v", err)
+			}
+			if _, err := bc.ImportBlock(data); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ImportBlock() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := bc.ImportBlock([]byte("{")); err == nil {
+		t.Errorf("ImportBlock() of malformed JSON succeeded")
+	}
+}
+
+func TestVerifyHeaderChain(t *testing.T) {
+	bc := createBlockchainWithGenesis(GenesisConfig{Timestamp: defaultGenesisTimestamp, Difficulty: 8})
+	for range 10 {
+		mineTestBlock(t, bc, "miner")
+	}
+	headers := bc.ExportHeaders()
+	if len(headers) != 11 {
+		t.Fatalf("ExportHeaders() returned %d headers, want 11", len(headers))
+	}
+
+	// tampered returns a copy of the headers with header i changed by change
+	tampered := func(i int, change func(h *BlockHeader)) []BlockHeader {
+		copied := append([]BlockHeader{}, headers...)
+		change(&copied[i])
+		return copied
+	}
+	// reseal searches a nonce of a changed header with a valid proof of work, or without one if valid is false,
+	// and recalculates its hash
+	reseal := func(h *BlockHeader, valid bool) {
+		for h.Nonce = 0; meetsDifficulty(calculateHash(SHA256Hasher{}, *h), h.Difficulty) != valid; h.Nonce++ {
+		}
+		h.Hash = calculateHash(SHA256Hasher{}, *h)
+	}
+
+	tests := []struct {
+		name    string
+		headers []BlockHeader
+		// wantErr is a substring of the error, empty for a valid header chain
+		wantErr string
+	}{
+		{"exported headers", headers, ""},
+		{"genesis only", headers[:1], ""},
+		{"tampered nonce", tampered(5, func(h *BlockHeader) { h.Nonce++ }), "header 5: stored hash does not match"},
+		{"tampered Merkle root with recalculated hash", tampered(5, func(h *BlockHeader) {
+			h.MerkleRoot = strings.Repeat("0", 64)
+			reseal(h, false)
+		}), "header 5: invalid proof of work"},
+		{"dropped header", append(append([]BlockHeader{}, headers[:4]...), headers[5:]...), "header 5: index does not follow header 3"},
+		{"broken link", tampered(7, func(h *BlockHeader) {
+			h.PreviousHash = headers[5].Hash
+			reseal(h, true)
+		}), "header 7: previous hash does not match hash of header 6"},
+		{"tampered genesis", tampered(0, func(h *BlockHeader) { h.Timestamp++ }), "invalid genesis block"},
+		{"no headers", nil, "no headers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyHeaderChain(tt.headers)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("VerifyHeaderChain(): %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidChain) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("VerifyHeaderChain() error = %v, want ErrInvalidChain mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
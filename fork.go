@@ -0,0 +1,48 @@
+package main
+
+import "fmt"
+
+// DetectFork reports the candidate blocks which compete with another block for the same parent: a candidate
+// is a fork if its parent is a block of the chain or another candidate, and the chain or another candidate
+// already holds a different block on top of that parent. Candidates already in the chain and candidates
+// extending the tip alone are not forks, and orphans whose parent is unknown are skipped.
+// The forks are returned once each in candidate order; nothing is changed, resolving a fork is up to the caller.
+// Returns ErrInvalidBlock if a candidate's stored hash does not match its header
+func (bc *Blockchain) DetectFork(blocks []Block) ([]Block, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	known := map[string]bool{}               // hashes of chain blocks and candidates
+	children := map[string]map[string]bool{} // parent hash -> distinct hashes of the blocks built on it
+	addChild := func(block Block) {
+		known[block.Hash] = true
+		if children[block.PreviousHash] == nil {
+			children[block.PreviousHash] = map[string]bool{}
+		}
+		children[block.PreviousHash][block.Hash] = true
+	}
+
+	inChain := map[string]bool{}
+	for _, block := range bc.Chain {
+		inChain[block.Hash] = true
+		addChild(block)
+	}
+
+	for _, block := range blocks {
+		if calculateHash(bc.hasher(), block.BlockHeader) != block.Hash {
+			return nil, fmt.Errorf("%w: candidate block %d: stored hash does not match calculated hash", ErrInvalidBlock, block.Index)
+		}
+		addChild(block)
+	}
+
+	forks := []Block{}
+	reported := map[string]bool{}
+	for _, block := range blocks {
+		if known[block.PreviousHash] && len(children[block.PreviousHash]) > 1 && !inChain[block.Hash] && !reported[block.Hash] {
+			reported[block.Hash] = true
+			forks = append(forks, block.clone())
+		}
+	}
+
+	return forks, nil
+}
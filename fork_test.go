@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDetectFork(t *testing.T) {
+	bc := newTestBlockchain(t, nil)
+	local := mineTestBlock(t, bc, "local")
+
+	other := newTestBlockchain(t, nil)
+	rival := mineTestBlock(t, other, "rival")
+	rivalChild := mineTestBlock(t, other, "rival")
+
+	next := mineTestBlock(t, newTipChain(t, local), "next")
+	sibling := mineTestBlock(t, newTipChain(t, local), "sibling")
+
+	tampered := rival.clone()
+	tampered.Nonce++
+
+	tests := []struct {
+		name       string
+		candidates []Block
+		want       []Block
+		wantErr    error
+	}{
+		{"competing block", []Block{rival}, []Block{rival}, nil},
+		{"competing branch reports its first block", []Block{rival, rivalChild}, []Block{rival}, nil},
+		{"orphan is skipped", []Block{rivalChild}, []Block{}, nil},
+		{"block already in chain", []Block{local}, []Block{}, nil},
+		{"block extending the tip", []Block{next}, []Block{}, nil},
+		{"two candidates on the tip", []Block{next, sibling}, []Block{next, sibling}, nil},
+		{"tampered candidate", []Block{tampered}, nil, ErrInvalidBlock},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forks, err := bc.DetectFork(tt.candidates)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DetectFork() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(forks, tt.want) {
+				t.Errorf("DetectFork() = %d blocks, want %d", len(forks), len(tt.want))
+			}
+		})
+	}
+
+	if height, hash := bc.Tip(); height != 1 || hash != local.Hash {
+		t.Errorf("DetectFork() changed the chain")
+	}
+}
+
+// newTipChain returns a chain of the default test genesis extended by block
+func newTipChain(t *testing.T, block Block) *Blockchain {
+	t.Helper()
+	bc := newTestBlockchain(t, nil)
+	if _, err := bc.AppendBlock(block); err != nil {
+		t.Fatalf("AppendBlock(): %v", err)
+	}
+	return bc
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+
+	"blockchain/nodegrpc"
+	"blockchain/proto/nodepb"
+)
+
+// grpcBackend exposes a blockchain to the gRPC Node service (see nodegrpc.NewServer)
+type grpcBackend struct {
+	bc *Blockchain
+}
+
+// Blocks implements nodegrpc.Backend. A start one past the tip yields no blocks, a start beyond it
+// an error wrapping nodegrpc.ErrInvalidRange
+func (b grpcBackend) Blocks(start, limit int64) ([]*nodepb.Block, error) {
+	chain := b.bc.GetChain()
+	if start > int64(len(chain)) {
+		return nil, fmt.Errorf("%w: start index %d is beyond the tip %d", nodegrpc.ErrInvalidRange, start, len(chain)-1)
+	}
+
+	chain = chain[start:]
+	if limit > 0 && limit < int64(len(chain)) {
+		chain = chain[:limit]
+	}
+
+	blocks := make([]*nodepb.Block, len(chain))
+	for i, block := range chain {
+		blocks[i] = blockToProto(block)
+	}
+	return blocks, nil
+}
+
+// SubmitTransaction implements nodegrpc.Backend with the checks of submitTransaction
+func (b grpcBackend) SubmitTransaction(tx *nodepb.Transaction) (string, error) {
+	return b.bc.submitTransaction(transactionFromProto(tx))
+}
+
+// Tip implements nodegrpc.Backend
+func (b grpcBackend) Tip() (*nodepb.Block, int64, error) {
+	block, err := b.bc.GetLatestBlock()
+	if err != nil {
+		return nil, 0, err
+	}
+	return blockToProto(block), int64(block.Index), nil
+}
+
+// blockToProto converts a block to its gRPC message, field by field
+func blockToProto(block Block) *nodepb.Block {
+	msg := &nodepb.Block{
+		Header: &nodepb.BlockHeader{
+			Index:        int64(block.Index),
+			Timestamp:    block.Timestamp,
+			Nonce:        block.Nonce,
+			Difficulty:   int64(block.Difficulty),
+			PreviousHash: block.PreviousHash,
+			MerkleRoot:   block.MerkleRoot,
+			Hash:         block.Hash,
+		},
+		Pruned: block.Pruned,
+	}
+	for _, tx := range block.Transactions {
+		msg.Transactions = append(msg.Transactions, transactionToProto(tx))
+	}
+	return msg
+}
+
+// blockFromProto converts a gRPC block message back to a block; a message without a header yields a zero header
+func blockFromProto(msg *nodepb.Block) Block {
+	header := msg.GetHeader()
+	block := Block{
+		BlockHeader: BlockHeader{
+			Index:        int(header.GetIndex()),
+			Timestamp:    header.GetTimestamp(),
+			Nonce:        header.GetNonce(),
+			Difficulty:   int(header.GetDifficulty()),
+			PreviousHash: header.GetPreviousHash(),
+			MerkleRoot:   header.GetMerkleRoot(),
+			Hash:         header.GetHash(),
+		},
+		Transactions: []Transaction{},
+		Pruned:       msg.GetPruned(),
+	}
+	for _, tx := range msg.GetTransactions() {
+		block.Transactions = append(block.Transactions, transactionFromProto(tx))
+	}
+	return block
+}
+
+// transactionToProto converts a transaction to its gRPC message, field by field
+func transactionToProto(tx Transaction) *nodepb.Transaction {
+	msg := &nodepb.Transaction{
+		Sender:     tx.Sender,
+		Recipient:  tx.Recipient,
+		Amount:     tx.Amount,
+		Fee:        tx.Fee,
+		Nonce:      tx.Nonce,
+		Txid:       tx.TXID,
+		Signature:  tx.Signature,
+		PublicKey:  tx.PublicKey,
+		Memo:       tx.Memo,
+		ExpiryTime: tx.ExpiryTime,
+	}
+	for _, in := range tx.Inputs {
+		msg.Inputs = append(msg.Inputs, &nodepb.TXInput{Txid: in.TXID, Output: int64(in.Output)})
+	}
+	for _, out := range tx.Outputs {
+		msg.Outputs = append(msg.Outputs, &nodepb.TXOutput{Address: out.Address, Amount: out.Amount})
+	}
+	return msg
+}
+
+// transactionFromProto converts a gRPC transaction message back to a transaction. Empty repeated and bytes
+// fields become nil, as they decode from JSON, so the TXID of the transaction does not change
+func transactionFromProto(msg *nodepb.Transaction) Transaction {
+	tx := Transaction{
+		Sender:     msg.GetSender(),
+		Recipient:  msg.GetRecipient(),
+		Amount:     msg.GetAmount(),
+		Fee:        msg.GetFee(),
+		Nonce:      msg.GetNonce(),
+		TXID:       msg.GetTxid(),
+		Memo:       msg.GetMemo(),
+		ExpiryTime: msg.GetExpiryTime(),
+	}
+	if len(msg.GetSignature()) > 0 {
+		tx.Signature = msg.GetSignature()
+	}
+	if len(msg.GetPublicKey()) > 0 {
+		tx.PublicKey = msg.GetPublicKey()
+	}
+	for _, in := range msg.GetInputs() {
+		tx.Inputs = append(tx.Inputs, TXInput{TXID: in.GetTxid(), Output: int(in.GetOutput())})
+	}
+	for _, out := range msg.GetOutputs() {
+		tx.Outputs = append(tx.Outputs, TXOutput{Address: out.GetAddress(), Amount: out.GetAmount()})
+	}
+	return tx
+}
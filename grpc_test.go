@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+
+	"blockchain/nodegrpc"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestGRPCClient serves the Node service for bc over an in-process bufconn listener and returns a client for it
+func newTestGRPCClient(t *testing.T, bc *Blockchain) *nodegrpc.Client {
+	t.Helper()
+	ln := bufconn.Listen(1 << 20)
+	server := nodegrpc.NewServer(grpcBackend{bc: bc})
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+
+	client, err := nodegrpc.Dial("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Dial(): %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestGRPCGetBlocks(t *testing.T) {
+	bc := newTestBlockchain(t, map[string]int64{"alice": 100})
+	signed, _ := signedTestTransaction(t, 10)
+	if _, err := bc.submitTransaction(Transaction{Sender: "alice", Recipient: "bob", Amount: 30, Memo: "rent"}); err != nil {
+		t.Fatalf("submitTransaction(): %v", err)
+	}
+	mineTestBlock(t, bc, "miner")
+	mineTestBlock(t, bc, "miner")
+	chain := bc.GetChain()
+	client := newTestGRPCClient(t, bc)
+
+	tests := []struct {
+		name         string
+		start, limit int64
+		want         []Block
+		wantCode     codes.Code
+	}{
+		{"whole chain", 0, 0, chain, codes.OK},
+		{"limited", 1, 1, chain[1:2], codes.OK},
+		{"past the tip", 3, 0, []Block{}, codes.OK},
+		{"beyond the tip", 4, 0, nil, codes.OutOfRange},
+		{"negative start", -1, 0, nil, codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msgs, err := client.GetBlocks(context.Background(), tt.start, tt.limit)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("GetBlocks() error = %v, want code %v", err, tt.wantCode)
+			}
+			if err != nil {
+				return
+			}
+
+			blocks := []Block{}
+			for _, msg := range msgs {
+				blocks = append(blocks, blockFromProto(msg))
+			}
+			if !reflect.DeepEqual(blocks, tt.want) {
+				t.Errorf("GetBlocks() = %+v, want %+v", blocks, tt.want)
+			}
+			for _, block := range blocks {
+				if calculateHash(bc.hasher(), block.BlockHeader) != block.Hash {
+					t.Errorf("block %d does not match its hash after the conversion", block.Index)
+				}
+			}
+		})
+	}
+
+	// a signed transaction keeps its TXID and signature through the conversion
+	if got := transactionFromProto(transactionToProto(signed)); !reflect.DeepEqual(got, signed) || !VerifyTransaction(got) {
+		t.Errorf("converted transaction = %+v, want %+v", got, signed)
+	}
+}
+
+func TestGRPCSubmitTransactionAndGetTip(t *testing.T) {
+	bc := newTestBlockchain(t, map[string]int64{"alice": 100})
+	client := newTestGRPCClient(t, bc)
+	ctx := context.Background()
+
+	txid, err := client.SubmitTransaction(ctx, transactionToProto(Transaction{Sender: "alice", Recipient: "bob", Amount: 30}))
+	if err != nil {
+		t.Fatalf("SubmitTransaction(): %v", err)
+	}
+	if mempool := bc.Mempool(); len(mempool) != 1 || mempool[0].TXID != txid {
+		t.Errorf("mempool = %v, want the submitted transaction %s", mempool, txid)
+	}
+
+	_, err = client.SubmitTransaction(ctx, transactionToProto(Transaction{Sender: "alice", Recipient: "bob", Amount: 1000}))
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("SubmitTransaction() of an overdraft error = %v, want InvalidArgument", err)
+	}
+
+	mined := mineTestBlock(t, bc, "miner")
+	tip, height, err := client.GetTip(ctx)
+	if err != nil {
+		t.Fatalf("GetTip(): %v", err)
+	}
+	if height != 1 || !reflect.DeepEqual(blockFromProto(tip), mined) {
+		t.Errorf("GetTip() = %+v at %d, want %+v at 1", blockFromProto(tip), height, mined)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Hasher produces the hex encoded digest used for block hashes, transaction IDs and Merkle tree nodes.
+// A blockchain uses a single Hasher for its whole lifetime, since changing it invalidates every stored hash.
+// Implementations must be safe for concurrent use, as mining hashes block candidates from several goroutines
+type Hasher interface {
+	Hash(data []byte) string
+}
+
+// SHA256Hasher is the default Hasher, hashing data with a single pass of SHA-256
+type SHA256Hasher struct{}
+
+// Hash returns the hex encoded SHA-256 digest of data
+func (SHA256Hasher) Hash(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// DoubleSHA256Hasher hashes data with two passes of SHA-256, sha256(sha256(data)), as Bitcoin does
+type DoubleSHA256Hasher struct{}
+
+// Hash returns the hex encoded SHA-256 digest of the SHA-256 digest of data
+func (DoubleSHA256Hasher) Hash(data []byte) string {
+	first := sha256.Sum256(data)
+	hash := sha256.Sum256(first[:])
+	return hex.EncodeToString(hash[:])
+}
+
+// hasherNames maps the names persisted by SaveToFile to the built-in hashers
+var hasherNames = map[string]Hasher{
+	"sha256":        SHA256Hasher{},
+	"double-sha256": DoubleSHA256Hasher{},
+}
+
+// hasherName returns the persisted name of a built-in hasher, or an empty string for any other hasher
+func hasherName(h Hasher) string {
+	for name, builtin := range hasherNames {
+		if h == builtin {
+			return name
+		}
+	}
+	return ""
+}
+
+// hasherOrDefault returns h, or SHA256Hasher if h is nil
+func hasherOrDefault(h Hasher) Hasher {
+	if h == nil {
+		return SHA256Hasher{}
+	}
+	return h
+}
+
+// hasher returns the hasher of the chain, falling back to SHA256Hasher when none is set
+func (bc *Blockchain) hasher() Hasher {
+	return hasherOrDefault(bc.Hasher)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync/atomic"
+	"testing"
+)
+
+// mockHasher hashes with SHA-256 over a "mock" prefix, so its digests differ from those of SHA256Hasher,
+// and counts its calls
+type mockHasher struct {
+	calls *atomic.Int64
+}
+
+func (h mockHasher) Hash(data []byte) string {
+	h.calls.Add(1)
+	hash := sha256.Sum256(append([]byte("mock"), data...))
+	return hex.EncodeToString(hash[:])
+}
+
+func TestInjectedHasherIsUsed(t *testing.T) {
+	h := mockHasher{calls: &atomic.Int64{}}
+	bc := createBlockchainWithGenesis(GenesisConfig{
+		Timestamp:  defaultGenesisTimestamp,
+		Difficulty: 1,
+		Balances:   map[string]int64{"alice": 1000},
+		Hasher:     h,
+	})
+	if _, err := bc.addTransaction("alice", "bob", 10); err != nil {
+		t.Fatalf("addTransaction(): %v", err)
+	}
+	block := mineTestBlock(t, bc, "miner")
+	if h.calls.Load() == 0 {
+		t.Fatalf("the injected hasher was never called")
+	}
+
+	for _, b := range bc.GetChain() {
+		if b.Hash != h.Hash(b.serializeForHash()) {
+			t.Errorf("block %d hash %s is not the injected hash of its header", b.Index, b.Hash)
+		}
+		if b.Hash == calculateHash(SHA256Hasher{}, b.BlockHeader) {
+			t.Errorf("block %d is hashed with the default hasher", b.Index)
+		}
+		if b.MerkleRoot != computeMerkleRoot(h, b.Transactions) {
+			t.Errorf("block %d Merkle root is not computed with the injected hasher", b.Index)
+		}
+	}
+	for _, tx := range block.Transactions {
+		if tx.TXID != generateTransactionID(h, tx) {
+			t.Errorf("transaction %s is not identified with the injected hasher", tx.TXID)
+		}
+	}
+	if valid, err := bc.IsChainValid(); !valid {
+		t.Errorf("IsChainValid() = false: %v", err)
+	}
+}
+
+func TestHasherVectors(t *testing.T) {
+	tests := []struct {
+		name   string
+		hasher Hasher
+		data   string
+		want   string
+	}{
+		{"sha256 empty", SHA256Hasher{}, "", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"sha256 abc", SHA256Hasher{}, "abc", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"double sha256 empty", DoubleSHA256Hasher{}, "", "5df6e0e2761359d30a8275058e299fcc0381534545f55cf43e41983f5d4c9456"},
+		{"double sha256 abc", DoubleSHA256Hasher{}, "abc", "4f8b42c22dd3729b519ba6f68d2da7cc5b2d606d05daed5ad5128cc03e6c6358"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.hasher.Hash([]byte(tt.data)); got != tt.want {
+			t.Errorf("%s: Hash(%q) = %s, want %s", tt.name, tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestDoubleSHA256Chain(t *testing.T) {
+	single := createBlockchainWithGenesis(GenesisConfig{Timestamp: defaultGenesisTimestamp, Difficulty: 1})
+	double := createBlockchainWithGenesis(GenesisConfig{Timestamp: defaultGenesisTimestamp, Difficulty: 1, Hasher: DoubleSHA256Hasher{}})
+
+	genesis, doubleGenesis := single.GetChain()[0], double.GetChain()[0]
+	if genesis.Hash == doubleGenesis.Hash {
+		t.Fatalf("single and double SHA-256 genesis blocks share the hash %s", genesis.Hash)
+	}
+	header := doubleGenesis.BlockHeader
+	if want := (SHA256Hasher{}).Hash(mustDecodeHex(t, (SHA256Hasher{}).Hash(header.serializeForHash()))); doubleGenesis.Hash != want {
+		t.Errorf("double SHA-256 genesis hash = %s, want sha256(sha256(header)) %s", doubleGenesis.Hash, want)
+	}
+
+	block := mineTestBlock(t, double, "miner")
+	if block.Hash != calculateHash(DoubleSHA256Hasher{}, block.BlockHeader) || block.Transactions[0].TXID != generateTransactionID(DoubleSHA256Hasher{}, block.Transactions[0]) {
+		t.Errorf("mined block is not hashed with double SHA-256")
+	}
+	if valid, err := double.IsChainValid(); !valid {
+		t.Errorf("IsChainValid() = false: %v", err)
+	}
+}
+
+// mustDecodeHex decodes a hex string and fails the test on an error
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("hex.DecodeString(%q): %v", s, err)
+	}
+	return data
+}
@@ -0,0 +1,103 @@
+package main
+
+import "fmt"
+
+// Direction tells whether a transaction in an address's history was sent or received by the address
+type Direction string
+
+const (
+	DirectionIncoming Direction = "incoming" // the address is the recipient
+	DirectionOutgoing Direction = "outgoing" // the address is the sender
+)
+
+// HistoryEntry is a transaction involving an address together with the block confirming it
+// and its direction as seen from the address
+type HistoryEntry struct {
+	Transaction
+	BlockIndex int       `json:"block_index"` // UnconfirmedBlockIndex for mempool transactions
+	Direction  Direction `json:"direction"`
+}
+
+// GetTransactionHistory returns every confirmed transaction sent or received by the address in chain order, once
+// even if it pays the address in several outputs,
+// followed by its pending mempool transactions in arrival order if includePending is set
+func (bc *Blockchain) GetTransactionHistory(address string, includePending bool) []HistoryEntry {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	history := []HistoryEntry{}
+	add := func(tx Transaction, blockIndex int) {
+		if tx.Sender == address {
+			history = append(history, HistoryEntry{Transaction: tx.clone(), BlockIndex: blockIndex, Direction: DirectionOutgoing})
+			return
+		}
+		for _, out := range tx.payments() {
+			if out.Address == address {
+				history = append(history, HistoryEntry{Transaction: tx.clone(), BlockIndex: blockIndex, Direction: DirectionIncoming})
+				return
+			}
+		}
+	}
+
+	for _, block := range bc.Chain {
+		for _, tx := range block.Transactions {
+			add(tx, block.Index)
+		}
+	}
+	if includePending {
+		for _, tx := range bc.Transactions {
+			add(tx, UnconfirmedBlockIndex)
+		}
+	}
+
+	return history
+}
+
+// ConfirmedTransaction is a transaction together with the index of the block confirming it
+type ConfirmedTransaction struct {
+	Transaction
+	BlockIndex int `json:"block_index"`
+}
+
+// RecentTransactions returns the last n confirmed transactions of the whole chain, most recent first:
+// the blocks are walked from the tip towards genesis and the transactions of each block in reverse order,
+// stopping as soon as n are collected. Fewer are returned if the chain holds fewer; transactions of pruned
+// blocks are gone and n of zero or less yields an empty slice
+func (bc *Blockchain) RecentTransactions(n int) []ConfirmedTransaction {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	recent := []ConfirmedTransaction{}
+	for i := len(bc.Chain) - 1; i >= 0 && len(recent) < n; i-- {
+		block := bc.Chain[i]
+		for j := len(block.Transactions) - 1; j >= 0 && len(recent) < n; j-- {
+			recent = append(recent, ConfirmedTransaction{Transaction: block.Transactions[j].clone(), BlockIndex: block.Index})
+		}
+	}
+
+	return recent
+}
+
+// TransactionsInRange returns every confirmed transaction whose amount, in base units, lies within
+// [minAmount, maxAmount] in chain order, e.g. all transfers above a threshold with maxAmount set to math.MaxInt64.
+// The amount of a transaction paying several outputs is their total; coinbase transactions are included and
+// transactions of pruned blocks are gone. Returns ErrInvalidRange if minAmount exceeds maxAmount
+func (bc *Blockchain) TransactionsInRange(minAmount, maxAmount int64) ([]Transaction, error) {
+	if minAmount > maxAmount {
+		return nil, fmt.Errorf("%w: minimum %s exceeds maximum %s", ErrInvalidRange, formatAmount(minAmount), formatAmount(maxAmount))
+	}
+
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	matches := []Transaction{}
+	for _, block := range bc.Chain {
+		for _, tx := range block.Transactions {
+			if tx.Amount >= minAmount && tx.Amount <= maxAmount {
+				matches = append(matches, tx.clone())
+			}
+		}
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestTransactionsInRange(t *testing.T) {
+	bc := newTestBlockchain(t, map[string]int64{"alice": 10_000})
+	var txids []string
+	for _, amount := range []int64{10, 50, 200} {
+		txid, err := bc.addTransaction("alice", "bob", amount)
+		if err != nil {
+			t.Fatalf("addTransaction(): %v", err)
+		}
+		txids = append(txids, txid)
+	}
+	multi, err := bc.addMultiTransaction("alice", []TXOutput{{Address: "carol", Amount: 30}, {Address: "dave", Amount: 40}})
+	if err != nil {
+		t.Fatalf("addMultiTransaction(): %v", err)
+	}
+	coinbase := mineTestBlock(t, bc, "miner").Transactions[0]
+	if _, err := bc.addTransaction("alice", "bob", 20); err != nil {
+		t.Fatalf("addTransaction(): %v", err) // pending transactions are never in range
+	}
+	allocation := bc.GetChain()[0].Transactions[0]
+
+	tests := []struct {
+		name     string
+		min, max int64
+		want     []string
+		wantErr  error
+	}{
+		{"some", 40, 100, []string{txids[1], multi}, nil},
+		{"bounds included", 10, 10, []string{txids[0]}, nil},
+		{"multi-output total", 70, 70, []string{multi}, nil},
+		{"all", 0, math.MaxInt64, []string{allocation.TXID, coinbase.TXID, txids[0], txids[1], txids[2], multi}, nil},
+		{"none", 201, 9_999, []string{}, nil},
+		{"inverted", 100, 40, nil, ErrInvalidRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := bc.TransactionsInRange(tt.min, tt.max)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("TransactionsInRange(%d, %d) error = %v, want %v", tt.min, tt.max, err, tt.wantErr)
+			}
+			var ids []string
+			if got != nil {
+				ids = []string{}
+			}
+			for _, tx := range got {
+				ids = append(ids, tx.TXID)
+			}
+			if !slices.Equal(ids, tt.want) || (got == nil) != (tt.want == nil) {
+				t.Errorf("TransactionsInRange(%d, %d) = %v, want %v", tt.min, tt.max, ids, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+// OnNewBlock registers fn to be called with every block appended by addBlock, mined by MineBlock
+// or submitted through SubmitMinedBlock.
+// Callbacks run in the registration order on the goroutine which added the block, after the chain
+// is consistent and the lock is released, so they may call back into the blockchain.
+// Blocks adopted through ReplaceChain or AppendBlock are not reported
+func (bc *Blockchain) OnNewBlock(fn func(Block)) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	bc.onNewBlock = append(bc.onNewBlock, fn)
+}
+
+// notifyNewBlock passes a copy of the block to every registered callback; the caller must not hold the lock
+func (bc *Blockchain) notifyNewBlock(block Block) {
+	bc.mu.RLock()
+	callbacks := append([]func(Block){}, bc.onNewBlock...)
+	bc.mu.RUnlock()
+
+	for _, fn := range callbacks {
+		fn(block.clone())
+	}
+}
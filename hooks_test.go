@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOnNewBlockNotifiesEveryCallback(t *testing.T) {
+	bc := newTestBlockchain(t, nil)
+
+	var order []string
+	var first, second []Block
+	bc.OnNewBlock(func(b Block) {
+		order = append(order, "first")
+		first = append(first, b)
+	})
+	bc.OnNewBlock(func(b Block) {
+		order = append(order, "second")
+		second = append(second, b)
+		// the lock is released, so a callback may read the chain
+		if height, hash := bc.Tip(); height != b.Index || hash != b.Hash {
+			t.Errorf("Tip() in the callback = %d, %s, want the notified block", height, hash)
+		}
+	})
+
+	mined := mineTestBlock(t, bc, "miner")
+	if !reflect.DeepEqual(first, []Block{mined}) || !reflect.DeepEqual(second, []Block{mined}) {
+		t.Fatalf("callbacks received %v and %v, want the mined block once each", first, second)
+	}
+	if !reflect.DeepEqual(order, []string{"first", "second"}) {
+		t.Errorf("callbacks ran in order %v, want registration order", order)
+	}
+
+	// blocks adopted from peers are not reported
+	other := newTestBlockchain(t, nil)
+	mineTestBlock(t, other, "other")
+	mineTestBlock(t, other, "other")
+	if replaced, err := bc.ReplaceChain(other.GetChain()); !replaced {
+		t.Fatalf("ReplaceChain() = false, %v", err)
+	}
+	if len(first) != 1 || len(second) != 1 {
+		t.Errorf("ReplaceChain() notified the callbacks")
+	}
+}
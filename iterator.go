@@ -0,0 +1,44 @@
+package main
+
+// ChainIterator walks the chain from the tip back to the genesis block following the PreviousHash links,
+// one block per call to Next, without copying the chain. It does not hold the lock between calls, so blocks
+// may be mined while it is in use; they are not visited, as the iterator starts at the tip it was created at
+type ChainIterator struct {
+	bc    *Blockchain
+	index int    // position of the next block in bc.Chain; -1 once the genesis block was returned
+	hash  string // hash the next block must have, the PreviousHash of the last returned block
+}
+
+// Iterator returns an iterator starting at the current tip of the chain; the iterator of an empty chain is exhausted
+func (bc *Blockchain) Iterator() *ChainIterator {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if len(bc.Chain) == 0 {
+		return &ChainIterator{bc: bc, index: -1}
+	}
+	tip := bc.Chain[len(bc.Chain)-1]
+	return &ChainIterator{bc: bc, index: tip.Index, hash: tip.Hash}
+}
+
+// Next returns a copy of the next block towards genesis and true, or false once the genesis block was returned.
+// It also returns false if the chain was replaced by ReplaceChain since the iterator started and the block
+// it links to is no longer part of the chain; a pruned block is returned as its header-only stub
+func (it *ChainIterator) Next() (Block, bool) {
+	if it.index < 0 {
+		return Block{}, false
+	}
+
+	it.bc.mu.RLock()
+	defer it.bc.mu.RUnlock()
+
+	if it.index >= len(it.bc.Chain) || it.bc.Chain[it.index].Hash != it.hash {
+		it.index = -1
+		return Block{}, false
+	}
+
+	block := it.bc.Chain[it.index].clone()
+	it.index--
+	it.hash = block.PreviousHash
+	return block, true
+}
@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestChainIterator(t *testing.T) {
+	bc := newTestBlockchain(t, nil)
+	for range 4 {
+		mineTestBlock(t, bc, "miner")
+	}
+	chain := bc.GetChain()
+
+	it := bc.Iterator()
+	mineTestBlock(t, bc, "miner") // mined after the iterator started, so not visited
+	count := 0
+	for block, ok := it.Next(); ok; block, ok = it.Next() {
+		want := chain[len(chain)-1-count]
+		if block.Hash != want.Hash {
+			t.Errorf("block %d of the iteration has index %d, want %d", count, block.Index, want.Index)
+		}
+		count++
+	}
+	if count != len(chain) {
+		t.Errorf("iterated %d blocks, want %d", count, len(chain))
+	}
+	if _, ok := it.Next(); ok {
+		t.Error("Next() after the genesis block returned a block")
+	}
+}
+
+func TestChainIteratorStopsAtReplacedBlock(t *testing.T) {
+	local, competitor := forkTestChains(t)
+	mineTestBlock(t, competitor, "other")
+	it := local.Iterator()
+	if _, err := local.ReplaceChain(competitor.GetChain()); err != nil {
+		t.Fatalf("ReplaceChain(): %v", err)
+	}
+
+	if block, ok := it.Next(); ok {
+		t.Errorf("Next() = block %d of the replaced chain, want false", block.Index)
+	}
+}
+
+func TestChainIteratorOfEmptyChain(t *testing.T) {
+	it := (&Blockchain{}).Iterator()
+	if block, ok := it.Next(); ok {
+		t.Errorf("Next() = block %d, want an exhausted iterator", block.Index)
+	}
+}
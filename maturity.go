@@ -0,0 +1,41 @@
+package main
+
+// coinbaseMatureLocked reports whether the coinbase transaction of the block at index can be spent,
+// i.e. at least bc.CoinbaseMaturity blocks were mined on top of it. Genesis allocations are always spendable
+func (bc *Blockchain) coinbaseMatureLocked(index int) bool {
+	return bc.coinbaseMatureAtLocked(index, len(bc.Chain)-1)
+}
+
+// coinbaseMatureAtLocked reports whether the coinbase transaction of the block at index was spendable
+// once the block at height was the tip
+func (bc *Blockchain) coinbaseMatureAtLocked(index, height int) bool {
+	return bc.CoinbaseMaturity <= 0 || index == 0 || height-index >= bc.CoinbaseMaturity
+}
+
+// immatureOutputsLocked returns the outpoints of the coinbase outputs which cannot be spent yet
+func (bc *Blockchain) immatureOutputsLocked() map[string]bool {
+	immature := map[string]bool{}
+	for i := len(bc.Chain) - 1; i > 0 && !bc.coinbaseMatureLocked(i); i-- {
+		for _, tx := range bc.Chain[i].Transactions {
+			if tx.Sender != coinbaseSender {
+				continue
+			}
+			for j := range tx.outputs() {
+				immature[outpoint(tx.TXID, j)] = true
+			}
+		}
+	}
+
+	return immature
+}
+
+// unspendableOutputsLocked returns the outpoints new transactions must not spend: those spent by
+// mempool transactions and immature coinbase outputs
+func (bc *Blockchain) unspendableOutputsLocked() map[string]bool {
+	unspendable := bc.mempoolSpentLocked()
+	for key := range bc.immatureOutputsLocked() {
+		unspendable[key] = true
+	}
+
+	return unspendable
+}
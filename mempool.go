@@ -0,0 +1,300 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// Mempool returns a copy of the pending transactions in arrival order which shares no slices with the mempool
+func (bc *Blockchain) Mempool() []Transaction {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	pending := make([]Transaction, len(bc.Transactions))
+	for i, tx := range bc.Transactions {
+		pending[i] = tx.clone()
+	}
+
+	return pending
+}
+
+// ClearMempool drops every pending transaction; the nonces of their senders become available again
+func (bc *Blockchain) ClearMempool() {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	bc.Transactions = []Transaction{}
+	bc.syncReceivedLocked()
+	bc.rebuildNoncesLocked()
+}
+
+// PruneMempool drops every mempool transaction received more than maxAge ago or whose ExpiryTime has passed,
+// together with the later transactions of the same senders which can no longer be mined without them.
+// Returns the number of dropped transactions
+func (bc *Blockchain) PruneMempool(maxAge time.Duration) int {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	now := time.Now()
+	cutoff, timestamp := now.Add(-maxAge), bc.timestampLocked(now)
+	return bc.dropFromMempoolLocked(func(tx Transaction) bool {
+		return bc.received[tx.TXID].Before(cutoff) || tx.expiredAt(timestamp)
+	})
+}
+
+// ReplaceTransaction bumps the fee of the pending transaction oldTXID to newFee base units: the transaction
+// is swapped in place for a copy paying newFee, with the same nonce and a recomputed TXID, which is returned.
+// With the UTXO model its inputs and change are selected again to cover the higher fee. Its receive time is kept,
+// so a fee bump does not delay PruneMempool. Returns ErrTransactionNotFound if oldTXID is not in the mempool,
+// ErrFeeTooLow if newFee does not exceed the current fee, ErrInsufficientFunds if the sender cannot afford
+// the higher fee besides its other pending transactions, and ErrInvalidSignature for a signed transaction,
+// since changing the fee invalidates its signature; the sender has to sign and submit a replacement itself
+func (bc *Blockchain) ReplaceTransaction(oldTXID string, newFee int64) (string, error) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	index := -1
+	for i, tx := range bc.Transactions {
+		if tx.TXID == oldTXID {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return "", fmt.Errorf("%w: %s is not pending in the mempool", ErrTransactionNotFound, oldTXID)
+	}
+
+	old := bc.Transactions[index]
+	if newFee <= old.Fee {
+		return "", fmt.Errorf("%w: new fee %s does not exceed fee %s of %s", ErrFeeTooLow, formatAmount(newFee), formatAmount(old.Fee), oldTXID)
+	}
+	if len(old.Signature) > 0 {
+		return "", fmt.Errorf("%w: changing the fee of %s invalidates its signature", ErrInvalidSignature, oldTXID)
+	}
+
+	tx := old.clone()
+	tx.Fee = newFee
+	if err := validateTransactionFields(tx); err != nil {
+		return "", err
+	}
+
+	// the funds of the replaced transaction are available to its replacement
+	pending := bc.Transactions
+	bc.Transactions = append(append([]Transaction{}, pending[:index]...), pending[index+1:]...)
+	defer func() { bc.Transactions = pending }()
+
+	if bc.useUTXO {
+		tx.Inputs, tx.Outputs = nil, nil
+		if err := bc.fillUTXOTransactionLocked(&tx); err != nil {
+			return "", err
+		}
+	} else {
+		available := bc.balanceLocked(tx.Sender) - bc.pendingOutgoingLocked(tx.Sender)
+		if tx.Amount+tx.Fee > available {
+			return "", fmt.Errorf("%w: %s has %s available, needs %s", ErrInsufficientFunds, tx.Sender, formatAmount(available), formatAmount(tx.Amount+tx.Fee))
+		}
+	}
+
+	tx.TXID = generateTransactionID(bc.hasher(), tx)
+	if bc.hasTransactionLocked(tx.TXID) {
+		return "", fmt.Errorf("%w: %s", ErrDuplicateTransaction, tx.TXID)
+	}
+
+	pending[index] = tx
+	bc.received[tx.TXID] = bc.received[oldTXID]
+	delete(bc.received, oldTXID)
+
+	return tx.TXID, nil
+}
+
+// makeRoomLocked evicts the cheapest mempool transaction, the oldest one among equal fees, if the mempool is full
+// and tx pays a higher fee; otherwise a full mempool rejects tx with ErrMempoolFull.
+// Transactions of tx's own sender are never evicted for it, as that would invalidate tx's nonce
+func (bc *Blockchain) makeRoomLocked(tx Transaction) error {
+	if bc.MaxMempoolSize <= 0 || len(bc.Transactions) < bc.MaxMempoolSize {
+		return nil
+	}
+
+	victim := -1
+	for i, pending := range bc.Transactions {
+		if pending.Sender == tx.Sender {
+			continue
+		}
+		if victim == -1 || pending.Fee < bc.Transactions[victim].Fee {
+			victim = i
+		}
+	}
+
+	if victim == -1 || tx.Fee <= bc.Transactions[victim].Fee {
+		return fmt.Errorf("%w: %d transactions pending", ErrMempoolFull, len(bc.Transactions))
+	}
+
+	evicted := bc.Transactions[victim].TXID
+	bc.dropFromMempoolLocked(func(pending Transaction) bool {
+		return pending.TXID == evicted
+	})
+
+	return nil
+}
+
+// dropFromMempoolLocked removes the mempool transactions matched by drop and every pending transaction
+// of the same sender with a higher nonce, then recalculates the sender nonces.
+// Returns the number of removed transactions
+func (bc *Blockchain) dropFromMempoolLocked(drop func(Transaction) bool) int {
+	lowestDropped := map[string]uint64{}
+	for _, tx := range bc.Transactions {
+		if !drop(tx) {
+			continue
+		}
+		if nonce, ok := lowestDropped[tx.Sender]; !ok || tx.Nonce < nonce {
+			lowestDropped[tx.Sender] = tx.Nonce
+		}
+	}
+
+	remaining := []Transaction{}
+	for _, tx := range bc.Transactions {
+		nonce, senderDropped := lowestDropped[tx.Sender]
+		if drop(tx) || (senderDropped && tx.Nonce > nonce) {
+			delete(bc.received, tx.TXID)
+			continue
+		}
+		remaining = append(remaining, tx)
+	}
+
+	dropped := len(bc.Transactions) - len(remaining)
+	bc.Transactions = remaining
+	bc.rebuildNoncesLocked()
+
+	return dropped
+}
+
+// mempoolRejectionsLocked rechecks the mempool against the current chain state and returns, keyed by TXID,
+// why each transaction which can never be mined is rejected: its TXID does not match its contents or its fields
+// are invalid, its signature does not verify (or it is unsigned while bc.RequireSignatures is set), it is already
+// confirmed by the chain or its nonce is not above the last one the chain confirmed for its sender. A later transaction of the same sender is rejected with it, as its nonce can no longer follow.
+// A transaction its sender cannot afford is not rejected here; selectMempoolLocked skips it until it becomes affordable
+func (bc *Blockchain) mempoolRejectionsLocked() map[string]error {
+	confirmed := map[string]bool{}
+	for _, block := range bc.Chain {
+		for _, tx := range block.Transactions {
+			confirmed[tx.TXID] = true
+		}
+	}
+
+	nonces := bc.confirmedNoncesLocked()
+	rejected := map[string]error{}
+	lowestRejected := map[string]uint64{}
+	for _, tx := range bc.Transactions {
+		err := validateTransaction(bc.hasher(), tx)
+		switch {
+		case err != nil:
+		case (len(tx.Signature) > 0 || bc.RequireSignatures) && !verifyTransaction(bc.hasher(), tx):
+			err = fmt.Errorf("%w: transaction %s", ErrInvalidSignature, tx.TXID)
+		case confirmed[tx.TXID]:
+			err = fmt.Errorf("%w: %s is already confirmed", ErrDuplicateTransaction, tx.TXID)
+		case tx.Nonce <= nonces[tx.Sender]:
+			err = fmt.Errorf("%w: %d is not above confirmed nonce %d of %s", ErrInvalidNonce, tx.Nonce, nonces[tx.Sender], tx.Sender)
+		default:
+			continue
+		}
+
+		rejected[tx.TXID] = err
+		if nonce, ok := lowestRejected[tx.Sender]; !ok || tx.Nonce < nonce {
+			lowestRejected[tx.Sender] = tx.Nonce
+		}
+	}
+
+	for _, tx := range bc.Transactions {
+		if nonce, ok := lowestRejected[tx.Sender]; ok && tx.Nonce > nonce && rejected[tx.TXID] == nil {
+			rejected[tx.TXID] = fmt.Errorf("%w: %d follows rejected nonce %d of %s", ErrInvalidNonce, tx.Nonce, nonce, tx.Sender)
+		}
+	}
+
+	return rejected
+}
+
+// dropRejectedMempoolLocked removes the transactions found by mempoolRejectionsLocked from the mempool,
+// logging each with the reason, and returns them
+func (bc *Blockchain) dropRejectedMempoolLocked() []Transaction {
+	rejected := bc.mempoolRejectionsLocked()
+	if len(rejected) == 0 {
+		return nil
+	}
+
+	dropped := []Transaction{}
+	for _, tx := range bc.Transactions {
+		if err := rejected[tx.TXID]; err != nil {
+			log.Printf("dropping transaction %s from the mempool: %v", tx.TXID, err)
+			dropped = append(dropped, tx)
+		}
+	}
+	bc.dropFromMempoolLocked(func(tx Transaction) bool {
+		return rejected[tx.TXID] != nil
+	})
+
+	return dropped
+}
+
+// ReinjectMempool submits txs to the mempool in order, as if each had just been received, e.g. the pending
+// transactions of another node or ones saved before a restart. Each passes every check of a new transaction
+// (see submitTransaction) against the current chain state, so transactions which became invalid, e.g. by now
+// overdrawing their sender or being confirmed meanwhile, are dropped and logged instead. Returns the number
+// of transactions added to the mempool and of dropped ones
+func (bc *Blockchain) ReinjectMempool(txs []Transaction) (kept, dropped int) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	return bc.reinjectMempoolLocked(txs)
+}
+
+// reinjectMempoolLocked is ReinjectMempool for callers already holding the write lock. A transaction which
+// still has a receive time, because it was pending when the mempool was revalidated, keeps it
+func (bc *Blockchain) reinjectMempoolLocked(txs []Transaction) (kept, dropped int) {
+	for _, tx := range txs {
+		received, hasReceived := bc.received[tx.TXID]
+		err := validateTransactionFields(tx)
+		if err == nil {
+			var txid string
+			if txid, err = bc.submitTransactionLocked(tx.clone()); err == nil && hasReceived {
+				bc.received[txid] = received
+			}
+		}
+		if err != nil {
+			log.Printf("dropping transaction %s from the mempool: %v", tx.TXID, err)
+			dropped++
+			continue
+		}
+		kept++
+	}
+
+	return kept, dropped
+}
+
+// revalidateMempoolLocked empties the mempool and reinjects its transactions in arrival order, dropping
+// those which are no longer valid against the chain, as needed once a mempool was loaded along with a chain.
+// The kept transactions keep their receive times
+func (bc *Blockchain) revalidateMempoolLocked() {
+	pending := bc.Transactions
+	bc.Transactions = []Transaction{}
+	bc.rebuildNoncesLocked()
+
+	bc.reinjectMempoolLocked(pending)
+	bc.syncReceivedLocked()
+}
+
+// syncReceivedLocked records the current time as the receive time of mempool transactions without one
+// and forgets the receive times of transactions which are no longer pending
+func (bc *Blockchain) syncReceivedLocked() {
+	now := time.Now()
+	received := make(map[string]time.Time, len(bc.Transactions))
+	for _, tx := range bc.Transactions {
+		if t, ok := bc.received[tx.TXID]; ok {
+			received[tx.TXID] = t
+		} else {
+			received[tx.TXID] = now
+		}
+	}
+
+	bc.received = received
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMiningDropsTransactionsInvalidatedAfterSubmission(t *testing.T) {
+	tests := []struct {
+		name       string
+		invalidate func(t *testing.T, bc *Blockchain, tx Transaction)
+	}{
+		{"signatures required", func(t *testing.T, bc *Blockchain, tx Transaction) {
+			bc.RequireSignatures = true
+		}},
+		{"confirmed by a peer block", func(t *testing.T, bc *Blockchain, tx Transaction) {
+			block := sealTestBlock(t, bc, []Transaction{testCoinbase(bc, "other", bc.BlockReward), tx})
+			bc.mu.Lock()
+			defer bc.mu.Unlock()
+			// appended as is, so the mempool keeps its copy of the transaction
+			bc.Chain = append(bc.Chain, block)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := newTestBlockchain(t, map[string]int64{"alice": 100})
+			txid, err := bc.submitTransaction(Transaction{Sender: "alice", Recipient: "bob", Amount: 10})
+			if err != nil {
+				t.Fatalf("submitTransaction(): %v", err)
+			}
+			tt.invalidate(t, bc, bc.Mempool()[0])
+
+			block := mineTestBlock(t, bc, "miner")
+			for _, tx := range block.Transactions {
+				if tx.TXID == txid {
+					t.Fatalf("mined block contains the invalidated transaction %s", txid)
+				}
+			}
+			if mempool := bc.Mempool(); len(mempool) != 0 {
+				t.Errorf("mempool = %v, want the invalidated transaction dropped", mempool)
+			}
+		})
+	}
+}
+
+func TestMaxTxPerBlockLeavesRestInMempool(t *testing.T) {
+	for _, limit := range []int{4, 6} {
+		t.Run(fmt.Sprintf("limit %d", limit), func(t *testing.T) {
+			bc := newTestBlockchain(t, map[string]int64{"alice": 1000})
+			bc.MaxTxPerBlock = limit
+			for i := range 10 {
+				if _, err := bc.addTransaction("alice", "bob", int64(i+1)); err != nil {
+					t.Fatalf("addTransaction(): %v", err)
+				}
+			}
+
+			block := mineTestBlock(t, bc, "miner")
+			if got := len(block.Transactions) - 1; got != limit {
+				t.Errorf("block includes %d mempool transactions, want %d", got, limit)
+			}
+			if got := bc.PendingCount(); got != 10-limit {
+				t.Errorf("PendingCount() = %d, want %d", got, 10-limit)
+			}
+
+			for bc.PendingCount() > 0 {
+				mineTestBlock(t, bc, "miner")
+			}
+			if got := bc.GetBalance("bob"); got != 55 {
+				t.Errorf("GetBalance(bob) = %d once the mempool is mined, want 55", got)
+			}
+		})
+	}
+}
+
+func TestMiningPrefersHighestFees(t *testing.T) {
+	bc := newTestBlockchain(t, map[string]int64{"alice": 1000, "bob": 1000, "carol": 1000})
+	bc.MaxTxPerBlock = 2
+	fees := map[string]int64{"alice": 1, "bob": 5, "carol": 3}
+	for sender, fee := range fees {
+		if _, err := bc.submitTransaction(Transaction{Sender: sender, Recipient: "dave", Amount: 10, Fee: fee}); err != nil {
+			t.Fatalf("submitTransaction(): %v", err)
+		}
+	}
+
+	block := mineTestBlock(t, bc, "miner")
+	if len(block.Transactions) != 3 || block.Transactions[1].Fee != 5 || block.Transactions[2].Fee != 3 {
+		t.Fatalf("block includes %+v, want the transactions paying fees 5 and 3 in that order", block.Transactions[1:])
+	}
+	if coinbase := block.Transactions[0]; coinbase.Amount != bc.BlockReward+8 {
+		t.Errorf("coinbase pays %d, want the reward %d plus the fees of 8", coinbase.Amount, bc.BlockReward)
+	}
+	if pending := bc.Mempool(); len(pending) != 1 || pending[0].Sender != "alice" {
+		t.Errorf("mempool = %+v, want only the cheapest transaction of alice", pending)
+	}
+}
+
+func TestMempoolEvictsCheapestWhenFull(t *testing.T) {
+	bc := newTestBlockchain(t, map[string]int64{"alice": 1000, "bob": 1000, "carol": 1000, "dave": 1000})
+	bc.MaxMempoolSize = 2
+
+	submit := func(sender string, fee int64) error {
+		_, err := bc.submitTransaction(Transaction{Sender: sender, Recipient: "erin", Amount: 10, Fee: fee})
+		return err
+	}
+	if err := submit("alice", 1); err != nil {
+		t.Fatalf("submitTransaction(): %v", err)
+	}
+	if err := submit("bob", 3); err != nil {
+		t.Fatalf("submitTransaction(): %v", err)
+	}
+
+	tests := []struct {
+		sender      string
+		fee         int64
+		wantErr     error
+		wantSenders []string
+	}{
+		{"carol", 2, nil, []string{"bob", "carol"}},           // evicts alice paying 1
+		{"dave", 2, ErrMempoolFull, []string{"bob", "carol"}}, // does not pay more than the cheapest
+		{"dave", 5, nil, []string{"bob", "dave"}},             // evicts carol paying 2
+		{"bob", 9, nil, []string{"bob", "bob"}},               // evicts dave paying 5
+		{"bob", 20, ErrMempoolFull, []string{"bob", "bob"}},   // never evicts its own sender's transactions
+	}
+
+	for _, tt := range tests {
+		err := submit(tt.sender, tt.fee)
+		if !errors.Is(err, tt.wantErr) {
+			t.Fatalf("submitTransaction(%s, fee %d) error = %v, want %v", tt.sender, tt.fee, err, tt.wantErr)
+		}
+		var senders []string
+		for _, tx := range bc.Mempool() {
+			senders = append(senders, tx.Sender)
+		}
+		slices.Sort(senders)
+		if !slices.Equal(senders, tt.wantSenders) {
+			t.Errorf("after %s paying %d the mempool holds transactions of %v, want %v", tt.sender, tt.fee, senders, tt.wantSenders)
+		}
+	}
+}
+
+func TestPruneMempoolByAge(t *testing.T) {
+	bc := newTestBlockchain(t, map[string]int64{"alice": 1000, "bob": 1000})
+	old, err := bc.addTransaction("alice", "carol", 1)
+	if err != nil {
+		t.Fatalf("addTransaction(): %v", err)
+	}
+	if _, err := bc.addTransaction("alice", "carol", 2); err != nil {
+		t.Fatalf("addTransaction(): %v", err)
+	}
+	fresh, err := bc.addTransaction("bob", "carol", 3)
+	if err != nil {
+		t.Fatalf("addTransaction(): %v", err)
+	}
+	bc.mu.Lock()
+	bc.received[old] = time.Now().Add(-2 * time.Hour)
+	bc.mu.Unlock()
+
+	// the second transaction of alice goes too, since its nonce follows the pruned one
+	if dropped := bc.PruneMempool(time.Hour); dropped != 2 {
+		t.Errorf("PruneMempool() = %d, want 2", dropped)
+	}
+	if pending := bc.Mempool(); len(pending) != 1 || pending[0].TXID != fresh {
+		t.Errorf("mempool = %+v, want only the fresh transaction of bob", pending)
+	}
+	if _, err := bc.addTransaction("alice", "carol", 1); err != nil {
+		t.Errorf("addTransaction() after pruning the pending nonces of alice: %v", err)
+	}
+}
+
+func TestReplaceTransaction(t *testing.T) {
+	tests := []struct {
+		name    string
+		utxo    bool
+		newFee  int64
+		txid    func(submitted string) string
+		wantErr error
+	}{
+		{"higher fee", false, 5, nil, nil},
+		{"higher fee under the UTXO model", true, 5, nil, nil},
+		{"equal fee", false, 2, nil, ErrFeeTooLow},
+		{"lower fee", false, 1, nil, ErrFeeTooLow},
+		{"fee beyond the balance", false, 91, nil, ErrInsufficientFunds},
+		{"unknown transaction", false, 5, func(string) string { return "unknown" }, ErrTransactionNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := newTestBlockchain(t, map[string]int64{"alice": 100})
+			if tt.utxo {
+				bc.EnableUTXO()
+			}
+			submitted, err := bc.submitTransaction(Transaction{Sender: "alice", Recipient: "bob", Amount: 10, Fee: 2})
+			if err != nil {
+				t.Fatalf("submitTransaction(): %v", err)
+			}
+			old := bc.Mempool()[0]
+			bc.mu.Lock()
+			received := bc.received[submitted]
+			bc.mu.Unlock()
+			txid := submitted
+			if tt.txid != nil {
+				txid = tt.txid(submitted)
+			}
+
+			replaced, err := bc.ReplaceTransaction(txid, tt.newFee)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReplaceTransaction() error = %v, want %v", err, tt.wantErr)
+			}
+			pending := bc.Mempool()
+			if tt.wantErr != nil {
+				if len(pending) != 1 || pending[0].TXID != submitted {
+					t.Errorf("mempool = %+v, want the original transaction untouched", pending)
+				}
+				return
+			}
+
+			if len(pending) != 1 || pending[0].TXID != replaced || replaced == submitted {
+				t.Fatalf("mempool = %+v, want only the replacement %s", pending, replaced)
+			}
+			tx := pending[0]
+			if tx.Fee != tt.newFee || tx.Nonce != old.Nonce || tx.Amount != old.Amount {
+				t.Errorf("replacement pays %d with nonce %d and amount %d, want %d, %d and %d", tx.Fee, tx.Nonce, tx.Amount, tt.newFee, old.Nonce, old.Amount)
+			}
+			if tx.TXID != generateTransactionID(bc.hasher(), tx) {
+				t.Error("replacement TXID does not match its contents")
+			}
+			bc.mu.Lock()
+			_, oldReceived := bc.received[submitted]
+			newReceived := bc.received[replaced]
+			bc.mu.Unlock()
+			if oldReceived || !newReceived.Equal(received) {
+				t.Errorf("receive time of the replacement is %v, want the original %v", newReceived, received)
+			}
+
+			mineTestBlock(t, bc, "miner")
+			if balance := bc.GetBalance("alice"); balance != 100-10-tt.newFee {
+				t.Errorf("alice has %d units after mining, want %d", balance, 100-10-tt.newFee)
+			}
+			if valid, err := bc.IsChainValid(); !valid {
+				t.Errorf("IsChainValid() = false: %v", err)
+			}
+		})
+	}
+}
+
+func TestReplaceSignedTransactionIsRejected(t *testing.T) {
+	tx, _ := signedTestTransaction(t, 10)
+	bc := newTestBlockchain(t, map[string]int64{tx.Sender: 100})
+	txid, err := bc.submitTransaction(tx)
+	if err != nil {
+		t.Fatalf("submitTransaction(): %v", err)
+	}
+
+	if _, err := bc.ReplaceTransaction(txid, tx.Fee+1); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("ReplaceTransaction() error = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestReinjectMempool(t *testing.T) {
+	balances := map[string]int64{"alice": 100, "bob": 100, "carol": 100}
+	saved := newTestBlockchain(t, balances)
+	confirmed, err := saved.addTransaction("bob", "alice", 50)
+	if err != nil {
+		t.Fatalf("addTransaction(): %v", err)
+	}
+	mineTestBlock(t, saved, "miner")
+	// alice can only afford 120 with the 50 units bob paid her in block 1
+	overdraw, err := saved.addTransaction("alice", "erin", 120)
+	if err != nil {
+		t.Fatalf("addTransaction(): %v", err)
+	}
+	valid, err := saved.addTransaction("carol", "dave", 10)
+	if err != nil {
+		t.Fatalf("addTransaction(): %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "chain.json")
+	if err := saved.SaveToFile(path); err != nil {
+		t.Fatalf("SaveToFile(): %v", err)
+	}
+	reloaded, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile(): %v", err)
+	}
+	pending := reloaded.Mempool()
+	if len(pending) != 2 {
+		t.Fatalf("reloaded mempool holds %d transactions, want both saved ones", len(pending))
+	}
+
+	t.Run("onto a chain without block 1", func(t *testing.T) {
+		other := newTestBlockchain(t, balances)
+		mineTestBlock(t, other, "other")
+
+		if kept, dropped := other.ReinjectMempool(pending); kept != 1 || dropped != 1 {
+			t.Fatalf("ReinjectMempool() = %d kept, %d dropped, want 1 and 1", kept, dropped)
+		}
+		if got := other.Mempool(); len(got) != 1 || got[0].TXID != valid {
+			t.Fatalf("mempool = %+v, want only %s, the overdraw %s dropped", got, valid, overdraw)
+		}
+		mineTestBlock(t, other, "other")
+		if balance := other.GetBalance("dave"); balance != 10 {
+			t.Errorf("dave has %d units, want 10", balance)
+		}
+	})
+
+	t.Run("confirmed and pending again", func(t *testing.T) {
+		block, err := reloaded.GetBlockByIndex(1)
+		if err != nil {
+			t.Fatalf("GetBlockByIndex(): %v", err)
+		}
+		var again []Transaction
+		for _, tx := range block.Transactions {
+			if tx.TXID == confirmed {
+				again = append(again, tx)
+			}
+		}
+		again = append(again, pending...)
+
+		if kept, dropped := reloaded.ReinjectMempool(again); kept != 0 || dropped != 3 {
+			t.Errorf("ReinjectMempool() = %d kept, %d dropped, want 0 and 3", kept, dropped)
+		}
+		if got := reloaded.Mempool(); !slices.EqualFunc(got, pending, func(a, b Transaction) bool { return a.TXID == b.TXID }) {
+			t.Errorf("mempool = %+v, want the reloaded transactions unchanged", got)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+)
+
+// computeMerkleRoot builds a binary Merkle tree hashed with h over the TXIDs of the transactions and returns its root.
+// Each parent node is the hash of its two children (see hashPair); when a level has an odd number of nodes
+// the last one is duplicated. A single transaction's TXID is its own root, and an empty transaction list
+// yields the hash of empty input
+func computeMerkleRoot(h Hasher, txs []Transaction) string {
+	if len(txs) == 0 {
+		return h.Hash(nil)
+	}
+
+	level := make([]string, len(txs))
+	for i, tx := range txs {
+		level[i] = tx.TXID
+	}
+
+	for len(level) > 1 {
+		if len(level)%2 != 0 {
+			level = append(level, level[len(level)-1])
+		}
+
+		next := make([]string, 0, len(level)/2)
+		for i := 0; i < len(level); i += 2 {
+			next = append(next, hashPair(h, level[i], level[i+1]))
+		}
+		level = next
+	}
+
+	return level[0]
+}
+
+// hashPair returns the hash of two concatenated Merkle tree nodes computed with h.
+// The nodes are sorted before concatenation, so a proof only needs the sibling hashes
+// and not whether each sibling is the left or the right child
+func hashPair(h Hasher, left, right string) string {
+	if right < left {
+		left, right = right, left
+	}
+	return h.Hash([]byte(left + right))
+}
+
+// MerkleProof returns the sibling hashes along the path from the transaction with the given TXID
+// to the Merkle root of the block at blockIndex, ordered from the leaf level upwards.
+// Returns ErrBlockNotFound when the block does not exist and ErrTransactionNotFound when it does not contain the transaction
+func (bc *Blockchain) MerkleProof(blockIndex int, txid string) ([]string, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if blockIndex < 0 || blockIndex >= len(bc.Chain) {
+		return nil, fmt.Errorf("%w: index %d", ErrBlockNotFound, blockIndex)
+	}
+	block := bc.Chain[blockIndex]
+
+	position := -1
+	level := make([]string, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		level[i] = tx.TXID
+		if tx.TXID == txid && position == -1 {
+			position = i
+		}
+	}
+	if position == -1 {
+		return nil, fmt.Errorf("%w: %s in block %d", ErrTransactionNotFound, txid, blockIndex)
+	}
+
+	proof := []string{}
+	for len(level) > 1 {
+		if len(level)%2 != 0 {
+			level = append(level, level[len(level)-1])
+		}
+
+		proof = append(proof, level[position^1]) // sibling is the other node of the pair
+
+		next := make([]string, 0, len(level)/2)
+		for i := 0; i < len(level); i += 2 {
+			next = append(next, hashPair(bc.hasher(), level[i], level[i+1]))
+		}
+		level = next
+		position /= 2
+	}
+
+	return proof, nil
+}
+
+// VerifyMerkleProof recomputes the Merkle root from a TXID and the sibling hashes returned by MerkleProof
+// and reports whether it matches the given root. It assumes the chain uses the default SHA256Hasher
+func VerifyMerkleProof(txid string, proof []string, root string) bool {
+	return verifyMerkleProof(SHA256Hasher{}, txid, proof, root)
+}
+
+// verifyMerkleProof is VerifyMerkleProof for a chain using the hasher h
+func verifyMerkleProof(h Hasher, txid string, proof []string, root string) bool {
+	hash := txid
+	for _, sibling := range proof {
+		hash = hashPair(h, hash, sibling)
+	}
+
+	return hash == root
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// sha256Hex returns the hex encoded SHA-256 digest of s
+func sha256Hex(s string) string {
+	hash := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(hash[:])
+}
+
+// merklePair hashes two nodes in the sorted order hashPair uses
+func merklePair(left, right string) string {
+	if right < left {
+		left, right = right, left
+	}
+	return sha256Hex(left + right)
+}
+
+func TestComputeMerkleRoot(t *testing.T) {
+	a, b, c := sha256Hex("a"), sha256Hex("b"), sha256Hex("c")
+
+	tests := []struct {
+		name  string
+		txids []string
+		want  string
+	}{
+		{"no transactions", nil, sha256Hex("")},
+		{"one transaction", []string{a}, a},
+		{"two transactions", []string{a, b}, merklePair(a, b)},
+		{"three transactions duplicate the last", []string{a, b, c}, merklePair(merklePair(a, b), merklePair(c, c))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txs := make([]Transaction, len(tt.txids))
+			for i, txid := range tt.txids {
+				txs[i].TXID = txid
+			}
+			if got := computeMerkleRoot(SHA256Hasher{}, txs); got != tt.want {
+				t.Errorf("computeMerkleRoot() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerkleRootCommitsToTransactions(t *testing.T) {
+	bc := newTestBlockchain(t, map[string]int64{"alice": 1000})
+	if _, err := bc.addTransaction("alice", "bob", 10); err != nil {
+		t.Fatalf("addTransaction(): %v", err)
+	}
+	block := mineTestBlock(t, bc, "miner")
+	if block.MerkleRoot != computeMerkleRoot(bc.hasher(), block.Transactions) {
+		t.Fatalf("mined block has Merkle root %s, want the root of its transactions", block.MerkleRoot)
+	}
+
+	bc.Chain[1].Transactions[1].Amount = 11
+	bc.Chain[1].Transactions[1].TXID = generateTransactionID(bc.hasher(), bc.Chain[1].Transactions[1])
+	if valid, _ := bc.IsChainValid(); valid {
+		t.Errorf("IsChainValid() = true after a transaction changed under the Merkle root")
+	}
+}
+
+func TestMerkleProof(t *testing.T) {
+	bc := newTestBlockchain(t, map[string]int64{"alice": 1000})
+	for i := range 4 {
+		if _, err := bc.addTransaction("alice", "bob", int64(10+i)); err != nil {
+			t.Fatalf("addTransaction(): %v", err)
+		}
+	}
+	block := mineTestBlock(t, bc, "miner")
+
+	for _, tx := range block.Transactions {
+		proof, err := bc.MerkleProof(block.Index, tx.TXID)
+		if err != nil {
+			t.Fatalf("MerkleProof(%s): %v", tx.TXID, err)
+		}
+		if !VerifyMerkleProof(tx.TXID, proof, block.MerkleRoot) {
+			t.Errorf("VerifyMerkleProof() = false for transaction %s", tx.TXID)
+		}
+
+		tampered := append([]string{}, proof...)
+		tampered[len(tampered)-1] = sha256Hex("tampered")
+		if VerifyMerkleProof(tx.TXID, tampered, block.MerkleRoot) {
+			t.Errorf("VerifyMerkleProof() = true for a tampered proof of %s", tx.TXID)
+		}
+		if VerifyMerkleProof(sha256Hex("other"), proof, block.MerkleRoot) {
+			t.Errorf("VerifyMerkleProof() = true for another TXID with the proof of %s", tx.TXID)
+		}
+	}
+
+	if _, err := bc.MerkleProof(block.Index, sha256Hex("missing")); !errors.Is(err, ErrTransactionNotFound) {
+		t.Errorf("MerkleProof() of a missing transaction error = %v, want ErrTransactionNotFound", err)
+	}
+	if _, err := bc.MerkleProof(block.Index+1, block.Transactions[0].TXID); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("MerkleProof() of a missing block error = %v, want ErrBlockNotFound", err)
+	}
+}
@@ -0,0 +1,77 @@
+// Package metrics instruments a blockchain node with Prometheus metrics and serves them with promhttp:
+// a counter of mined blocks and a histogram of mining durations, which the node reports with ObserveMined,
+// and gauges of the mempool size and the difficulty, which are read from the node on every scrape.
+// The node only uses the Node type, so the Prometheus client stays out of its core
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MiningDurationBuckets are the upper bounds in seconds of the mining duration histogram
+var MiningDurationBuckets = []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300}
+
+// Source is the part of the node the gauges are read from; its methods must be safe for concurrent use
+type Source interface {
+	// PendingCount returns the number of transactions in the mempool
+	PendingCount() int
+	// CurrentDifficulty returns the difficulty required for the next block
+	CurrentDifficulty() int
+}
+
+// Node holds the metrics of a node in a registry of its own, so several nodes in one process do not collide;
+// it is safe for concurrent use
+type Node struct {
+	registry       *prometheus.Registry
+	blocksMined    prometheus.Counter
+	miningDuration prometheus.Histogram
+}
+
+// NewNode creates the metrics of the node src, with the mempool and difficulty gauges reading from src
+func NewNode(src Source) *Node {
+	n := &Node{
+		registry: prometheus.NewRegistry(),
+		blocksMined: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "blockchain_blocks_mined_total",
+			Help: "Number of blocks mined by this node.",
+		}),
+		miningDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "blockchain_mining_duration_seconds",
+			Help:    "Time spent mining a block.",
+			Buckets: MiningDurationBuckets,
+		}),
+	}
+	n.registry.MustRegister(
+		n.blocksMined,
+		n.miningDuration,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "blockchain_mempool_transactions",
+			Help: "Number of transactions in the mempool.",
+		}, func() float64 {
+			return float64(src.PendingCount())
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "blockchain_difficulty_bits",
+			Help: "Leading zero bits required in the hash of the next block.",
+		}, func() float64 {
+			return float64(src.CurrentDifficulty())
+		}),
+	)
+
+	return n
+}
+
+// ObserveMined records a successfully mined block and the time it took to mine
+func (n *Node) ObserveMined(duration time.Duration) {
+	n.blocksMined.Inc()
+	n.miningDuration.Observe(duration.Seconds())
+}
+
+// Handler returns an HTTP handler serving the metrics of the node to a Prometheus scraper
+func (n *Node) Handler() http.Handler {
+	return promhttp.HandlerFor(n.registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testSource is a Source whose values are set by the test
+type testSource struct {
+	pending, difficulty atomic.Int64
+}
+
+func (s *testSource) PendingCount() int      { return int(s.pending.Load()) }
+func (s *testSource) CurrentDifficulty() int { return int(s.difficulty.Load()) }
+
+// scrape returns the body served by the handler of n
+func scrape(t *testing.T, n *Node) string {
+	t.Helper()
+	server := httptest.NewServer(n.Handler())
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET = %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	return string(body)
+}
+
+func TestNodeMetrics(t *testing.T) {
+	src := &testSource{}
+	src.pending.Store(3)
+	src.difficulty.Store(16)
+	n := NewNode(src)
+	n.ObserveMined(200 * time.Millisecond)
+	n.ObserveMined(2 * time.Second)
+
+	body := scrape(t, n)
+	for _, want := range []string{
+		"# TYPE blockchain_blocks_mined_total counter\nblockchain_blocks_mined_total 2\n",
+		"# TYPE blockchain_mining_duration_seconds histogram\n",
+		`blockchain_mining_duration_seconds_bucket{le="0.1"} 0` + "\n",
+		`blockchain_mining_duration_seconds_bucket{le="0.5"} 1` + "\n",
+		`blockchain_mining_duration_seconds_bucket{le="5"} 2` + "\n",
+		`blockchain_mining_duration_seconds_bucket{le="+Inf"} 2` + "\n",
+		"blockchain_mining_duration_seconds_sum 2.2\n",
+		"blockchain_mining_duration_seconds_count 2\n",
+		"# TYPE blockchain_mempool_transactions gauge\nblockchain_mempool_transactions 3\n",
+		"# TYPE blockchain_difficulty_bits gauge\nblockchain_difficulty_bits 16\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics are missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestGaugesAreReadOnEveryScrape(t *testing.T) {
+	src := &testSource{}
+	n := NewNode(src)
+
+	for _, pending := range []int64{0, 5, 1} {
+		src.pending.Store(pending)
+		want := "blockchain_mempool_transactions " + strconv.FormatInt(pending, 10) + "\n"
+		if body := scrape(t, n); !strings.Contains(body, want) {
+			t.Errorf("metrics are missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestNodesHaveSeparateRegistries(t *testing.T) {
+	a, b := NewNode(&testSource{}), NewNode(&testSource{})
+	a.ObserveMined(time.Second)
+
+	if body := scrape(t, b); !strings.Contains(body, "blockchain_blocks_mined_total 0\n") {
+		t.Errorf("a block mined by another node was counted:\n%s", body)
+	}
+}
@@ -0,0 +1,138 @@
+// Package nodegrpc serves the Node service of proto/node.proto over gRPC and provides a client for it.
+// The server works on the generated nodepb messages and delegates to a Backend, so the node converting its
+// own structs to those messages is the only part which knows both, and the core types stay free of gRPC.
+package nodegrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"blockchain/proto/nodepb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// ErrInvalidRange is returned by a Backend for a GetBlocks request outside the chain, which the server
+// reports as codes.OutOfRange
+var ErrInvalidRange = errors.New("invalid block range")
+
+// Backend is the node behind the server; every method must be safe for concurrent use
+type Backend interface {
+	// Blocks returns the blocks from start up to the tip, at most limit of them unless limit is 0
+	Blocks(start, limit int64) ([]*nodepb.Block, error)
+	// SubmitTransaction adds a transaction to the mempool and returns its TXID
+	SubmitTransaction(tx *nodepb.Transaction) (string, error)
+	// Tip returns the latest block and its height
+	Tip() (*nodepb.Block, int64, error)
+}
+
+// server implements nodepb.NodeServer on top of a Backend
+type server struct {
+	nodepb.UnimplementedNodeServer
+	backend Backend
+}
+
+// NewServer returns a gRPC server with the Node service registered for backend, ready to Serve a listener
+func NewServer(backend Backend, opts ...grpc.ServerOption) *grpc.Server {
+	s := grpc.NewServer(opts...)
+	nodepb.RegisterNodeServer(s, &server{backend: backend})
+	return s
+}
+
+// GetBlocks implements nodepb.NodeServer. A negative start or limit is rejected with codes.InvalidArgument
+func (s *server) GetBlocks(_ context.Context, req *nodepb.GetBlocksRequest) (*nodepb.GetBlocksResponse, error) {
+	if req.GetStartIndex() < 0 || req.GetLimit() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "start index %d and limit %d must not be negative", req.GetStartIndex(), req.GetLimit())
+	}
+
+	blocks, err := s.backend.Blocks(req.GetStartIndex(), req.GetLimit())
+	if errors.Is(err, ErrInvalidRange) {
+		return nil, status.Error(codes.OutOfRange, err.Error())
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &nodepb.GetBlocksResponse{Blocks: blocks}, nil
+}
+
+// SubmitTransaction implements nodepb.NodeServer. Like POST /transactions, a transaction the node rejects
+// is reported as codes.InvalidArgument with the reason
+func (s *server) SubmitTransaction(_ context.Context, req *nodepb.SubmitTransactionRequest) (*nodepb.SubmitTransactionResponse, error) {
+	if req.GetTransaction() == nil {
+		return nil, status.Error(codes.InvalidArgument, "transaction is missing")
+	}
+
+	txid, err := s.backend.SubmitTransaction(req.GetTransaction())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &nodepb.SubmitTransactionResponse{Txid: txid}, nil
+}
+
+// GetTip implements nodepb.NodeServer
+func (s *server) GetTip(context.Context, *nodepb.GetTipRequest) (*nodepb.GetTipResponse, error) {
+	block, height, err := s.backend.Tip()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &nodepb.GetTipResponse{Block: block, Height: height}, nil
+}
+
+// Client calls the Node service of a remote node
+type Client struct {
+	conn *grpc.ClientConn
+	node nodepb.NodeClient
+}
+
+// Dial returns a client for the node at target, e.g. "localhost:9090". Without options the connection is
+// unencrypted, like the HTTP API and the peer protocol; the connection is established lazily by the first call
+func Dial(target string, opts ...grpc.DialOption) (*Client, error) {
+	if len(opts) == 0 {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+
+	conn, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", target, err)
+	}
+
+	return &Client{conn: conn, node: nodepb.NewNodeClient(conn)}, nil
+}
+
+// Close closes the connection of the client
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// GetBlocks returns the blocks of the remote chain from start up to its tip, at most limit of them unless limit is 0
+func (c *Client) GetBlocks(ctx context.Context, start, limit int64) ([]*nodepb.Block, error) {
+	resp, err := c.node.GetBlocks(ctx, &nodepb.GetBlocksRequest{StartIndex: start, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetBlocks(), nil
+}
+
+// SubmitTransaction adds a transaction to the mempool of the remote node and returns its TXID
+func (c *Client) SubmitTransaction(ctx context.Context, tx *nodepb.Transaction) (string, error) {
+	resp, err := c.node.SubmitTransaction(ctx, &nodepb.SubmitTransactionRequest{Transaction: tx})
+	if err != nil {
+		return "", err
+	}
+	return resp.GetTxid(), nil
+}
+
+// GetTip returns the latest block of the remote chain and its height
+func (c *Client) GetTip(ctx context.Context) (*nodepb.Block, int64, error) {
+	resp, err := c.node.GetTip(ctx, &nodepb.GetTipRequest{})
+	if err != nil {
+		return nil, 0, err
+	}
+	return resp.GetBlock(), resp.GetHeight(), nil
+}
@@ -0,0 +1,64 @@
+package nodegrpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"blockchain/proto/nodepb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// failingBackend rejects every request with err
+type failingBackend struct {
+	err error
+}
+
+func (b failingBackend) Blocks(start, limit int64) ([]*nodepb.Block, error)    { return nil, b.err }
+func (b failingBackend) SubmitTransaction(*nodepb.Transaction) (string, error) { return "", b.err }
+func (b failingBackend) Tip() (*nodepb.Block, int64, error)                    { return nil, 0, b.err }
+
+func TestServerErrorCodes(t *testing.T) {
+	ln := bufconn.Listen(1 << 20)
+	server := NewServer(failingBackend{err: errors.New("rejected")})
+	go server.Serve(ln)
+	defer server.Stop()
+
+	client, err := Dial("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Dial(): %v", err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+		want codes.Code
+	}{
+		{"GetBlocks with negative limit", func() error { _, err := client.GetBlocks(ctx, 0, -1); return err }, codes.InvalidArgument},
+		{"GetBlocks failing", func() error { _, err := client.GetBlocks(ctx, 0, 0); return err }, codes.Internal},
+		{"SubmitTransaction without transaction", func() error { _, err := client.SubmitTransaction(ctx, nil); return err }, codes.InvalidArgument},
+		{"SubmitTransaction rejected", func() error {
+			_, err := client.SubmitTransaction(ctx, &nodepb.Transaction{Sender: "alice"})
+			return err
+		}, codes.InvalidArgument},
+		{"GetTip failing", func() error { _, _, err := client.GetTip(ctx); return err }, codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); status.Code(err) != tt.want {
+				t.Errorf("error = %v, want code %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// maxOrphanBlocks bounds the number of orphan blocks held by AddBlockFromNetwork; the oldest one is evicted
+// to make room, so a peer cannot make the node hold an arbitrary number of blocks which never connect
+const maxOrphanBlocks = 100
+
+// orphan is a block whose parent is not part of the chain yet, with the time it was received
+type orphan struct {
+	block    Block
+	received time.Time
+}
+
+// AddBlockFromNetwork adds a block received from another node, which may arrive before its parent during a sync.
+// A block extending the tip is appended like by AppendBlock. A block whose parent is unknown is held in the orphan
+// pool instead, once its hash checks out, and connected as soon as its parent is appended; appending a block
+// connects every pooled descendant which then extends the tip, one generation after the other. Orphans failing
+// validation when they are connected are dropped and logged. Blocks already in the chain or the pool are a no-op.
+// Returns ErrInvalidBlock for a block which cannot extend the chain, e.g. one competing with a block below the tip,
+// and an error wrapping ErrInvalidChain for a block extending the tip which fails validation
+func (bc *Blockchain) AddBlockFromNetwork(block Block) error {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if _, pooled := bc.orphans[block.Hash]; pooled || bc.hasBlockLocked(block.Hash) {
+		return nil
+	}
+
+	if !bc.hasBlockLocked(block.PreviousHash) {
+		return bc.addOrphanLocked(block)
+	}
+	if _, err := bc.appendBlockLocked(block); err != nil {
+		return err
+	}
+
+	bc.connectOrphansLocked(block.Hash)
+	return nil
+}
+
+// OrphanCount returns the number of blocks waiting in the orphan pool for their parent
+func (bc *Blockchain) OrphanCount() int {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return len(bc.orphans)
+}
+
+// addOrphanLocked holds a block whose parent is not in the chain, evicting the oldest orphan if the pool is full.
+// Only the hash of the block is checked, the rest is validated once it connects. A block which cannot be
+// above the tip is rejected with ErrInvalidBlock, as its parent would have to be part of the chain already
+func (bc *Blockchain) addOrphanLocked(block Block) error {
+	tip := bc.Chain[len(bc.Chain)-1]
+	if block.Index <= tip.Index+1 {
+		return fmt.Errorf("%w: block %d with unknown previous hash %s cannot extend tip %d", ErrInvalidBlock,
+			block.Index, shortHash(block.PreviousHash), tip.Index)
+	}
+	if calculateHash(bc.hasher(), block.BlockHeader) != block.Hash {
+		return fmt.Errorf("%w: block %d: stored hash does not match calculated hash", ErrInvalidBlock, block.Index)
+	}
+
+	if len(bc.orphans) >= maxOrphanBlocks {
+		oldest := ""
+		for hash, o := range bc.orphans {
+			if oldest == "" || o.received.Before(bc.orphans[oldest].received) {
+				oldest = hash
+			}
+		}
+		delete(bc.orphans, oldest)
+	}
+	if bc.orphans == nil {
+		bc.orphans = map[string]orphan{}
+	}
+	bc.orphans[block.Hash] = orphan{block: block.clone(), received: time.Now()}
+
+	return nil
+}
+
+// connectOrphansLocked appends the pooled children of the block with hash parent, then their children, until no
+// orphan extends the tip. The first child which passes validation is appended; its siblings no longer extend
+// the tip and are dropped along with children which fail validation
+func (bc *Blockchain) connectOrphansLocked(parent string) {
+	for parent != "" {
+		next := ""
+		for hash, o := range bc.orphans {
+			if o.block.PreviousHash != parent {
+				continue
+			}
+			delete(bc.orphans, hash)
+			if next != "" {
+				continue
+			}
+			if _, err := bc.appendBlockLocked(o.block); err != nil {
+				log.Printf("dropping orphan block %d: %v", o.block.Index, err)
+				continue
+			}
+			next = hash
+		}
+		parent = next
+	}
+}
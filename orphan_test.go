@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestAddBlockFromNetworkInReverseOrder(t *testing.T) {
+	balances := map[string]int64{"alice": 100}
+	miner := newTestBlockchain(t, balances)
+	for _, amount := range []int64{1, 2, 3, 4, 5, 6} {
+		if _, err := miner.addTransaction("alice", "bob", amount); err != nil {
+			t.Fatalf("addTransaction(): %v", err)
+		}
+		mineTestBlock(t, miner, "miner")
+	}
+	blocks := miner.GetChain()[1:]
+
+	bc := newTestBlockchain(t, balances)
+	reversed := slices.Clone(blocks)
+	slices.Reverse(reversed)
+	for i, block := range reversed {
+		if err := bc.AddBlockFromNetwork(block); err != nil {
+			t.Fatalf("AddBlockFromNetwork(%d): %v", block.Index, err)
+		}
+		// a resent orphan is a no-op
+		if err := bc.AddBlockFromNetwork(block); err != nil {
+			t.Fatalf("AddBlockFromNetwork(%d) again: %v", block.Index, err)
+		}
+
+		wantOrphans, wantHeight := i+1, 0
+		if block.Index == 1 {
+			wantOrphans, wantHeight = 0, len(blocks)
+		}
+		if got := bc.OrphanCount(); got != wantOrphans {
+			t.Errorf("after block %d: OrphanCount() = %d, want %d", block.Index, got, wantOrphans)
+		}
+		if height, _ := bc.Tip(); height != wantHeight {
+			t.Errorf("after block %d: tip height = %d, want %d", block.Index, height, wantHeight)
+		}
+	}
+
+	if !reflect.DeepEqual(bc.GetChain(), miner.GetChain()) {
+		t.Error("chain built from the reversed blocks differs from the mined chain")
+	}
+	if balance := bc.GetBalance("bob"); balance != 21 {
+		t.Errorf("bob has %d units, want 21", balance)
+	}
+	if valid, err := bc.IsChainValid(); !valid {
+		t.Errorf("IsChainValid() = false: %v", err)
+	}
+}
+
+func TestInvalidOrphansAreDropped(t *testing.T) {
+	miner := newTestBlockchain(t, nil)
+	for range 3 {
+		mineTestBlock(t, miner, "miner")
+	}
+	blocks := miner.GetChain()
+
+	bc := newTestBlockchain(t, nil)
+	// block 3 inflates its coinbase; its hash checks out, so it is pooled until its parent arrives
+	inflated := blocks[3].clone()
+	inflated.Transactions[0].Amount *= 2
+	inflated.Transactions[0].TXID = generateTransactionID(bc.hasher(), inflated.Transactions[0])
+	inflated.MerkleRoot = computeMerkleRoot(bc.hasher(), inflated.Transactions)
+	inflated = resealTestBlock(bc, inflated)
+
+	tampered := blocks[3].clone()
+	tampered.Nonce++
+	if err := bc.AddBlockFromNetwork(tampered); !errors.Is(err, ErrInvalidBlock) {
+		t.Errorf("AddBlockFromNetwork() of an orphan with a wrong hash error = %v, want ErrInvalidBlock", err)
+	}
+	if err := bc.AddBlockFromNetwork(inflated); err != nil {
+		t.Fatalf("AddBlockFromNetwork(3): %v", err)
+	}
+	for _, block := range []Block{blocks[2], blocks[1]} {
+		if err := bc.AddBlockFromNetwork(block); err != nil {
+			t.Fatalf("AddBlockFromNetwork(%d): %v", block.Index, err)
+		}
+	}
+
+	if height, _ := bc.Tip(); height != 2 {
+		t.Errorf("tip height = %d, want 2 with the inflated block 3 dropped", height)
+	}
+	if got := bc.OrphanCount(); got != 0 {
+		t.Errorf("OrphanCount() = %d, want 0", got)
+	}
+	if _, err := bc.GetBlockByIndex(3); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("GetBlockByIndex(3) error = %v, want ErrBlockNotFound", err)
+	}
+}
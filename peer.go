@@ -0,0 +1,315 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// maxPeerMessageSize bounds the length of a single peer message, so a peer cannot make the node
+// allocate an arbitrary amount of memory
+const maxPeerMessageSize = 64 << 20
+
+// peerBroadcastQueue is the number of new blocks waiting to be broadcast; further blocks are dropped
+// until the queue drains, peers then catch up by requesting the chain
+const peerBroadcastQueue = 64
+
+// peerDialTimeout limits how long ConnectPeer waits for the connection to be established
+const peerDialTimeout = 10 * time.Second
+
+// peerHandshakeTimeout limits how long a new connection may take to exchange hello messages
+const peerHandshakeTimeout = 10 * time.Second
+
+// peerWriteTimeout limits how long a single message may take to be written to a peer; a peer which does not
+// read its messages in time is dropped, so it cannot stall the broadcast to every other peer
+const peerWriteTimeout = 10 * time.Second
+
+// Types of the messages exchanged between peers
+const (
+	peerMessageHello    = "hello"     // first message in both directions, carrying the genesis hash of the sender
+	peerMessageBlock    = "block"     // a block newly added to the sender's chain
+	peerMessageGetChain = "get_chain" // request for the full chain of the receiver
+	peerMessageChain    = "chain"     // the full chain of the sender, the answer to get_chain
+)
+
+// peerMessage is a message of the peer protocol. On the wire every message is its JSON encoding
+// preceded by its length as a 4 byte big-endian unsigned integer
+type peerMessage struct {
+	Type    string  `json:"type"`
+	Genesis string  `json:"genesis,omitempty"`
+	Block   *Block  `json:"block,omitempty"`
+	Chain   []Block `json:"chain,omitempty"`
+}
+
+// peer is a connection to another node
+type peer struct {
+	addr string
+	conn net.Conn
+	mu   sync.Mutex // serializes the messages written to conn
+}
+
+// peerSet holds the connected peers of a blockchain and the queue of blocks to broadcast to them
+type peerSet struct {
+	mu        sync.Mutex
+	conns     map[*peer]bool
+	queue     chan Block
+	startOnce sync.Once
+}
+
+// ConnectPeer opens a TCP connection to the node listening on addr (see ServePeers), asks it for its chain
+// to catch up and keeps exchanging blocks with it in the background until either side closes the connection.
+// Blocks added to bc by addBlock or MineBlock are broadcast to every connected peer.
+// Returns ErrGenesisMismatch if the peer's chain starts from a different genesis block
+func (bc *Blockchain) ConnectPeer(addr string) error {
+	conn, err := net.DialTimeout("tcp", addr, peerDialTimeout)
+	if err != nil {
+		return fmt.Errorf("connect to peer %s: %w", addr, err)
+	}
+	if err := bc.handshakePeer(conn, true); err != nil {
+		conn.Close()
+		return fmt.Errorf("connect to peer %s: %w", addr, err)
+	}
+
+	p := bc.addPeer(addr, conn)
+	if err := p.send(peerMessage{Type: peerMessageGetChain}); err != nil {
+		bc.removePeer(p)
+		return fmt.Errorf("request chain from peer %s: %w", addr, err)
+	}
+
+	go bc.handlePeer(p)
+	return nil
+}
+
+// ServePeers accepts peer connections on ln and exchanges blocks with every connected peer in the background.
+// Peers whose chain starts from a different genesis block are disconnected after the handshake.
+// It blocks until ln fails, e.g. because it was closed, and returns the error
+func (bc *Blockchain) ServePeers(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			addr := conn.RemoteAddr().String()
+			if err := bc.handshakePeer(conn, false); err != nil {
+				log.Printf("rejecting peer %s: %v", addr, err)
+				conn.Close()
+				return
+			}
+			bc.handlePeer(bc.addPeer(addr, conn))
+		}()
+	}
+}
+
+// handshakePeer exchanges hello messages over a new connection, the initiator sending first, and checks
+// that the peer's genesis hash matches the local one. The accepting side answers before comparing,
+// so both ends learn about a mismatch. Returns ErrGenesisMismatch if the genesis blocks differ
+func (bc *Blockchain) handshakePeer(conn net.Conn, initiator bool) error {
+	conn.SetDeadline(time.Now().Add(peerHandshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	bc.mu.RLock()
+	hello := peerMessage{Type: peerMessageHello, Genesis: bc.Chain[0].Hash}
+	bc.mu.RUnlock()
+
+	if initiator {
+		if err := writePeerMessage(conn, hello); err != nil {
+			return fmt.Errorf("send hello: %w", err)
+		}
+	}
+	msg, err := readPeerMessage(conn)
+	if err != nil {
+		return fmt.Errorf("read hello: %w", err)
+	}
+	if msg.Type != peerMessageHello {
+		return fmt.Errorf("expected hello message, got %q", msg.Type)
+	}
+	if !initiator {
+		if err := writePeerMessage(conn, hello); err != nil {
+			return fmt.Errorf("send hello: %w", err)
+		}
+	}
+
+	if msg.Genesis != hello.Genesis {
+		return fmt.Errorf("%w: peer chain starts from %s, local chain from %s", ErrGenesisMismatch, shortHash(msg.Genesis), shortHash(hello.Genesis))
+	}
+	return nil
+}
+
+// addPeer registers conn as a connected peer, starting the broadcast of new blocks on first use
+func (bc *Blockchain) addPeer(addr string, conn net.Conn) *peer {
+	bc.peers.startOnce.Do(func() {
+		bc.peers.queue = make(chan Block, peerBroadcastQueue)
+		bc.OnNewBlock(func(block Block) {
+			select {
+			case bc.peers.queue <- block:
+			default:
+				log.Printf("peer broadcast queue is full, dropping block %d", block.Index)
+			}
+		})
+		go func() {
+			for block := range bc.peers.queue {
+				bc.broadcastBlock(block, nil)
+			}
+		}()
+	})
+
+	p := &peer{addr: addr, conn: conn}
+	bc.peers.mu.Lock()
+	if bc.peers.conns == nil {
+		bc.peers.conns = map[*peer]bool{}
+	}
+	bc.peers.conns[p] = true
+	bc.peers.mu.Unlock()
+
+	return p
+}
+
+// removePeer closes the connection to p and forgets it
+func (bc *Blockchain) removePeer(p *peer) {
+	bc.peers.mu.Lock()
+	delete(bc.peers.conns, p)
+	bc.peers.mu.Unlock()
+
+	p.conn.Close()
+}
+
+// broadcastBlock sends the block to every connected peer except from, dropping the peers which cannot be written to
+// within peerWriteTimeout
+func (bc *Blockchain) broadcastBlock(block Block, from *peer) {
+	bc.peers.mu.Lock()
+	peers := make([]*peer, 0, len(bc.peers.conns))
+	for p := range bc.peers.conns {
+		if p != from {
+			peers = append(peers, p)
+		}
+	}
+	bc.peers.mu.Unlock()
+
+	for _, p := range peers {
+		if err := p.send(peerMessage{Type: peerMessageBlock, Block: &block}); err != nil {
+			log.Printf("sending block %d to peer %s failed: %v", block.Index, p.addr, err)
+			bc.removePeer(p)
+		}
+	}
+}
+
+// handlePeer reads and answers the messages of p until the connection fails, then removes the peer
+func (bc *Blockchain) handlePeer(p *peer) {
+	defer bc.removePeer(p)
+
+	for {
+		msg, err := readPeerMessage(p.conn)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				log.Printf("reading from peer %s failed: %v", p.addr, err)
+			}
+			return
+		}
+
+		switch msg.Type {
+		case peerMessageBlock:
+			if msg.Block != nil {
+				bc.receivePeerBlock(p, *msg.Block)
+			}
+		case peerMessageGetChain:
+			if err := p.send(peerMessage{Type: peerMessageChain, Chain: bc.GetChain()}); err != nil {
+				log.Printf("sending chain to peer %s failed: %v", p.addr, err)
+				return
+			}
+		case peerMessageChain:
+			if _, err := bc.ReplaceChain(msg.Chain); err != nil {
+				log.Printf("rejecting chain from peer %s: %v", p.addr, err)
+			}
+		default:
+			log.Printf("ignoring message of unknown type %q from peer %s", msg.Type, p.addr)
+		}
+	}
+}
+
+// receivePeerBlock appends a block received from p if it extends the tip (see AppendBlock) and relays it
+// to the other peers. A block further ahead means bc missed blocks, so the full chain of p is requested instead;
+// blocks bc already has and competing blocks below the tip are ignored
+func (bc *Blockchain) receivePeerBlock(p *peer, block Block) {
+	if bc.HasBlock(block.Hash) {
+		return
+	}
+
+	height, tip := bc.Tip()
+	if block.Index > height+1 || (block.Index == height+1 && block.PreviousHash != tip) {
+		if err := p.send(peerMessage{Type: peerMessageGetChain}); err != nil {
+			log.Printf("requesting chain from peer %s failed: %v", p.addr, err)
+		}
+		return
+	}
+	if block.Index <= height {
+		return
+	}
+
+	appended, err := bc.AppendBlock(block)
+	if err != nil {
+		log.Printf("rejecting block %d from peer %s: %v", block.Index, p.addr, err)
+		return
+	}
+	if appended {
+		bc.broadcastBlock(block, p)
+	}
+}
+
+// send writes msg to the peer, failing once the write takes longer than peerWriteTimeout
+func (p *peer) send(msg peerMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.conn.SetWriteDeadline(time.Now().Add(peerWriteTimeout)); err != nil {
+		return err
+	}
+	return writePeerMessage(p.conn, msg)
+}
+
+// writePeerMessage writes msg to w as a length-prefixed JSON message
+func writePeerMessage(w io.Writer, msg peerMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal peer message: %w", err)
+	}
+	if len(data) > maxPeerMessageSize {
+		return fmt.Errorf("peer message of %d bytes exceeds the limit of %d bytes", len(data), maxPeerMessageSize)
+	}
+
+	frame := make([]byte, 4, 4+len(data))
+	binary.BigEndian.PutUint32(frame, uint32(len(data)))
+	_, err = w.Write(append(frame, data...))
+	return err
+}
+
+// readPeerMessage reads a length-prefixed JSON message written by writePeerMessage from r
+func readPeerMessage(r io.Reader) (peerMessage, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return peerMessage{}, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxPeerMessageSize {
+		return peerMessage{}, fmt.Errorf("peer message of %d bytes exceeds the limit of %d bytes", size, maxPeerMessageSize)
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return peerMessage{}, err
+	}
+
+	var msg peerMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return peerMessage{}, fmt.Errorf("unmarshal peer message: %w", err)
+	}
+	return msg, nil
+}
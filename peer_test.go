@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// waitForTip polls bc until its tip is hash, failing the test after a few seconds
+func waitForTip(t *testing.T, bc *Blockchain, hash string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, tip := bc.Tip(); tip == hash {
+			return
+		}
+		if time.Now().After(deadline) {
+			_, tip := bc.Tip()
+			t.Fatalf("tip = %s, want %s", shortHash(tip), shortHash(hash))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// listenTestPeer serves bc on a local TCP listener which is closed at the end of the test and returns its address
+func listenTestPeer(t *testing.T, bc *Blockchain) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen(): %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go bc.ServePeers(ln)
+	return ln.Addr().String()
+}
+
+func TestPeersExchangeBlocksAndTransactions(t *testing.T) {
+	balances := map[string]int64{"alice": 100, "bob": 100}
+	a, b := newTestBlockchain(t, balances), newTestBlockchain(t, balances)
+	if err := b.ConnectPeer(listenTestPeer(t, a)); err != nil {
+		t.Fatalf("ConnectPeer(): %v", err)
+	}
+
+	// both directions: a block mined on one node carries its transaction to the other
+	steps := []struct {
+		miner, relay     *Blockchain
+		sender, receiver string
+	}{
+		{a, b, "alice", "carol"},
+		{b, a, "bob", "dave"},
+	}
+	for _, step := range steps {
+		if _, err := step.miner.addTransaction(step.sender, step.receiver, 10); err != nil {
+			t.Fatalf("addTransaction(): %v", err)
+		}
+		block := mineTestBlock(t, step.miner, "miner")
+		waitForTip(t, step.relay, block.Hash)
+		if balance := step.relay.GetBalance(step.receiver); balance != 10 {
+			t.Errorf("%s has %d units on the relaying node, want 10", step.receiver, balance)
+		}
+	}
+	for _, bc := range []*Blockchain{a, b} {
+		if height, _ := bc.Tip(); height != 2 {
+			t.Errorf("tip height = %d, want 2", height)
+		}
+		if valid, err := bc.IsChainValid(); !valid {
+			t.Errorf("IsChainValid() = false: %v", err)
+		}
+	}
+}
+
+// stalledConn shortens every write deadline so a test does not wait for peerWriteTimeout, counting the deadlines set
+type stalledConn struct {
+	net.Conn
+	deadlines int
+}
+
+func (c *stalledConn) SetWriteDeadline(time.Time) error {
+	c.deadlines++
+	return c.Conn.SetWriteDeadline(time.Now().Add(50 * time.Millisecond))
+}
+
+func TestStalledPeerIsDroppedOnWriteTimeout(t *testing.T) {
+	bc := newTestBlockchain(t, nil)
+
+	// nobody reads the remote end of the stalled pipe, so writes to it block until the deadline
+	stalledLocal, stalledRemote := net.Pipe()
+	defer stalledRemote.Close()
+	stalled := &stalledConn{Conn: stalledLocal}
+	healthyLocal, healthyRemote := net.Pipe()
+	defer healthyRemote.Close()
+	received := make(chan peerMessage, 1)
+	go func() {
+		if msg, err := readPeerMessage(healthyRemote); err == nil {
+			received <- msg
+		}
+	}()
+
+	stalledPeer := bc.addPeer("stalled", stalled)
+	healthyPeer := bc.addPeer("healthy", healthyLocal)
+	block := bc.GetChain()[0]
+	done := make(chan struct{})
+	go func() {
+		bc.broadcastBlock(block, nil)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("broadcastBlock() blocked on the stalled peer")
+	}
+
+	if stalled.deadlines == 0 {
+		t.Error("no write deadline was set before writing to the peer")
+	}
+	bc.peers.mu.Lock()
+	stalledConnected, healthyConnected := bc.peers.conns[stalledPeer], bc.peers.conns[healthyPeer]
+	bc.peers.mu.Unlock()
+	if stalledConnected {
+		t.Error("stalled peer is still connected")
+	}
+	if !healthyConnected {
+		t.Error("healthy peer was dropped")
+	}
+	select {
+	case msg := <-received:
+		if msg.Type != peerMessageBlock || msg.Block == nil || msg.Block.Hash != block.Hash {
+			t.Errorf("healthy peer received %+v, want block %s", msg, shortHash(block.Hash))
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("healthy peer did not receive the block")
+	}
+}
+
+func TestPeerBlocksWithInflatedCoinbaseAreRejected(t *testing.T) {
+	inflated := func(bc *Blockchain) Block {
+		return sealTestBlock(t, bc, []Transaction{testCoinbase(bc, "mallory", 1<<60)})
+	}
+
+	tests := []struct {
+		name string
+		// logsOnly marks paths that log rejections instead of returning them.
+		logsOnly bool
+		submit   func(bc *Blockchain, block Block) error
+	}{
+		{"AppendBlock", false, func(bc *Blockchain, block Block) error {
+			_, err := bc.AppendBlock(block)
+			return err
+		}},
+		{"ReplaceChain", false, func(bc *Blockchain, block Block) error {
+			_, err := bc.ReplaceChain(append(bc.GetChain(), block))
+			return err
+		}},
+		{"receivePeerBlock", true, func(bc *Blockchain, block Block) error {
+			local, remote := net.Pipe()
+			defer local.Close()
+			defer remote.Close()
+			bc.receivePeerBlock(&peer{addr: "test", conn: local}, block)
+			return nil
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := newTestBlockchain(t, nil)
+			if err := tt.submit(bc, inflated(bc)); !tt.logsOnly && !errors.Is(err, ErrInvalidChain) {
+				t.Fatalf("error = %v, want ErrInvalidChain", err)
+			}
+			if height, _ := bc.Tip(); height != 0 {
+				t.Errorf("tip height = %d, want 0", height)
+			}
+			if balance := bc.GetBalance("mallory"); balance != 0 {
+				t.Errorf("mallory has %d units, want 0", balance)
+			}
+		})
+	}
+}
+
+func TestChainsOfDifferentGenesisConfigsDoNotMerge(t *testing.T) {
+	local := newTestBlockchain(t, map[string]int64{"alice": 100})
+	remote := createBlockchainWithGenesis(GenesisConfig{
+		Timestamp:  defaultGenesisTimestamp,
+		Difficulty: 1,
+		Balances:   map[string]int64{"mallory": 100},
+	})
+	for range 3 {
+		mineTestBlock(t, remote, "mallory")
+	}
+	if local.GetChain()[0].Hash == remote.GetChain()[0].Hash {
+		t.Fatal("both genesis configs produce the same genesis block")
+	}
+
+	t.Run("ReplaceChain", func(t *testing.T) {
+		replaced, err := local.ReplaceChain(remote.GetChain())
+		if replaced || !errors.Is(err, ErrGenesisMismatch) || !errors.Is(err, ErrInvalidChain) {
+			t.Fatalf("ReplaceChain() = %v, %v, want ErrGenesisMismatch and ErrInvalidChain", replaced, err)
+		}
+	})
+
+	t.Run("peer handshake", func(t *testing.T) {
+		if err := local.ConnectPeer(listenTestPeer(t, remote)); !errors.Is(err, ErrGenesisMismatch) {
+			t.Fatalf("ConnectPeer() error = %v, want ErrGenesisMismatch", err)
+		}
+		local.peers.mu.Lock()
+		connected := len(local.peers.conns)
+		local.peers.mu.Unlock()
+		if connected != 0 {
+			t.Errorf("%d peers connected, want 0", connected)
+		}
+	})
+
+	if height, _ := local.Tip(); height != 0 {
+		t.Errorf("local tip height = %d, want 0", height)
+	}
+	if balance := local.GetBalance("mallory"); balance != 0 {
+		t.Errorf("mallory has %d units on the local chain, want 0", balance)
+	}
+}
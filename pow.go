@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// parallelProofOfWork is proofOfWork spread over workers goroutines; zero or fewer workers means runtime.NumCPU()
+func (bc *Blockchain) parallelProofOfWork(maxIterations uint64, workers int) (uint64, int64, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return bc.parallelProofOfWorkLocked(context.Background(), bc.nextBlockTimestampLocked(), maxIterations, workers)
+}
+
+// parallelProofOfWorkLocked searches the nonces below maxIterations with several workers, worker w trying
+// the nonces w, w+workers, w+2*workers, ... so their ranges are disjoint. A worker finding a valid nonce lowers
+// the shared upper bound, which stops every worker once it passes the bound. The result is always the lowest
+// valid nonce, the same one the serial proofOfWork finds for the same timestamp. A worker whose next nonce
+// would pass the largest uint64 stops instead of wrapping around to nonces already tried.
+// The candidate block carries candidateTimestamp, which is returned with the nonce.
+// Cancelling ctx stops all workers and returns ctx.Err()
+func (bc *Blockchain) parallelProofOfWorkLocked(ctx context.Context, candidateTimestamp int64, maxIterations uint64, workers int) (uint64, int64, error) {
+	if err := validateDifficulty(bc.Difficulty); err != nil {
+		return 0, 0, err
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	lastBlock, err := bc.latestBlockLocked()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	merkleRoot := computeMerkleRoot(bc.hasher(), bc.blockTransactionsLocked(candidateTimestamp))
+
+	var bound atomic.Uint64 // lowest valid nonce found so far, maxIterations while none is found
+	bound.Store(maxIterations)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(start uint64) {
+			defer wg.Done()
+			stride := uint64(workers)
+			for nonce, ok := start, true; ok && nonce < bound.Load(); nonce, ok = nextNonce(nonce, stride) {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+				}
+
+				if !bc.isProofValidLocked(lastBlock, nonce, merkleRoot, candidateTimestamp) {
+					continue
+				}
+				for current := bound.Load(); nonce < current; current = bound.Load() {
+					if bound.CompareAndSwap(current, nonce) {
+						break
+					}
+				}
+				return
+			}
+		}(uint64(w))
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	if nonce := bound.Load(); nonce < maxIterations {
+		return nonce, candidateTimestamp, nil
+	}
+
+	return 0, 0, ErrProofNotFound
+}
+
+// nextNonce returns the nonce stride after nonce and true, or false once that would pass the largest uint64,
+// as the nonce space is exhausted then and wrapping around would only try nonces again
+func nextNonce(nonce, stride uint64) (uint64, bool) {
+	if nonce > math.MaxUint64-stride {
+		return 0, false
+	}
+	return nonce + stride, true
+}
+
+// defaultEstimateSamples is the number of hashes EstimateMiningTime times when no sample size is given
+const defaultEstimateSamples = 1000
+
+// EstimateMiningTime projects how long MineBlock takes on average at the current difficulty (and Target, if set).
+// It times sampleNonces proof-of-work hashes (see PoWAlgorithm) of a candidate of the next block to measure the hash rate of one CPU and
+// multiplies the time per hash by the expected number of attempts, 2^256 divided by the target (2^difficulty
+// without Target), spread over all CPUs like parallelProofOfWorkLocked. A sampleNonces of zero or less means
+// defaultEstimateSamples. Returns 0 for a difficulty outside the allowed range; estimates too long to be
+// represented saturate at the largest time.Duration
+func (bc *Blockchain) EstimateMiningTime(sampleNonces int) time.Duration {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	target := bc.targetLocked(bc.Difficulty)
+	if target == nil || target.Sign() <= 0 {
+		return 0
+	}
+	if sampleNonces <= 0 {
+		sampleNonces = defaultEstimateSamples
+	}
+
+	lastBlock := bc.Chain[len(bc.Chain)-1]
+	timestamp := bc.nextBlockTimestampLocked()
+	candidate := BlockHeader{
+		Index:        lastBlock.Index + 1,
+		Timestamp:    timestamp,
+		Difficulty:   bc.Difficulty,
+		PreviousHash: lastBlock.Hash,
+		MerkleRoot:   computeMerkleRoot(bc.hasher(), bc.blockTransactionsLocked(timestamp)),
+	}
+	start := time.Now()
+	for nonce := 0; nonce < sampleNonces; nonce++ {
+		candidate.Nonce = uint64(nonce)
+		bc.proofHashLocked(candidate)
+	}
+	perHash := float64(time.Since(start)) / float64(sampleNonces)
+
+	space := new(big.Float).SetInt(new(big.Int).Lsh(big.NewInt(1), maxDifficulty))
+	attempts, _ := space.Quo(space, new(big.Float).SetInt(target)).Float64()
+	estimate := attempts * perHash / float64(runtime.NumCPU())
+	if estimate >= math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+
+	return time.Duration(math.Max(estimate, 1))
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"runtime"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParallelProofOfWorkFindsLowestNonce(t *testing.T) {
+	bc := createBlockchainWithGenesis(GenesisConfig{Timestamp: defaultGenesisTimestamp, Difficulty: 8})
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	lastBlock, _ := bc.latestBlockLocked()
+	for _, workers := range []int{1, 3, 8} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			timestamp := defaultGenesisTimestamp + int64(workers)
+			merkleRoot := computeMerkleRoot(bc.hasher(), bc.blockTransactionsLocked(timestamp))
+			want := uint64(0)
+			for !bc.isProofValidLocked(lastBlock, want, merkleRoot, timestamp) {
+				want++
+			}
+
+			nonce, gotTimestamp, err := bc.parallelProofOfWorkLocked(context.Background(), timestamp, defaultMaxIterations, workers)
+			if err != nil {
+				t.Fatalf("parallelProofOfWorkLocked(): %v", err)
+			}
+			if nonce != want || gotTimestamp != timestamp {
+				t.Errorf("parallelProofOfWorkLocked() = %d, %d, want %d, %d", nonce, gotTimestamp, want, timestamp)
+			}
+		})
+	}
+}
+
+func TestNextNonce(t *testing.T) {
+	tests := []struct {
+		nonce, stride uint64
+		want          uint64
+		wantOK        bool
+	}{
+		{0, 1, 1, true},
+		{math.MaxUint32, 1, math.MaxUint32 + 1, true},
+		{math.MaxUint32 - 1, 4, math.MaxUint32 + 3, true},
+		{math.MaxUint64 - 8, 8, math.MaxUint64, true},
+		{math.MaxUint64 - 1, 1, math.MaxUint64, true},
+		{math.MaxUint64, 1, 0, false},
+		{math.MaxUint64 - 3, 8, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d+%d", tt.nonce, tt.stride), func(t *testing.T) {
+			if got, ok := nextNonce(tt.nonce, tt.stride); got != tt.want || ok != tt.wantOK {
+				t.Errorf("nextNonce() = %d, %v, want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestExhaustedNonceSpaceIsReported(t *testing.T) {
+	h := mockHasher{calls: &atomic.Int64{}}
+	bc := createBlockchainWithGenesis(GenesisConfig{Timestamp: defaultGenesisTimestamp, Difficulty: 1, Hasher: h})
+	var attempts atomic.Int64
+	bc.SetProofPredicate(func(string) bool {
+		attempts.Add(1)
+		return false
+	})
+	const maxIterations = 1000
+
+	tests := []struct {
+		name   string
+		search func() (uint64, int64, error)
+	}{
+		{"serial", func() (uint64, int64, error) { return bc.proofOfWork(maxIterations) }},
+		{"parallel", func() (uint64, int64, error) { return bc.parallelProofOfWork(maxIterations, 3) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts.Store(0)
+			calls := h.calls.Load()
+			if _, _, err := tt.search(); !errors.Is(err, ErrProofNotFound) {
+				t.Fatalf("error = %v, want ErrProofNotFound", err)
+			}
+			if got := attempts.Load(); got != maxIterations {
+				t.Errorf("%d nonces tried, want every one of the %d", got, maxIterations)
+			}
+			if got := h.calls.Load() - calls; got < maxIterations {
+				t.Errorf("the mocked hasher was called %d times, want at least %d", got, maxIterations)
+			}
+		})
+	}
+}
+
+func TestNoncesPastUint32HashApart(t *testing.T) {
+	header := BlockHeader{Index: 1, Timestamp: defaultGenesisTimestamp, Difficulty: 1, PreviousHash: "0"}
+	// nonces equal in their low 32 bits would collide if the nonce were truncated to 32 bits
+	pairs := [][2]uint64{{0, 1 << 32}, {math.MaxUint32, math.MaxUint64}, {math.MaxUint32, math.MaxUint32 + 1}}
+	for _, pair := range pairs {
+		a, b := header, header
+		a.Nonce, b.Nonce = pair[0], pair[1]
+		if reflect.DeepEqual(a.serializeForHash(), b.serializeForHash()) {
+			t.Errorf("nonces %d and %d serialize alike", pair[0], pair[1])
+		}
+		if calculateHash(SHA256Hasher{}, a) == calculateHash(SHA256Hasher{}, b) {
+			t.Errorf("nonces %d and %d hash alike", pair[0], pair[1])
+		}
+	}
+}
+
+// BenchmarkProofOfWork compares the serial proof of work with the parallel one; every iteration
+// mines a fresh candidate timestamp, so run it with enough iterations to average out the luck of the nonce
+func BenchmarkProofOfWork(b *testing.B) {
+	bc := createBlockchainWithGenesis(GenesisConfig{Timestamp: defaultGenesisTimestamp, Difficulty: 14})
+
+	b.Run("serial", func(b *testing.B) {
+		for b.Loop() {
+			if _, _, err := bc.proofOfWork(defaultMaxIterations); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	for _, workers := range []int{2, runtime.NumCPU()} {
+		b.Run(fmt.Sprintf("parallel %d", workers), func(b *testing.B) {
+			for b.Loop() {
+				if _, _, err := bc.parallelProofOfWork(defaultMaxIterations, workers); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// TestScryptRFC7914Vectors pins the scrypt implementation behind ScryptPoW to the test vectors of
+// RFC 7914 section 12; the last vector needs 1 GiB of memory and is left out
+func TestScryptRFC7914Vectors(t *testing.T) {
+	tests := []struct {
+		name, password, salt string
+		n, r, p              int
+		want                 string
+	}{
+		{"empty", "", "", 16, 1, 1, "77d6576238657b203b19ca42c18a0497f16b4844e3074ae8dfdffa3fede21442" +
+			"fcd0069ded0948f8326a753a0fc81f17e8d3e0fb2e0d3628cf35e20c38d18906"},
+		{"NaCl", "password", "NaCl", 1024, 8, 16, "fdbabe1c9d3472007856e7190d01e9fe7c6ad7cbc8237830e77376634b373162" +
+			"2eaf30d92e22a3886ff109279d9830dac727afb94a83ee6d8360cbdfa2cc0640"},
+		{"SodiumChloride", "pleaseletmein", "SodiumChloride", 16384, 8, 1, "7023bdcb3afd7348461c06cd81fd38ebfda8fbba904f8e3ea9b543f6545da1f2" +
+			"d5432955613f0fcf62d49705242a9af9e61e85dc0d651e40dfcf017b45575887"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := scrypt.Key([]byte(tt.password), []byte(tt.salt), tt.n, tt.r, tt.p, 64)
+			if err != nil {
+				t.Fatalf("scrypt.Key(): %v", err)
+			}
+			if got := hex.EncodeToString(key); got != tt.want {
+				t.Errorf("scrypt.Key() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScryptPoWMineThenValidate(t *testing.T) {
+	bc := createBlockchainWithGenesis(GenesisConfig{Timestamp: defaultGenesisTimestamp, Difficulty: 4, PoW: ScryptPoW{}})
+	for range 2 {
+		block := mineTestBlock(t, bc, "miner")
+		if proof := (ScryptPoW{}).ProofHash(bc.hasher(), block.serializeForHash()); !meetsDifficulty(proof, block.Difficulty) {
+			t.Fatalf("block %d: scrypt proof %s does not meet difficulty %d", block.Index, proof, block.Difficulty)
+		}
+	}
+	if valid, err := bc.IsChainValid(); !valid {
+		t.Fatalf("IsChainValid() = false: %v", err)
+	}
+
+	headers := bc.ExportHeaders()
+	if err := verifyHeaderChain(bc.hasher(), ScryptPoW{}, headers); err != nil {
+		t.Fatalf("verifyHeaderChain(): %v", err)
+	}
+
+	// a nonce whose scrypt proof misses the difficulty, with the block hash recalculated to match it
+	tip := &headers[len(headers)-1]
+	for {
+		tip.Nonce++
+		if !meetsDifficulty((ScryptPoW{}).ProofHash(bc.hasher(), tip.serializeForHash()), tip.Difficulty) {
+			break
+		}
+	}
+	tip.Hash = calculateHash(bc.hasher(), *tip)
+	if err := verifyHeaderChain(bc.hasher(), ScryptPoW{}, headers); !errors.Is(err, ErrInvalidChain) {
+		t.Fatalf("verifyHeaderChain() with an invalid scrypt proof = %v, want ErrInvalidChain", err)
+	}
+}
+
+func TestProofPredicateHashEndsInF(t *testing.T) {
+	// the built-in check of difficulty 40 could not be met in a test, the predicate accepts a sixteenth of all hashes
+	bc := createBlockchainWithGenesis(GenesisConfig{Timestamp: defaultGenesisTimestamp, Difficulty: 40})
+	endsInF := func(hash string) bool { return strings.HasSuffix(hash, "f") }
+	bc.SetProofPredicate(endsInF)
+	for range 3 {
+		mineTestBlock(t, bc, "miner")
+	}
+
+	bc.mu.RLock()
+	for _, block := range bc.Chain[1:] {
+		if proof := bc.proofHashLocked(block.BlockHeader); !endsInF(proof) {
+			t.Errorf("block %d has proof-of-work hash %s, which does not end in f", block.Index, proof)
+		}
+	}
+	bc.mu.RUnlock()
+	if valid, err := bc.IsChainValid(); !valid {
+		t.Fatalf("IsChainValid() with the predicate = false: %v", err)
+	}
+
+	t.Run("block rejected by the predicate", func(t *testing.T) {
+		block := bc.GetChain()[1]
+		bc.mu.RLock()
+		for block.Nonce++; endsInF(bc.proofHashLocked(block.BlockHeader)); block.Nonce++ {
+		}
+		bc.mu.RUnlock()
+		block.Hash = calculateHash(bc.hasher(), block.BlockHeader)
+
+		other := createBlockchainWithGenesis(GenesisConfig{Timestamp: defaultGenesisTimestamp, Difficulty: 40})
+		other.SetProofPredicate(endsInF)
+		if _, err := other.AppendBlock(block); !errors.Is(err, ErrInvalidChain) {
+			t.Errorf("AppendBlock() error = %v, want ErrInvalidChain", err)
+		}
+	})
+
+	t.Run("built-in check restored", func(t *testing.T) {
+		bc.SetProofPredicate(nil)
+		defer bc.SetProofPredicate(endsInF)
+		if valid, _ := bc.IsChainValid(); valid {
+			t.Error("IsChainValid() without the predicate = true, want the built-in difficulty check to fail")
+		}
+	})
+
+	t.Run("not persisted", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "chain.json")
+		if err := bc.SaveToFile(path); err != nil {
+			t.Fatalf("SaveToFile(): %v", err)
+		}
+		if _, err := LoadFromFile(path); !errors.Is(err, ErrInvalidChain) {
+			t.Errorf("LoadFromFile() error = %v, want ErrInvalidChain as the predicate is not saved", err)
+		}
+	})
+}
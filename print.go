@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// shortHashLength is the number of hex characters of a hash shown by the human-readable output
+const shortHashLength = 16
+
+// shortHash truncates a hash to shortHashLength characters for display
+func shortHash(hash string) string {
+	if len(hash) <= shortHashLength {
+		return hash
+	}
+	return hash[:shortHashLength] + "..."
+}
+
+// String formats the block with its index, UTC timestamp, shortened hashes, nonce, difficulty, size
+// and one line per transaction, or a note that the transactions were pruned
+func (b Block) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Block #%d\n", b.Index)
+	layout := time.DateTime + " MST"
+	if b.Timestamp > maxSecondTimestamp {
+		layout = time.DateTime + ".000 MST"
+	}
+	fmt.Fprintf(&sb, "  Timestamp:    %s\n", blockTime(b.Timestamp).UTC().Format(layout))
+	fmt.Fprintf(&sb, "  Hash:         %s\n", shortHash(b.Hash))
+	fmt.Fprintf(&sb, "  Previous:     %s\n", shortHash(b.PreviousHash))
+	fmt.Fprintf(&sb, "  Merkle root:  %s\n", shortHash(b.MerkleRoot))
+	fmt.Fprintf(&sb, "  Nonce:        %d\n", b.Nonce)
+	fmt.Fprintf(&sb, "  Difficulty:   %d\n", b.Difficulty)
+	fmt.Fprintf(&sb, "  Size:         %d bytes\n", b.Size())
+	if b.Pruned {
+		sb.WriteString("  Transactions: pruned\n")
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, "  Transactions: %d\n", len(b.Transactions))
+	for _, tx := range b.Transactions {
+		fmt.Fprintf(&sb, "    %s\n", tx)
+	}
+
+	return sb.String()
+}
+
+// String formats the transaction on a single line as sender, recipients, amount, fee, memo and expiry time if any
+// and shortened TXID
+func (tx Transaction) String() string {
+	recipients := tx.Recipient
+	if payments := tx.payments(); len(payments) > 1 {
+		addresses := make([]string, len(payments))
+		for i, out := range payments {
+			addresses[i] = out.Address
+		}
+		recipients = strings.Join(addresses, ", ")
+	}
+	memo := ""
+	if tx.Memo != "" {
+		memo = fmt.Sprintf(" memo %q", tx.Memo)
+	}
+	if tx.ExpiryTime != 0 {
+		memo += fmt.Sprintf(" expires %d", tx.ExpiryTime)
+	}
+	return fmt.Sprintf("%s -> %s: %s (fee %s)%s txid %s", tx.Sender, recipients,
+		formatAmount(tx.Amount), formatAmount(tx.Fee), memo, shortHash(tx.TXID))
+}
+
+// String formats every block of the chain from genesis to tip, followed by the size of the mempool
+func (bc *Blockchain) String() string {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	var sb strings.Builder
+	for _, block := range bc.Chain {
+		sb.WriteString(block.String())
+		sb.WriteString("\n")
+	}
+	fmt.Fprintf(&sb, "Pending transactions: %d\n", len(bc.Transactions))
+
+	return sb.String()
+}
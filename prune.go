@@ -0,0 +1,106 @@
+package main
+
+import "fmt"
+
+// Checkpoint summarizes the state of the chain up to a height whose block bodies were dropped by Prune,
+// so balances, nonces and unspent outputs stay correct without the pruned transactions
+type Checkpoint struct {
+	Height   int                 `json:"height"`   // index of the last pruned block
+	Balances map[string]int64    `json:"balances"` // account-model balance of every address at Height
+	Nonces   map[string]uint64   `json:"nonces"`   // last nonce used by every sender at Height
+	UTXO     map[string]TXOutput `json:"utxo"`     // unspent outputs at Height, tracked even without the UTXO model so it can be enabled later
+}
+
+// Prune drops the transactions of every block except the last keep blocks, leaving header-only stubs
+// which still carry everything their hashes cover, so IsChainValid can verify the links and proofs of work
+// of the whole chain. The state of the pruned blocks is folded into bc.Checkpoint.
+// Pruned transactions are no longer returned by FindTransaction, MerkleProof, GetTransactionHistory
+// or counted by TotalTransactions, and resubmitting one of them is not detected as a duplicate.
+// Returns ErrInvalidPrune if keep is less than 1, as the tip must remain a full block, or less than CoinbaseMaturity,
+// as the checkpoint credits the coinbase rewards it covers as spendable; a keep covering the whole chain prunes nothing
+func (bc *Blockchain) Prune(keep int) error {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if keep < max(1, bc.CoinbaseMaturity) {
+		return fmt.Errorf("%w: at least %d blocks must be kept, got %d", ErrInvalidPrune, max(1, bc.CoinbaseMaturity), keep)
+	}
+
+	height := len(bc.Chain) - 1 - keep
+	if height < 0 || (bc.Checkpoint != nil && height <= bc.Checkpoint.Height) {
+		return nil
+	}
+
+	checkpoint, err := bc.checkpointAtLocked(height)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidPrune, err)
+	}
+	for i := 0; i <= height; i++ {
+		bc.Chain[i] = bc.Chain[i].stub()
+	}
+	bc.Checkpoint = checkpoint
+
+	return nil
+}
+
+// checkpointAtLocked folds the blocks up to and including height into a copy of the current checkpoint.
+// A block which does not apply to the UTXO set fails it with the error of applyBlockToUTXO under the UTXO model,
+// and is left out of the tracked set otherwise, as the account model does not check transaction inputs
+func (bc *Blockchain) checkpointAtLocked(height int) (*Checkpoint, error) {
+	checkpoint := bc.Checkpoint.clone()
+	checkpoint.Height = height
+	for _, block := range bc.Chain[:height+1] {
+		for _, tx := range block.Transactions {
+			if tx.Sender != coinbaseSender {
+				checkpoint.Balances[tx.Sender] -= tx.Amount + tx.Fee
+				if tx.Nonce > checkpoint.Nonces[tx.Sender] {
+					checkpoint.Nonces[tx.Sender] = tx.Nonce
+				}
+			}
+			for _, out := range tx.payments() {
+				checkpoint.Balances[out.Address] += out.Amount
+			}
+		}
+	}
+
+	for _, block := range bc.Chain[:height+1] {
+		if _, err := applyBlockToUTXO(checkpoint.UTXO, block); err != nil && bc.useUTXO {
+			return nil, err
+		}
+	}
+
+	return checkpoint, nil
+}
+
+// clone returns a deep copy of the checkpoint; a nil checkpoint yields an empty one at height -1
+func (c *Checkpoint) clone() *Checkpoint {
+	clone := &Checkpoint{
+		Height:   -1,
+		Balances: map[string]int64{},
+		Nonces:   map[string]uint64{},
+		UTXO:     map[string]TXOutput{},
+	}
+	if c == nil {
+		return clone
+	}
+
+	clone.Height = c.Height
+	for address, balance := range c.Balances {
+		clone.Balances[address] = balance
+	}
+	for address, nonce := range c.Nonces {
+		clone.Nonces[address] = nonce
+	}
+	for key, out := range c.UTXO {
+		clone.UTXO[key] = out
+	}
+
+	return clone
+}
+
+// stub returns the header of the block without its transactions, marked as pruned
+func (b Block) stub() Block {
+	b.Transactions = nil
+	b.Pruned = true
+	return b
+}
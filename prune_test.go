@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPruneKeepsImmatureCoinbases(t *testing.T) {
+	tests := []struct {
+		name    string
+		keep    int
+		wantErr bool
+	}{
+		{"below maturity", 2, true},
+		{"maturity", 3, false},
+		{"above maturity", 4, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := newTestBlockchain(t, nil)
+			bc.CoinbaseMaturity = 3
+			for range 6 {
+				mineTestBlock(t, bc, "miner")
+			}
+			before := bc.GetBalance("miner")
+
+			err := bc.Prune(tt.keep)
+			if tt.wantErr != errors.Is(err, ErrInvalidPrune) || (!tt.wantErr && err != nil) {
+				t.Fatalf("Prune(%d) error = %v, want ErrInvalidPrune: %v", tt.keep, err, tt.wantErr)
+			}
+			if after := bc.GetBalance("miner"); after != before {
+				t.Errorf("miner balance went from %d to %d", before, after)
+			}
+
+			// the rewards still immature at the tip mature one by one, as without pruning
+			mineTestBlock(t, bc, "other")
+			if got, want := bc.GetBalance("miner"), before+bc.BlockReward; got != want {
+				t.Errorf("miner balance one block later = %d, want %d", got, want)
+			}
+			if valid, err := bc.IsChainValid(); !valid {
+				t.Errorf("IsChainValid(): %v", err)
+			}
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	tests := []struct {
+		name    string
+		utxo    bool
+		keep    int
+		wantErr bool
+	}{
+		{"no block kept", false, 0, true},
+		{"account model", false, 1, false},
+		{"UTXO model", true, 1, false},
+		{"whole chain kept", false, 10, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := newTestBlockchain(t, map[string]int64{"alice": 100})
+			if tt.utxo {
+				bc.EnableUTXO()
+			}
+			for _, amount := range []int64{10, 20} {
+				if _, err := bc.submitTransaction(Transaction{Sender: "alice", Recipient: "bob", Amount: amount}); err != nil {
+					t.Fatalf("submitTransaction(): %v", err)
+				}
+				mineTestBlock(t, bc, "miner")
+			}
+
+			err := bc.Prune(tt.keep)
+			if tt.wantErr != errors.Is(err, ErrInvalidPrune) || (!tt.wantErr && err != nil) {
+				t.Fatalf("Prune(%d) error = %v, want ErrInvalidPrune: %v", tt.keep, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			for _, address := range []string{"alice", "bob"} {
+				if got, want := bc.GetBalance(address), map[string]int64{"alice": 70, "bob": 30}[address]; got != want {
+					t.Errorf("GetBalance(%q) = %d, want %d", address, got, want)
+				}
+			}
+
+			// the pruned state keeps its nonces and outputs for new transactions
+			if _, err := bc.submitTransaction(Transaction{Sender: "alice", Recipient: "bob", Amount: 70}); err != nil {
+				t.Fatalf("submitTransaction() after Prune: %v", err)
+			}
+			mineTestBlock(t, bc, "miner")
+			if got := bc.GetBalance("alice"); got != 0 {
+				t.Errorf("GetBalance(alice) = %d, want 0", got)
+			}
+			if valid, err := bc.IsChainValid(); !valid {
+				t.Errorf("IsChainValid(): %v", err)
+			}
+		})
+	}
+}
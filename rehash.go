@@ -0,0 +1,54 @@
+package main
+
+import "fmt"
+
+// Rehash re-links the chain after its blocks were edited in place: starting from the genesis block it
+// recomputes the TXIDs and the Merkle root of every full block, sets each PreviousHash to the hash
+// of the block before it and re-mines every block after the genesis block, searching the lowest nonce whose
+// proof of work meets the difficulty the block records, before recalculating its Hash. Once it returns,
+// IsChainValid accepts the links and proofs of the chain, and only reports edits breaking other rules, such as
+// a transaction spending more than its sender owns; signatures over a changed TXID no longer verify either.
+// Returns ErrProofNotFound, leaving the chain partially re-linked, if a block has no valid nonce below
+// defaultMaxIterations, which keeps Rehash to chains of a low difficulty.
+//
+// Rehash forges proof of work for blocks nobody mined. It is meant for building test fixtures only and must never
+// be used on a production chain, where it would silently accept tampered blocks as the local history
+func (bc *Blockchain) Rehash() error {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	defer bc.rebuildUTXOLocked()
+	defer bc.rebuildNoncesLocked()
+
+	h := bc.hasher()
+	for i := range bc.Chain {
+		block := &bc.Chain[i]
+		if i > 0 {
+			block.PreviousHash = bc.Chain[i-1].Hash
+		}
+		if !block.Pruned {
+			for j := range block.Transactions {
+				block.Transactions[j].TXID = generateTransactionID(h, block.Transactions[j])
+			}
+			block.MerkleRoot = computeMerkleRoot(h, block.Transactions)
+		}
+		if i > 0 {
+			if err := bc.remineLocked(&block.BlockHeader); err != nil {
+				return err
+			}
+		}
+		block.Hash = calculateHash(h, block.BlockHeader)
+	}
+
+	return nil
+}
+
+// remineLocked sets the nonce of the header to the lowest one below defaultMaxIterations meeting the proof of work
+func (bc *Blockchain) remineLocked(header *BlockHeader) error {
+	for nonce := uint64(0); nonce < defaultMaxIterations; nonce++ {
+		header.Nonce = nonce
+		if bc.meetsProofLocked(*header) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: re-mining block %d", ErrProofNotFound, header.Index)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRehash(t *testing.T) {
+	tests := []struct {
+		name      string
+		amount    int64
+		wantErr   error
+		wantAlice int64
+	}{
+		{"edited amount", 40, nil, 960},
+		{"edited amount overspending", 5000, ErrInvalidChain, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := createBlockchainWithGenesis(GenesisConfig{
+				Timestamp:  defaultGenesisTimestamp,
+				Difficulty: 6,
+				Balances:   map[string]int64{"alice": 1000},
+			})
+			if _, err := bc.addTransaction("alice", "bob", 10); err != nil {
+				t.Fatalf("addTransaction(): %v", err)
+			}
+			for range 3 {
+				mineTestBlock(t, bc, "miner")
+			}
+
+			bc.Chain[1].Transactions[1].Amount = tt.amount
+			if valid, _ := bc.IsChainValid(); valid {
+				t.Fatalf("IsChainValid() = true after editing a block")
+			}
+			if err := bc.Rehash(); err != nil {
+				t.Fatalf("Rehash(): %v", err)
+			}
+
+			chain := bc.GetChain()
+			for i := 1; i < len(chain); i++ {
+				if chain[i].PreviousHash != chain[i-1].Hash || !meetsDifficulty(chain[i].Hash, chain[i].Difficulty) {
+					t.Errorf("block %d is not re-linked and re-mined", i)
+				}
+			}
+			_, err := bc.IsChainValid()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("IsChainValid() after Rehash() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && bc.GetBalance("alice") != tt.wantAlice {
+				t.Errorf("GetBalance(alice) = %d, want %d", bc.GetBalance("alice"), tt.wantAlice)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import "fmt"
+
+// maxReorgUndoDepth is the number of most recent blocks whose UTXO changes are kept, so Reorg can disconnect
+// them one by one; a reorg replacing deeper blocks rebuilds the UTXO set from the chain instead
+const maxReorgUndoDepth = 100
+
+// Reorg switches the chain to a competing branch: newBlocks must be consecutive blocks, the first one built on
+// a block of the chain, their common ancestor, and the chain ending in the branch must be longer than the current
+// one and pass the IsChainValid rules. The blocks above the ancestor are disconnected from the tip down, undoing
+// their spends and outputs in the UTXO set, then the branch is connected. Transactions of disconnected blocks which
+// the branch does not confirm return to the mempool, and the mempool is revalidated against the new chain, so
+// pending transactions no longer affordable there are dropped and logged. Balances of the account model and nonces
+// follow the new chain. Like blocks adopted through ReplaceChain the branch is not reported to OnNewBlock.
+// Returns an error wrapping ErrInvalidChain if the branch does not connect, forks below the checkpoint,
+// is not longer than the chain or fails validation; the chain is unchanged then
+func (bc *Blockchain) Reorg(newBlocks []Block) error {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if len(newBlocks) == 0 {
+		return fmt.Errorf("%w: branch contains no blocks", ErrInvalidChain)
+	}
+
+	ancestor := -1
+	for i, block := range bc.Chain {
+		if block.Hash == newBlocks[0].PreviousHash {
+			ancestor = i
+			break
+		}
+	}
+	switch {
+	case ancestor < 0:
+		return fmt.Errorf("%w: branch block %d builds on unknown block %s", ErrInvalidChain, newBlocks[0].Index, shortHash(newBlocks[0].PreviousHash))
+	case bc.Checkpoint != nil && ancestor < bc.Checkpoint.Height:
+		return fmt.Errorf("%w: branch forks below the checkpoint at block %d", ErrInvalidChain, bc.Checkpoint.Height)
+	case ancestor+1+len(newBlocks) <= len(bc.Chain):
+		return fmt.Errorf("%w: branch of %d blocks above block %d does not make the chain of %d blocks longer", ErrInvalidChain,
+			len(newBlocks), ancestor, len(bc.Chain))
+	}
+
+	if err := bc.reorgLocked(ancestor, newBlocks); err != nil {
+		return fmt.Errorf("branch is invalid: %w", err)
+	}
+	return nil
+}
+
+// reorgLocked replaces the blocks above the ancestor at index ancestor with newBlocks once the resulting chain
+// passes validation, returning the validation error otherwise (see Reorg)
+func (bc *Blockchain) reorgLocked(ancestor int, newBlocks []Block) error {
+	chain := bc.Chain[: ancestor+1 : ancestor+1]
+	for _, block := range newBlocks {
+		chain = append(chain, block.clone())
+	}
+	if err := bc.validateChainLocked(chain); err != nil {
+		return err
+	}
+
+	confirmed := map[string]bool{}
+	for _, block := range newBlocks {
+		for _, tx := range block.Transactions {
+			confirmed[tx.TXID] = true
+		}
+	}
+
+	pending := []Transaction{}
+	for _, block := range bc.Chain[ancestor+1:] {
+		for _, tx := range block.Transactions {
+			if tx.Sender != coinbaseSender && !confirmed[tx.TXID] {
+				pending = append(pending, tx)
+			}
+		}
+	}
+	for _, tx := range bc.Transactions {
+		if !confirmed[tx.TXID] {
+			pending = append(pending, tx)
+		}
+	}
+
+	undone := bc.useUTXO
+	for i := len(bc.Chain) - 1; i > ancestor && undone; i-- {
+		undone = bc.disconnectBlockUTXOLocked(bc.Chain[i])
+	}
+
+	bc.Chain = chain
+	for i := 0; i < len(newBlocks) && undone; i++ {
+		undone = bc.connectBlockUTXOLocked(newBlocks[i]) == nil
+	}
+	if !undone {
+		bc.rebuildUTXOLocked()
+	}
+
+	bc.Transactions = pending
+	bc.revalidateMempoolLocked()
+	bc.adjustDifficulty()
+
+	return nil
+}
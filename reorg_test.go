@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"maps"
+	"testing"
+)
+
+// forkTestChains returns a UTXO-model chain where alice paid bob 30 units in block 1 and a competing chain
+// with one block mined on the same genesis block
+func forkTestChains(t *testing.T) (*Blockchain, *Blockchain) {
+	t.Helper()
+	balances := map[string]int64{"alice": 100}
+
+	bc := newTestBlockchain(t, balances)
+	bc.EnableUTXO()
+	if _, err := bc.submitTransaction(Transaction{Sender: "alice", Recipient: "bob", Amount: 30}); err != nil {
+		t.Fatalf("submitTransaction(): %v", err)
+	}
+	mineTestBlock(t, bc, "miner")
+
+	competitor := newTestBlockchain(t, balances)
+	competitor.EnableUTXO()
+	mineTestBlock(t, competitor, "other")
+
+	return bc, competitor
+}
+
+func TestReorgRestoresUTXOSet(t *testing.T) {
+	bc, competitor := forkTestChains(t)
+	mineTestBlock(t, competitor, "other")
+	if err := bc.Reorg(competitor.GetChain()[1:]); err != nil {
+		t.Fatalf("Reorg(): %v", err)
+	}
+
+	tests := []struct {
+		address string
+		want    int64
+	}{
+		{"alice", 100},
+		{"bob", 0},
+		{"miner", 0},
+	}
+	for _, tt := range tests {
+		if got := bc.GetBalance(tt.address); got != tt.want {
+			t.Errorf("GetBalance(%q) = %d, want %d", tt.address, got, tt.want)
+		}
+	}
+	if mempool := bc.Mempool(); len(mempool) != 1 || mempool[0].Recipient != "bob" {
+		t.Errorf("mempool = %v, want the disconnected payment to bob", mempool)
+	}
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	connected := maps.Clone(bc.utxo)
+	bc.rebuildUTXOLocked()
+	if !maps.Equal(connected, bc.utxo) {
+		t.Errorf("UTXO set after Reorg = %v, rebuilt from the chain = %v", connected, bc.utxo)
+	}
+}
+
+func TestReorgRejectsBranchSpendingMissingOutput(t *testing.T) {
+	bc, competitor := forkTestChains(t)
+	before := bc.GetChain()
+
+	spend := utxoTestTransaction(bc, "alice", []TXInput{{TXID: "missing", Output: 0}}, 10, 0, 0)
+	tip := sealTestBlock(t, competitor, []Transaction{testCoinbase(competitor, "other", competitor.BlockReward), spend})
+	branch := append(competitor.GetChain()[1:], tip)
+
+	if err := bc.Reorg(branch); !errors.Is(err, ErrInvalidChain) {
+		t.Fatalf("Reorg() error = %v, want ErrInvalidChain", err)
+	}
+	if height, hash := bc.Tip(); height != len(before)-1 || hash != before[len(before)-1].Hash {
+		t.Errorf("tip moved to block %d %s", height, hash)
+	}
+	if got := bc.GetBalance("bob"); got != 30 {
+		t.Errorf("GetBalance(bob) = %d, want 30", got)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// Backoff between the attempts of MineWithRetry: the first retry waits mineRetryBaseDelay, one second
+// so the next candidate carries a new timestamp, and every further retry twice as long up to mineRetryMaxDelay
+const (
+	mineRetryBaseDelay = time.Second
+	mineRetryMaxDelay  = 30 * time.Second
+)
+
+// MineWithRetry is MineBlockCtx which tries up to attempts times, or once if attempts is zero or less, when
+// the proof of work gives up with ErrProofNotFound, as it may after a difficulty adjustment made blocks harder.
+// The lock is released while it waits before the next attempt, with an exponential backoff starting at one second,
+// so the next candidate has a new timestamp, giving a fresh nonce space, and a mempool selection taking in
+// the transactions added meanwhile. ctx bounds all attempts together: once it is cancelled or its deadline
+// passes, mining and waiting stop and ctx.Err() is returned. Any other error is returned at once.
+// Returns the mined block, or the error of the last attempt
+func (bc *Blockchain) MineWithRetry(ctx context.Context, minerAddress string, attempts int) (Block, error) {
+	return mineWithRetry(ctx, attempts, mineRetryBaseDelay, func(ctx context.Context) (Block, error) {
+		return bc.MineBlockCtx(ctx, minerAddress)
+	})
+}
+
+// mineWithRetry runs the attempts of MineWithRetry with mine, waiting delay before the first retry
+func mineWithRetry(ctx context.Context, attempts int, delay time.Duration, mine func(ctx context.Context) (Block, error)) (Block, error) {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		block, err := mine(ctx)
+		if !errors.Is(err, ErrProofNotFound) || attempt == attempts {
+			return block, err
+		}
+
+		log.Printf("mining attempt %d of %d failed: %v, retrying in %s", attempt, attempts, err, delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return Block{}, ctx.Err()
+		case <-timer.C:
+		}
+		delay = min(2*delay, mineRetryMaxDelay)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMineWithRetry(t *testing.T) {
+	mined := Block{BlockHeader: BlockHeader{Index: 1, Hash: "mined"}}
+	errUnexpected := errors.New("unexpected")
+
+	tests := []struct {
+		name         string
+		attempts     int
+		results      []error // result of every call to mine, nil for success
+		timeout      time.Duration
+		wantErr      error
+		wantAttempts int
+	}{
+		{"first attempt", 3, []error{nil}, 0, nil, 1},
+		{"second attempt", 3, []error{ErrProofNotFound, nil}, 0, nil, 2},
+		{"attempts exhausted", 2, []error{ErrProofNotFound, ErrProofNotFound, nil}, 0, ErrProofNotFound, 2},
+		{"no attempts tries once", 0, []error{ErrProofNotFound, nil}, 0, ErrProofNotFound, 1},
+		{"other errors are not retried", 3, []error{errUnexpected, nil}, 0, errUnexpected, 1},
+		{"deadline while waiting", 3, []error{ErrProofNotFound, nil}, time.Millisecond, context.DeadlineExceeded, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			delay := time.Millisecond
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+				delay = time.Minute
+			}
+
+			calls := 0
+			block, err := mineWithRetry(ctx, tt.attempts, delay, func(context.Context) (Block, error) {
+				err := tt.results[calls]
+				calls++
+				if err != nil {
+					return Block{}, err
+				}
+				return mined, nil
+			})
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("mineWithRetry() error = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantAttempts {
+				t.Errorf("mine called %d times, want %d", calls, tt.wantAttempts)
+			}
+			if err == nil && block.Hash != mined.Hash {
+				t.Errorf("mineWithRetry() = block %s, want %s", block.Hash, mined.Hash)
+			}
+		})
+	}
+}
+
+func TestMineWithRetryMinesBlock(t *testing.T) {
+	bc := newTestBlockchain(t, nil)
+
+	block, err := bc.MineWithRetry(context.Background(), "miner", 3)
+	if err != nil {
+		t.Fatalf("MineWithRetry(): %v", err)
+	}
+	if height, hash := bc.Tip(); height != 1 || hash != block.Hash {
+		t.Errorf("Tip() = %d, %s, want the mined block 1, %s", height, hash, block.Hash)
+	}
+}
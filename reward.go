@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// maxHalvings is the number of halvings after which the block reward is zero, like the 64 bit shift limit
+// of Bitcoin; shifting an int64 by more bits is zero anyway
+const maxHalvings = 64
+
+// blockRewardLocked returns the base units minted by the coinbase transaction of the block at index:
+// bc.BlockReward for the first HalvingInterval mined blocks, half of it for the next HalvingInterval blocks
+// and so on, down to zero after maxHalvings halvings. Without a HalvingInterval the reward stays constant
+func (bc *Blockchain) blockRewardLocked(index int) int64 {
+	if bc.HalvingInterval <= 0 || index < 1 {
+		return bc.BlockReward
+	}
+
+	halvings := (index - 1) / bc.HalvingInterval
+	if halvings >= maxHalvings {
+		return 0
+	}
+	return bc.BlockReward >> halvings
+}
+
+// treasuryShareLocked returns the base units of a coinbase paying total which go to bc.TreasuryAddress:
+// TreasuryPercent of total, rounded down. The miner receives the rest, so the split never changes the issuance.
+// Returns ErrInvalidTreasury if TreasuryPercent is outside [0, 100] or set without a TreasuryAddress
+func (bc *Blockchain) treasuryShareLocked(total int64) (int64, error) {
+	percent := bc.TreasuryPercent
+	switch {
+	case math.IsNaN(percent) || percent < 0 || percent > 100:
+		return 0, fmt.Errorf("%w: percent %g is outside [0, 100]", ErrInvalidTreasury, percent)
+	case percent > 0 && strings.TrimSpace(bc.TreasuryAddress) == "":
+		return 0, fmt.Errorf("%w: percent %g without a treasury address", ErrInvalidTreasury, percent)
+	}
+
+	// the float product of a total beyond 2^53 units may round above total
+	return min(int64(float64(total)*percent/100), total), nil
+}
+
+// coinbaseTransactionLocked is newCoinbaseTransaction paying the treasury share of reward (see treasuryShareLocked)
+// to bc.TreasuryAddress: the coinbase then has an output for the miner followed by one for the treasury,
+// and its Amount remains the whole reward. A miner mining to the treasury address gets a single output
+func (bc *Blockchain) coinbaseTransactionLocked(minerAddress string, reward int64, blockIndex int) (Transaction, error) {
+	share, err := bc.treasuryShareLocked(reward)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	tx := newCoinbaseTransaction(bc.hasher(), minerAddress, reward, blockIndex)
+	if share == 0 || bc.TreasuryAddress == minerAddress {
+		return tx, nil
+	}
+
+	if reward > share {
+		tx.Outputs = append(tx.Outputs, TXOutput{Address: minerAddress, Amount: reward - share})
+	}
+	tx.Outputs = append(tx.Outputs, TXOutput{Address: bc.TreasuryAddress, Amount: share})
+	tx.Recipient = tx.Outputs[0].Address
+	tx.TXID = generateTransactionID(bc.hasher(), tx)
+
+	return tx, nil
+}
+
+// validateCoinbaseLocked checks the coinbase transaction of a block: the block must start with the only transaction
+// sent by coinbaseSender, which spends no inputs, carries the block index as nonce, pays outputs summing up to its
+// amount and mints at most the block reward of its index (see blockRewardLocked) plus the fees of the block.
+// At least the treasury share of its amount (see treasuryShareLocked) must go to bc.TreasuryAddress.
+// Every other transaction must pass validateTransactionFields. Returns an error wrapping ErrInvalidChain otherwise
+func (bc *Blockchain) validateCoinbaseLocked(block Block) error {
+	if len(block.Transactions) == 0 || block.Transactions[0].Sender != coinbaseSender {
+		return fmt.Errorf("%w: block %d: first transaction is not a coinbase", ErrInvalidChain, block.Index)
+	}
+
+	fees := int64(0)
+	for _, tx := range block.Transactions[1:] {
+		if err := validateTransactionFields(tx); err != nil {
+			return fmt.Errorf("%w: block %d: transaction %s: %w", ErrInvalidChain, block.Index, tx.TXID, err)
+		}
+		if tx.Fee > math.MaxInt64-fees {
+			return fmt.Errorf("%w: block %d: fees overflow", ErrInvalidChain, block.Index)
+		}
+		fees += tx.Fee
+	}
+
+	coinbase := block.Transactions[0]
+	allowed := bc.blockRewardLocked(block.Index)
+	if fees > math.MaxInt64-allowed {
+		allowed = math.MaxInt64
+	} else {
+		allowed += fees
+	}
+
+	share, err := bc.treasuryShareLocked(coinbase.Amount)
+	if err != nil {
+		return fmt.Errorf("%w: block %d: %w", ErrInvalidChain, block.Index, err)
+	}
+
+	paid, treasury := int64(0), int64(0)
+	for _, out := range coinbase.outputs() {
+		if out.Amount < 0 || out.Amount > math.MaxInt64-paid {
+			return fmt.Errorf("%w: block %d: coinbase output amounts are invalid", ErrInvalidChain, block.Index)
+		}
+		paid += out.Amount
+		if out.Address == bc.TreasuryAddress {
+			treasury += out.Amount
+		}
+	}
+
+	switch {
+	case len(coinbase.Inputs) > 0:
+		return fmt.Errorf("%w: block %d: coinbase spends inputs", ErrInvalidChain, block.Index)
+	case coinbase.Nonce != uint64(block.Index):
+		return fmt.Errorf("%w: block %d: coinbase nonce %d is not the block index", ErrInvalidChain, block.Index, coinbase.Nonce)
+	case paid != coinbase.Amount:
+		return fmt.Errorf("%w: block %d: coinbase outputs pay %s, not its amount %s", ErrInvalidChain, block.Index, formatAmount(paid), formatAmount(coinbase.Amount))
+	case coinbase.Amount > allowed:
+		return fmt.Errorf("%w: block %d: coinbase of %s exceeds the reward plus fees of %s", ErrInvalidChain, block.Index,
+			formatAmount(coinbase.Amount), formatAmount(allowed))
+	case treasury < share:
+		return fmt.Errorf("%w: block %d: coinbase pays %s to the treasury, not its share of %s", ErrInvalidChain, block.Index,
+			formatAmount(treasury), formatAmount(share))
+	}
+
+	return nil
+}
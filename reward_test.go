@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBlockRewardHalving(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval int
+		index    int
+		want     int64
+	}{
+		{"no halving", 0, 1000, 5000},
+		{"first block", 10, 1, 5000},
+		{"last block before halving", 10, 10, 5000},
+		{"first halving", 10, 11, 2500},
+		{"second halving", 10, 21, 1250},
+		{"exhausted", 1, maxHalvings + 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := &Blockchain{BlockReward: 5000, HalvingInterval: tt.interval}
+			if got := bc.blockRewardLocked(tt.index); got != tt.want {
+				t.Errorf("blockRewardLocked(%d) = %d, want %d", tt.index, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHalvedRewardIsEnforced(t *testing.T) {
+	bc := newTestBlockchain(t, nil)
+	bc.HalvingInterval = 1
+	mineTestBlock(t, bc, "miner")
+
+	halved := bc.BlockReward / 2
+	if mined := mineTestBlock(t, bc, "miner"); mined.Transactions[0].Amount != halved {
+		t.Fatalf("mined coinbase of %d units, want %d", mined.Transactions[0].Amount, halved)
+	}
+
+	block := sealTestBlock(t, bc, []Transaction{testCoinbase(bc, "mallory", halved+1)})
+	if err := bc.SubmitMinedBlock(block); !errors.Is(err, ErrInvalidChain) {
+		t.Fatalf("SubmitMinedBlock() error = %v, want ErrInvalidChain", err)
+	}
+	if balance := bc.GetBalance("mallory"); balance != 0 {
+		t.Errorf("mallory has %d units, want 0", balance)
+	}
+}
+
+func TestTreasuryShareIsEnforced(t *testing.T) {
+	tests := []struct {
+		name    string
+		outputs func(reward int64) []TXOutput
+		wantErr bool
+	}{
+		{"full share", func(reward int64) []TXOutput {
+			return []TXOutput{{Address: "miner", Amount: reward - reward/10}, {Address: "treasury", Amount: reward / 10}}
+		}, false},
+		{"more than the share", func(reward int64) []TXOutput {
+			return []TXOutput{{Address: "treasury", Amount: reward}}
+		}, false},
+		{"missing treasury output", func(reward int64) []TXOutput {
+			return []TXOutput{{Address: "miner", Amount: reward}}
+		}, true},
+		{"short treasury output", func(reward int64) []TXOutput {
+			return []TXOutput{{Address: "miner", Amount: reward - reward/10 + 1}, {Address: "treasury", Amount: reward/10 - 1}}
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := newTestBlockchain(t, nil)
+			bc.TreasuryAddress, bc.TreasuryPercent = "treasury", 10
+
+			coinbase := testCoinbase(bc, "miner", bc.BlockReward)
+			coinbase.Outputs = tt.outputs(bc.BlockReward)
+			coinbase.Recipient = coinbase.Outputs[0].Address
+			coinbase.TXID = generateTransactionID(bc.hasher(), coinbase)
+
+			err := bc.SubmitMinedBlock(sealTestBlock(t, bc, []Transaction{coinbase}))
+			if tt.wantErr && !errors.Is(err, ErrInvalidChain) {
+				t.Fatalf("SubmitMinedBlock() error = %v, want ErrInvalidChain", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("SubmitMinedBlock() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestMinedTreasurySplitIsValid(t *testing.T) {
+	bc := newTestBlockchain(t, nil)
+	bc.TreasuryAddress, bc.TreasuryPercent = "treasury", 12.5
+	mineTestBlock(t, bc, "miner")
+	mineTestBlock(t, bc, "treasury")
+
+	if valid, err := bc.IsChainValid(); !valid {
+		t.Fatalf("chain with mined treasury splits is invalid: %v", err)
+	}
+	if want := bc.BlockReward + bc.BlockReward/8; bc.GetBalance("treasury") != want {
+		t.Errorf("treasury has %d units, want %d", bc.GetBalance("treasury"), want)
+	}
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"blockchain/metrics"
+)
+
+// defaultBlocksPageLimit is the number of blocks GET /blocks returns without a limit query parameter
+const defaultBlocksPageLimit = 10
+
+// maxBlocksPageLimit is the largest limit accepted by GET /blocks
+const maxBlocksPageLimit = 100
+
+// maxTransactionRequestSize is the largest request body accepted by POST /transactions
+const maxTransactionRequestSize = 1 << 20
+
+// maxTransactionBatchSize is the largest number of transactions accepted by POST /transactions/batch
+const maxTransactionBatchSize = 1000
+
+// maxTransactionBatchRequestSize is the largest request body accepted by POST /transactions/batch
+const maxTransactionBatchRequestSize = 16 << 20
+
+// transactionRequest is the JSON body accepted by POST /transactions; amount and fee are integers of base units
+// like every amount of the API, fee, nonce, memo, expiry_time, signature and public key are optional, signature and public key are base64 encoded.
+// The required fields are pointers so a missing field can be told apart from a zero value
+type transactionRequest struct {
+	Sender     *string `json:"sender"`
+	Recipient  *string `json:"recipient"`
+	Amount     *int64  `json:"amount"`
+	Fee        int64   `json:"fee,omitempty"`
+	Nonce      uint64  `json:"nonce,omitempty"`
+	Memo       string  `json:"memo,omitempty"`
+	ExpiryTime int64   `json:"expiry_time,omitempty"`
+	Signature  []byte  `json:"signature,omitempty"`
+	PublicKey  []byte  `json:"public_key,omitempty"`
+}
+
+// ParseTransactionRequest strictly decodes a single transactionRequest JSON object from r and returns
+// its sender, recipient and amount in base units. The body must not contain unknown fields or trailing data, sender,
+// recipient and amount are required and every field must have the right JSON type; the values must then
+// pass the field checks of the mempool (non-blank distinct addresses, positive amount, fee not negative).
+// Every failure is reported as an error wrapping ErrInvalidTransaction which names the offending field
+func ParseTransactionRequest(r io.Reader) (sender, recipient string, amount int64, err error) {
+	tx, err := parseTransactionRequest(r)
+	if err != nil {
+		return "", "", 0, err
+	}
+	return tx.Sender, tx.Recipient, tx.Amount, nil
+}
+
+// parseTransactionRequest is ParseTransactionRequest returning the whole requested transaction,
+// including the optional fields; its TXID is not computed
+func parseTransactionRequest(r io.Reader) (Transaction, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	var req transactionRequest
+	if err := dec.Decode(&req); err != nil {
+		return Transaction{}, fmt.Errorf("%w: %s", ErrInvalidTransaction, describeDecodeError(err))
+	}
+	if dec.More() {
+		return Transaction{}, fmt.Errorf("%w: request body must contain a single JSON object", ErrInvalidTransaction)
+	}
+
+	switch {
+	case req.Sender == nil:
+		return Transaction{}, fmt.Errorf("%w: missing field \"sender\"", ErrInvalidTransaction)
+	case req.Recipient == nil:
+		return Transaction{}, fmt.Errorf("%w: missing field \"recipient\"", ErrInvalidTransaction)
+	case req.Amount == nil:
+		return Transaction{}, fmt.Errorf("%w: missing field \"amount\"", ErrInvalidTransaction)
+	}
+
+	tx := Transaction{
+		Sender:     *req.Sender,
+		Recipient:  *req.Recipient,
+		Amount:     *req.Amount,
+		Fee:        req.Fee,
+		Nonce:      req.Nonce,
+		Memo:       req.Memo,
+		ExpiryTime: req.ExpiryTime,
+		Signature:  req.Signature,
+		PublicKey:  req.PublicKey,
+	}
+	if err := validateTransactionFields(tx); err != nil {
+		return Transaction{}, err
+	}
+
+	return tx, nil
+}
+
+// parseTransactionBatchRequest decodes a JSON array of transactionRequest objects from r, parsing every element
+// like parseTransactionRequest. A malformed array or one longer than maxTransactionBatchSize is an error wrapping
+// ErrInvalidTransaction; otherwise it returns one transaction and one error, exactly one of them set, per element
+func parseTransactionBatchRequest(r io.Reader) ([]Transaction, []error, error) {
+	dec := json.NewDecoder(r)
+
+	var elements []json.RawMessage
+	if err := dec.Decode(&elements); err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalidTransaction, describeDecodeError(err))
+	}
+	if dec.More() {
+		return nil, nil, fmt.Errorf("%w: request body must contain a single JSON array", ErrInvalidTransaction)
+	}
+	if len(elements) == 0 {
+		return nil, nil, fmt.Errorf("%w: batch contains no transactions", ErrInvalidTransaction)
+	}
+	if len(elements) > maxTransactionBatchSize {
+		return nil, nil, fmt.Errorf("%w: batch of %d transactions exceeds the limit of %d", ErrInvalidTransaction, len(elements), maxTransactionBatchSize)
+	}
+
+	txs := make([]Transaction, len(elements))
+	errs := make([]error, len(elements))
+	for i, element := range elements {
+		txs[i], errs[i] = parseTransactionRequest(bytes.NewReader(element))
+	}
+
+	return txs, errs, nil
+}
+
+// jsonTypeName names the JSON type a Go type of transactionRequest is decoded from
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Int64:
+		return "whole number"
+	case reflect.Uint, reflect.Uint64:
+		return "non-negative integer"
+	case reflect.Slice:
+		return "base64 string"
+	}
+	return t.String()
+}
+
+// describeDecodeError turns an error of json.Decoder.Decode into a message naming the failure mode
+func describeDecodeError(err error) string {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, io.EOF):
+		return "request body is empty"
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return "request body is truncated JSON"
+	case errors.As(err, &syntaxErr):
+		return fmt.Sprintf("malformed JSON at byte %d: %v", syntaxErr.Offset, err)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return fmt.Sprintf("field %q must be a %s, got %s", typeErr.Field, jsonTypeName(typeErr.Type), typeErr.Value)
+	case errors.As(err, &typeErr):
+		return fmt.Sprintf("request body must be a JSON object, got %s", typeErr.Value)
+	case errors.As(err, &maxBytesErr):
+		return fmt.Sprintf("request body exceeds %d bytes", maxBytesErr.Limit)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return "unknown field " + strings.TrimPrefix(err.Error(), "json: unknown field ")
+	}
+
+	return err.Error()
+}
+
+// StartServer serves the HTTP API of the blockchain on addr:
+//
+//	POST /transactions          submit a transaction, returns its TXID
+//	POST /transactions/batch    submit an array of transactions, returns the TXID or error of each
+//	GET  /chain                 return the full chain
+//	GET  /blocks?offset=&limit= return a page of blocks, most recent first, and the total count
+//	POST /mine?miner=<address>  mine the mempool into a new block, returns the block
+//	GET  /balance?address=      return the balance of an address
+//	GET  /stats                 return height, difficulty, supply, mempool size, block time and hash rate
+//	GET  /ws                    stream every new block as a JSON WebSocket message
+//	GET  /metrics               Prometheus metrics of mining and the mempool
+//
+// It blocks until the server fails and returns the error
+func StartServer(bc *Blockchain, addr string) error {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           newRouter(bc),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return server.ListenAndServe()
+}
+
+// newRouter registers the API handlers of the blockchain on a new mux
+func newRouter(bc *Blockchain) *http.ServeMux {
+	m := metrics.NewNode(bc)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /transactions", handleAddTransaction(bc))
+	mux.HandleFunc("POST /transactions/batch", handleAddTransactions(bc))
+	mux.HandleFunc("GET /chain", handleGetChain(bc))
+	mux.HandleFunc("GET /blocks", handleGetBlocks(bc))
+	mux.HandleFunc("POST /mine", handleMine(bc, m))
+	mux.HandleFunc("GET /balance", handleGetBalance(bc))
+	mux.HandleFunc("GET /stats", handleGetStats(bc))
+	mux.HandleFunc("GET /ws", handleWebSocket(newBlockFeed(bc)))
+	mux.Handle("GET /metrics", m.Handler())
+
+	return mux
+}
+
+// handleAddTransaction decodes a transaction from the request body and adds it to the mempool
+func handleAddTransaction(bc *Blockchain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tx, err := parseTransactionRequest(http.MaxBytesReader(w, r.Body, maxTransactionRequestSize))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		txid, err := bc.submitTransaction(tx)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, map[string]string{"txid": txid})
+	}
+}
+
+// batchResult is the outcome of one transaction of POST /transactions/batch, its TXID or why it was rejected
+type batchResult struct {
+	TXID  string `json:"txid,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// handleAddTransactions submits a batch of transactions, returning one result per transaction in request order
+// and the number of accepted and rejected ones.
+// The rejection of single transactions does not fail the request; only a malformed or oversized batch is a 400
+func handleAddTransactions(bc *Blockchain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		txs, parseErrs, err := parseTransactionBatchRequest(http.MaxBytesReader(w, r.Body, maxTransactionBatchRequestSize))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		results := make([]batchResult, len(txs))
+		valid := make([]Transaction, 0, len(txs))
+		positions := make([]int, 0, len(txs)) // index in txs of every valid transaction
+		for i, tx := range txs {
+			if parseErrs[i] != nil {
+				results[i].Error = parseErrs[i].Error()
+				continue
+			}
+			valid = append(valid, tx)
+			positions = append(positions, i)
+		}
+
+		txids, submitErrs := bc.AddTransactions(valid)
+		accepted := 0
+		for j, i := range positions {
+			if submitErrs[j] != nil {
+				results[i].Error = submitErrs[j].Error()
+				continue
+			}
+			results[i].TXID = txids[j]
+			accepted++
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"results":  results,
+			"accepted": accepted,
+			"rejected": len(txs) - accepted,
+		})
+	}
+}
+
+// handleGetChain returns every block of the chain
+func handleGetChain(bc *Blockchain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, bc.GetChain())
+	}
+}
+
+// handleGetBlocks returns a page of blocks starting offset blocks below the tip, at most limit blocks long.
+// offset defaults to 0, the most recent block, and limit to defaultBlocksPageLimit
+func handleGetBlocks(bc *Blockchain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		offset, err := intQueryParam(r, "offset", 0)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if offset < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("offset must not be negative, got %d", offset))
+			return
+		}
+
+		limit, err := intQueryParam(r, "limit", defaultBlocksPageLimit)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if limit < 1 || limit > maxBlocksPageLimit {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("limit must be between 1 and %d, got %d", maxBlocksPageLimit, limit))
+			return
+		}
+
+		blocks, total, err := bc.GetBlocksPage(offset, limit)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"blocks": blocks,
+			"total":  total,
+			"offset": offset,
+			"limit":  limit,
+		})
+	}
+}
+
+// handleMine mines a new block paying the reward to the address given in the miner query parameter
+// and records it in m; mining is aborted when the client disconnects
+func handleMine(bc *Blockchain, m *metrics.Node) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		miner := r.URL.Query().Get("miner")
+		if miner == "" {
+			writeError(w, http.StatusBadRequest, errors.New("missing miner query parameter"))
+			return
+		}
+
+		start := time.Now()
+		block, err := bc.MineBlockCtx(r.Context(), miner)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		m.ObserveMined(time.Since(start))
+
+		writeJSON(w, http.StatusCreated, block)
+	}
+}
+
+// handleGetBalance returns the balance of the address given in the address query parameter
+func handleGetBalance(bc *Blockchain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		address := r.URL.Query().Get("address")
+		if address == "" {
+			writeError(w, http.StatusBadRequest, errors.New("missing address query parameter"))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"address": address,
+			"balance": bc.GetBalance(address),
+		})
+	}
+}
+
+// handleGetStats returns the Stats snapshot of the chain
+func handleGetStats(bc *Blockchain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, bc.Stats())
+	}
+}
+
+// intQueryParam parses the integer query parameter name, returning fallback if it is absent
+func intQueryParam(r *http.Request, name string, fallback int) (int, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return fallback, nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s query parameter %q", name, raw)
+	}
+
+	return value, nil
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error as a JSON response of the form {"error": "..."} with the given status code
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// postJSON sends body to path on a test server of bc and returns the response status
+func postJSON(t *testing.T, bc *Blockchain, path, body string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	newRouter(bc).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+	return rec.Code, rec.Body.String()
+}
+
+func TestAddTransactionRejectsCoinbaseSender(t *testing.T) {
+	bc := newTestBlockchain(t, nil)
+
+	status, body := postJSON(t, bc, "/transactions", `{"sender": "COINBASE", "recipient": "mallory", "amount": 1000000000000000}`)
+	if status != http.StatusBadRequest {
+		t.Fatalf("POST /transactions = %d %s, want 400", status, body)
+	}
+
+	status, body = postJSON(t, bc, "/transactions/batch", `[{"sender": "COINBASE", "recipient": "mallory", "amount": 1}]`)
+	if status != http.StatusOK || !strings.Contains(body, `"rejected":1`) {
+		t.Fatalf("POST /transactions/batch = %d %s, want the transaction rejected", status, body)
+	}
+	if pending := len(bc.Mempool()); pending != 0 {
+		t.Errorf("mempool holds %d transactions, want 0", pending)
+	}
+}
+
+func TestMetricsCountMinedBlocks(t *testing.T) {
+	bc := newTestBlockchain(t, nil)
+	router := newRouter(bc)
+
+	for range 2 {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/mine?miner=miner", nil))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("POST /mine = %d %s", rec.Code, rec.Body.String())
+		}
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	for _, want := range []string{
+		"blockchain_blocks_mined_total 2\n",
+		"blockchain_mining_duration_seconds_count 2\n",
+		"blockchain_mempool_transactions 0\n",
+		"# TYPE blockchain_difficulty_bits gauge\n",
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("GET /metrics is missing %q:\n%s", want, rec.Body.String())
+		}
+	}
+}
+
+func TestRouterEndpoints(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"add transaction", http.MethodPost, "/transactions", `{"sender": "alice", "recipient": "bob", "amount": 10}`, http.StatusCreated, `"txid":`},
+		{"add malformed transaction", http.MethodPost, "/transactions", `{"sender": "alice",`, http.StatusBadRequest, `"error":`},
+		{"add unfunded transaction", http.MethodPost, "/transactions", `{"sender": "bob", "recipient": "alice", "amount": 10}`, http.StatusBadRequest, "insufficient"},
+		{"add batch", http.MethodPost, "/transactions/batch", `[{"sender": "alice", "recipient": "bob", "amount": 10}, {"sender": "bob", "recipient": "alice", "amount": 10}]`, http.StatusOK, `"accepted":1`},
+		{"add malformed batch", http.MethodPost, "/transactions/batch", `{}`, http.StatusBadRequest, `"error":`},
+		{"get chain", http.MethodGet, "/chain", "", http.StatusOK, `"index":0`},
+		{"get blocks", http.MethodGet, "/blocks?limit=1", "", http.StatusOK, `"total":1`},
+		{"get blocks with invalid limit", http.MethodGet, "/blocks?limit=0", "", http.StatusBadRequest, "limit must be between"},
+		{"mine", http.MethodPost, "/mine?miner=miner", "", http.StatusCreated, `"index":1`},
+		{"mine without miner", http.MethodPost, "/mine", "", http.StatusBadRequest, "missing miner"},
+		{"get balance", http.MethodGet, "/balance?address=alice", "", http.StatusOK, `"balance":1000`},
+		{"get balance without address", http.MethodGet, "/balance", "", http.StatusBadRequest, "missing address"},
+		{"get stats", http.MethodGet, "/stats", "", http.StatusOK, `"height":0`},
+		{"get metrics", http.MethodGet, "/metrics", "", http.StatusOK, "blockchain_blocks_mined_total 0"},
+		{"wrong method", http.MethodGet, "/mine", "", http.StatusMethodNotAllowed, ""},
+		{"unknown path", http.MethodGet, "/unknown", "", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(newRouter(newTestBlockchain(t, map[string]int64{"alice": 1000})))
+			defer server.Close()
+
+			req, err := http.NewRequest(tt.method, server.URL+tt.target, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("NewRequest(): %v", err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("%s %s: %v", tt.method, tt.target, err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(string(body), tt.wantBody) {
+				t.Errorf("%s %s = %d %s, want %d containing %q", tt.method, tt.target, resp.StatusCode, body, tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestGetBlocksPaging(t *testing.T) {
+	bc := newTestBlockchain(t, nil)
+	for range 12 {
+		mineTestBlock(t, bc, "miner")
+	}
+	router := newRouter(bc)
+
+	tests := []struct {
+		name        string
+		target      string
+		wantStatus  int
+		wantIndices []int
+	}{
+		{"default paging", "/blocks", http.StatusOK, []int{12, 11, 10, 9, 8, 7, 6, 5, 4, 3}},
+		{"offset and limit", "/blocks?offset=10&limit=5", http.StatusOK, []int{2, 1, 0}},
+		{"out of range offset", "/blocks?offset=13", http.StatusOK, []int{}},
+		{"negative offset", "/blocks?offset=-1", http.StatusBadRequest, nil},
+		{"over limit", fmt.Sprintf("/blocks?limit=%d", maxBlocksPageLimit+1), http.StatusBadRequest, nil},
+		{"malformed limit", "/blocks?limit=ten", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("GET %s = %d %s, want %d", tt.target, rec.Code, rec.Body.String(), tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var page struct {
+				Blocks []Block `json:"blocks"`
+				Total  int     `json:"total"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+				t.Fatalf("decode page: %v", err)
+			}
+			indices := []int{}
+			for _, block := range page.Blocks {
+				indices = append(indices, block.Index)
+			}
+			if !reflect.DeepEqual(indices, tt.wantIndices) || page.Total != 13 {
+				t.Errorf("GET %s = blocks %v of %d, want %v of 13", tt.target, indices, page.Total, tt.wantIndices)
+			}
+		})
+	}
+}
+
+func TestParseTransactionRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		// wantErr is a substring of the error message, empty for a valid request
+		wantErr string
+	}{
+		{"valid", `{"sender":"alice","recipient":"bob","amount":10}`, ""},
+		{"valid with optional fields", `{"sender":"alice","recipient":"bob","amount":10,"fee":1,"memo":"rent"}`, ""},
+		{"empty body", ``, "request body is empty"},
+		{"truncated", `{"sender":"alice"`, "truncated JSON"},
+		{"malformed", `{"sender":alice}`, "malformed JSON"},
+		{"not an object", `[1, 2]`, "must be a JSON object"},
+		{"two objects", `{"sender":"alice","recipient":"bob","amount":10} {}`, "single JSON object"},
+		{"missing sender", `{"recipient":"bob","amount":10}`, `missing field "sender"`},
+		{"missing recipient", `{"sender":"alice","amount":10}`, `missing field "recipient"`},
+		{"missing amount", `{"sender":"alice","recipient":"bob"}`, `missing field "amount"`},
+		{"amount as string", `{"sender":"alice","recipient":"bob","amount":"10"}`, `field "amount" must be a`},
+		{"sender as number", `{"sender":1,"recipient":"bob","amount":10}`, `field "sender" must be a`},
+		{"fractional amount", `{"sender":"alice","recipient":"bob","amount":1.5}`, `field "amount" must be a`},
+		{"extra field", `{"sender":"alice","recipient":"bob","amount":10,"admin":true}`, `unknown field "admin"`},
+		{"negative amount", `{"sender":"alice","recipient":"bob","amount":-5}`, "amount must be positive"},
+		{"zero amount", `{"sender":"alice","recipient":"bob","amount":0}`, "amount must be positive"},
+		{"blank sender", `{"sender":" ","recipient":"bob","amount":10}`, "sender must not be blank"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender, recipient, amount, err := ParseTransactionRequest(strings.NewReader(tt.body))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ParseTransactionRequest(): %v", err)
+				}
+				if sender != "alice" || recipient != "bob" || amount != 10 {
+					t.Errorf("ParseTransactionRequest() = %q, %q, %d, want alice, bob, 10", sender, recipient, amount)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidTransaction) {
+				t.Fatalf("error = %v, want ErrInvalidTransaction", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
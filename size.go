@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Size returns the length in bytes of the canonical binary encoding of the block (see serialize), the measure
+// MaxBlockSize limits. Unlike the JSON encoding it does not depend on field names or formatting, so it is stable
+// across versions and grows exactly with the data a block carries
+func (b Block) Size() int {
+	return len(b.serialize())
+}
+
+// serialize encodes the block in its canonical binary form: the header as encoded by serializeForHash followed by
+// its length-prefixed hash, then the number of transactions as a 4 byte big-endian integer and every transaction
+// as encoded by Transaction.serialize. A pruned block is encoded without transactions
+func (b Block) serialize() []byte {
+	data := appendLengthPrefixed(b.serializeForHash(), b.Hash)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(b.Transactions)))
+	for _, tx := range b.Transactions {
+		data = append(data, tx.serialize()...)
+	}
+	return data
+}
+
+// serialize encodes the transaction in its canonical binary form: the fields covered by the TXID
+// (see serializeForID) followed by the TXID, the signature and the public key, each length-prefixed
+func (tx Transaction) serialize() []byte {
+	data := appendLengthPrefixed(tx.serializeForID(), tx.TXID)
+	data = appendLengthPrefixed(data, string(tx.Signature))
+	return appendLengthPrefixed(data, string(tx.PublicKey))
+}
+
+// sizeWithoutCoinbase returns the size of the block minus the encoding of its coinbase transaction, if any,
+// which MaxBlockSize does not count
+func (b Block) sizeWithoutCoinbase() int {
+	size := b.Size()
+	if len(b.Transactions) > 0 && b.Transactions[0].Sender == coinbaseSender {
+		size -= len(b.Transactions[0].serialize())
+	}
+	return size
+}
+
+// deserializeBlock decodes a block from the canonical binary form written by serialize. The encoding does not
+// carry the Pruned flag, so a block decodes with Pruned unset, and empty signatures, public keys, inputs and outputs
+// decode as nil. Returns ErrInvalidBlock if data is truncated, has trailing bytes or holds an amount which is not
+// in the canonical form of formatAmount, so every decoded block serializes back to data
+func deserializeBlock(data []byte) (Block, error) {
+	d := decoder{data: data}
+	block := Block{BlockHeader: BlockHeader{
+		Index:      int(d.uint64()),
+		Timestamp:  int64(d.uint64()),
+		Nonce:      d.uint64(),
+		Difficulty: int(d.uint64()),
+	}}
+	block.PreviousHash = d.string()
+	block.MerkleRoot = d.string()
+	block.Hash = d.string()
+
+	count := d.uint32()
+	block.Transactions = []Transaction{}
+	for i := uint32(0); i < count && d.err == nil; i++ {
+		block.Transactions = append(block.Transactions, d.transaction())
+	}
+
+	if d.err == nil && len(d.data) > 0 {
+		d.err = fmt.Errorf("%d trailing bytes", len(d.data))
+	}
+	if d.err != nil {
+		return Block{}, fmt.Errorf("%w: decode: %w", ErrInvalidBlock, d.err)
+	}
+	return block, nil
+}
+
+// decoder reads the fields of the canonical binary encoding from data, keeping the first error;
+// once it is set every further read returns a zero value
+type decoder struct {
+	data []byte
+	err  error
+}
+
+// next consumes n bytes, or returns nil and sets the error if fewer are left
+func (d *decoder) next(n uint64) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if uint64(len(d.data)) < n {
+		d.err = fmt.Errorf("need %d bytes, %d left", n, len(d.data))
+		return nil
+	}
+
+	field := d.data[:n]
+	d.data = d.data[n:]
+	return field
+}
+
+func (d *decoder) uint32() uint32 {
+	if field := d.next(4); field != nil {
+		return binary.BigEndian.Uint32(field)
+	}
+	return 0
+}
+
+func (d *decoder) uint64() uint64 {
+	if field := d.next(8); field != nil {
+		return binary.BigEndian.Uint64(field)
+	}
+	return 0
+}
+
+// string reads a field written by appendLengthPrefixed
+func (d *decoder) string() string {
+	return string(d.next(uint64(d.uint32())))
+}
+
+// bytes is string for byte slices, returning nil for an empty field
+func (d *decoder) bytes() []byte {
+	if field := d.next(uint64(d.uint32())); len(field) > 0 {
+		return append([]byte{}, field...)
+	}
+	return nil
+}
+
+// amount reads an amount written by formatAmount
+func (d *decoder) amount() int64 {
+	field := d.string()
+	if d.err != nil {
+		return 0
+	}
+
+	units, err := parseFormattedAmount(field)
+	if err != nil {
+		d.err = err
+	}
+	return units
+}
+
+// transaction reads a transaction written by Transaction.serialize
+func (d *decoder) transaction() Transaction {
+	tx := Transaction{Sender: d.string(), Recipient: d.string()}
+	tx.Amount = d.amount()
+	tx.Fee = d.amount()
+	tx.Nonce = d.uint64()
+	tx.Memo = d.string()
+	tx.ExpiryTime = int64(d.uint64())
+
+	for i, count := uint32(0), d.uint32(); i < count && d.err == nil; i++ {
+		in := TXInput{TXID: d.string()}
+		in.Output = int(d.uint64())
+		tx.Inputs = append(tx.Inputs, in)
+	}
+	for i, count := uint32(0), d.uint32(); i < count && d.err == nil; i++ {
+		out := TXOutput{Address: d.string()}
+		out.Amount = d.amount()
+		tx.Outputs = append(tx.Outputs, out)
+	}
+
+	tx.TXID = d.string()
+	tx.Signature = d.bytes()
+	tx.PublicKey = d.bytes()
+	return tx
+}
+
+// parseFormattedAmount is the inverse of formatAmount; it accepts the canonical form only
+func parseFormattedAmount(s string) (int64, error) {
+	whole, fraction, _ := strings.Cut(strings.TrimPrefix(s, "-"), ".")
+	wholeUnits, wholeErr := strconv.ParseUint(whole, 10, 64)
+	fractionUnits, fractionErr := strconv.ParseUint(fraction, 10, 64)
+
+	// a magnitude beyond int64 wraps around and fails the comparison like every other non-canonical form
+	units := int64(wholeUnits*uint64(UnitsPerCoin) + fractionUnits)
+	if strings.HasPrefix(s, "-") {
+		units = -units
+	}
+	if wholeErr != nil || fractionErr != nil || formatAmount(units) != s {
+		return 0, fmt.Errorf("amount %q is not in canonical form", s)
+	}
+	return units, nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParseFormattedAmount(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"0.00000000", 0, false},
+		{"0.30000000", 30_000_000, false},
+		{"-1.00000001", -100_000_001, false},
+		{formatAmount(math.MaxInt64), math.MaxInt64, false},
+		{formatAmount(math.MinInt64), math.MinInt64, false},
+		{"-0.00000000", 0, true},
+		{"00.30000000", 0, true},
+		{"0.3", 0, true},
+		{"+0.30000000", 0, true},
+		{"92233720368.54775808", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseFormattedAmount(tt.in)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("parseFormattedAmount(%q) = %d, %v, want %d, error: %v", tt.in, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeserializeBlock(t *testing.T) {
+	bc := newTestBlockchain(t, map[string]int64{"alice": 100})
+	bc.EnableUTXO()
+	if _, err := bc.submitTransaction(Transaction{Sender: "alice", Recipient: "bob", Amount: 30, Fee: 1, Memo: "rent"}); err != nil {
+		t.Fatalf("submitTransaction(): %v", err)
+	}
+	mined := mineTestBlock(t, bc, "miner")
+	signed, _ := signedTestTransaction(t, 10)
+	mined.Transactions = append(mined.Transactions, signed)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    Block
+		wantErr bool
+	}{
+		{"genesis", bc.Chain[0].serialize(), bc.Chain[0], false},
+		{"with inputs, outputs and signature", mined.serialize(), mined, false},
+		{"no transactions", Block{}.serialize(), Block{Transactions: []Transaction{}}, false},
+		{"truncated", mined.serialize()[:mined.Size()-1], Block{}, true},
+		{"trailing bytes", append(mined.serialize(), 0), Block{}, true},
+		{"non-canonical amount", bytes.Replace(mined.serialize(), []byte("0.00000030"), []byte("0.0000003+"), 1), Block{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := deserializeBlock(tt.data)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidBlock) {
+					t.Fatalf("deserializeBlock() error = %v, want ErrInvalidBlock", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("deserializeBlock(): %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("deserializeBlock() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// FuzzBlockRoundTrip encodes arbitrary blocks of one transaction with serialize and checks that deserializeBlock
+// restores them exactly, that their hashes and TXIDs are stable and that the decoded block encodes to the same bytes.
+// Run it with go test -run '^$' -fuzz FuzzBlockRoundTrip
+func FuzzBlockRoundTrip(f *testing.F) {
+	f.Add(0, int64(defaultGenesisTimestamp), uint64(0), 1, "0", "", "COINBASE", "alice", int64(100), int64(0), uint64(0), "", int64(0), []byte(nil), 0)
+	f.Add(7, int64(-1), uint64(math.MaxUint64), 256, "prev", "root", "alice", "bob", int64(math.MinInt64), int64(math.MaxInt64), uint64(3), "\x80memo", int64(-5), []byte{1, 2}, 2)
+
+	f.Fuzz(func(t *testing.T, index int, timestamp int64, nonce uint64, difficulty int, previousHash, merkleRoot,
+		sender, recipient string, amount, fee int64, txNonce uint64, memo string, expiry int64, signature []byte, outputs int) {
+		tx := Transaction{Sender: sender, Recipient: recipient, Amount: amount, Fee: fee, Nonce: txNonce, Memo: memo,
+			ExpiryTime: expiry, Signature: signature}
+		for i := range outputs % 4 {
+			tx.Inputs = append(tx.Inputs, TXInput{TXID: memo, Output: i - 1})
+			tx.Outputs = append(tx.Outputs, TXOutput{Address: recipient, Amount: amount - int64(i)})
+		}
+		tx.TXID = generateTransactionID(SHA256Hasher{}, tx)
+		if len(tx.Signature) == 0 {
+			tx.Signature = nil
+		}
+
+		block := Block{
+			BlockHeader: BlockHeader{Index: index, Timestamp: timestamp, Nonce: nonce, Difficulty: difficulty,
+				PreviousHash: previousHash, MerkleRoot: merkleRoot},
+			Transactions: []Transaction{tx},
+		}
+		block.Hash = calculateHash(SHA256Hasher{}, block.BlockHeader)
+
+		data := block.serialize()
+		decoded, err := deserializeBlock(data)
+		if err != nil {
+			t.Fatalf("deserializeBlock(): %v", err)
+		}
+		if !reflect.DeepEqual(decoded, block) {
+			t.Fatalf("deserializeBlock() = %+v, want %+v", decoded, block)
+		}
+		if calculateHash(SHA256Hasher{}, decoded.BlockHeader) != block.Hash {
+			t.Errorf("hash of the decoded block changed")
+		}
+		if generateTransactionID(SHA256Hasher{}, decoded.Transactions[0]) != tx.TXID {
+			t.Errorf("TXID of the decoded transaction changed")
+		}
+		if !bytes.Equal(decoded.serialize(), data) {
+			t.Errorf("decoded block encodes to other bytes")
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"time"
+)
+
+// snapshotVersion is the version of the format written by Snapshot. It is raised whenever a field is added
+// whose absence would change the restored state, so an older Restore rejects a snapshot it cannot reproduce
+const snapshotVersion = 1
+
+// nodeSnapshot is the JSON representation of a node written by Snapshot: everything SaveToFile persists
+// plus the state otherwise derived on load, the nonce counters, the UTXO set and the mempool receive times
+type nodeSnapshot struct {
+	Version int `json:"version"`
+	blockchainFile
+	Nonces   map[string]uint64    `json:"nonces"`
+	UTXO     map[string]TXOutput  `json:"utxo_set"`
+	Received map[string]time.Time `json:"received"`
+}
+
+// Snapshot serializes the complete state of the node, the chain, the mempool, the difficulty and every setting
+// together with the nonce counters, the UTXO set and the time each pending transaction was received, so Restore
+// recreates the node exactly, including the order in which PruneMempool expires transactions.
+// Callbacks and peers are not part of the snapshot. The hasher and proof-of-work algorithm must be built-in ones
+func (bc *Blockchain) Snapshot() ([]byte, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	snapshot := nodeSnapshot{
+		Version:        snapshotVersion,
+		blockchainFile: bc.fileLocked(),
+		Nonces:         bc.nonces,
+		UTXO:           bc.utxo,
+		Received:       bc.received,
+	}
+	if snapshot.Hasher == "" {
+		return nil, fmt.Errorf("snapshot: hasher %T is not a built-in hasher", bc.Hasher)
+	}
+	if snapshot.PoW == "" {
+		return nil, fmt.Errorf("snapshot: proof-of-work algorithm %T is not a built-in algorithm", bc.PoW)
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("marshal snapshot: %w", err)
+	}
+	return data, nil
+}
+
+// Restore recreates a node from a snapshot written by Snapshot. The chain is validated with IsChainValid
+// like LoadFromFile, and the nonce counters and UTXO set of the snapshot must match the ones derived from
+// the chain and the mempool, so a damaged or edited snapshot is rejected instead of restoring diverging state.
+// The mempool is then checked against the chain like transactions passed to ReinjectMempool; pending transactions
+// which cannot be added anymore are dropped, a snapshot taken of a consistent node keeps all of them.
+// Returns ErrInvalidSnapshot if the version is not supported or the state does not match
+func Restore(data []byte) (*Blockchain, error) {
+	var snapshot nodeSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("unmarshal snapshot: %w", err)
+	}
+
+	if snapshot.Version < 1 || snapshot.Version > snapshotVersion {
+		return nil, fmt.Errorf("%w: version %d is not supported, expected at most %d", ErrInvalidSnapshot, snapshot.Version, snapshotVersion)
+	}
+	if len(snapshot.Chain) == 0 {
+		return nil, fmt.Errorf("%w: snapshot contains no blocks", ErrInvalidChain)
+	}
+
+	bc := &Blockchain{received: snapshot.Received}
+	bc.applyFileLocked(snapshot.blockchainFile)
+
+	if _, err := bc.IsChainValid(); err != nil {
+		return nil, fmt.Errorf("snapshot is corrupted: %w", err)
+	}
+	if !maps.Equal(bc.nonces, snapshot.Nonces) {
+		return nil, fmt.Errorf("%w: nonces do not match the chain and the mempool", ErrInvalidSnapshot)
+	}
+	if !maps.Equal(bc.utxo, snapshot.UTXO) {
+		return nil, fmt.Errorf("%w: UTXO set does not match the chain", ErrInvalidSnapshot)
+	}
+	bc.revalidateMempoolLocked()
+
+	return bc, nil
+}
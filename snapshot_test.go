@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"maps"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotRestoreRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		utxo  bool
+		prune bool
+	}{
+		{"account", false, false},
+		{"utxo", true, false},
+		{"pruned account", false, true},
+		{"pruned utxo", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := newTestBlockchain(t, map[string]int64{"alice": 1000})
+			if tt.utxo {
+				bc.EnableUTXO()
+			}
+			for _, amount := range []int64{10, 20, 30} {
+				if _, err := bc.addTransaction("alice", "bob", amount); err != nil {
+					t.Fatalf("addTransaction(): %v", err)
+				}
+				mineTestBlock(t, bc, "miner")
+			}
+			if tt.prune {
+				if err := bc.Prune(1); err != nil {
+					t.Fatalf("Prune(): %v", err)
+				}
+			}
+			if _, err := bc.addTransaction("alice", "carol", 5); err != nil {
+				t.Fatalf("addTransaction(): %v", err)
+			}
+
+			data, err := bc.Snapshot()
+			if err != nil {
+				t.Fatalf("Snapshot(): %v", err)
+			}
+			restored, err := Restore(data)
+			if err != nil {
+				t.Fatalf("Restore(): %v", err)
+			}
+
+			if !reflect.DeepEqual(restored.GetChain(), bc.GetChain()) {
+				t.Error("restored chain differs")
+			}
+			if !reflect.DeepEqual(restored.Mempool(), bc.Mempool()) {
+				t.Errorf("restored mempool = %+v, want %+v", restored.Mempool(), bc.Mempool())
+			}
+			for _, address := range []string{"alice", "bob", "carol", "miner"} {
+				if got, want := restored.GetBalance(address), bc.GetBalance(address); got != want {
+					t.Errorf("%s has %d units after restoring, want %d", address, got, want)
+				}
+			}
+			restored.mu.RLock()
+			bc.mu.RLock()
+			if !maps.Equal(restored.utxo, bc.utxo) {
+				t.Errorf("restored UTXO set = %v, want %v", restored.utxo, bc.utxo)
+			}
+			if !tt.utxo && len(bc.utxo) != 0 {
+				t.Errorf("account model chain tracks %d unspent outputs, want none", len(bc.utxo))
+			}
+			bc.mu.RUnlock()
+			restored.mu.RUnlock()
+
+			again, err := restored.Snapshot()
+			if err != nil {
+				t.Fatalf("Snapshot() of the restored node: %v", err)
+			}
+			if !bytes.Equal(again, data) {
+				t.Error("snapshot of the restored node differs from the original snapshot")
+			}
+			if _, err := restored.MineBlock("miner"); err != nil {
+				t.Errorf("MineBlock() on the restored node: %v", err)
+			}
+		})
+	}
+}
+
+func TestRestoreRejectsEditedSnapshot(t *testing.T) {
+	bc := newTestBlockchain(t, map[string]int64{"alice": 1000})
+	bc.EnableUTXO()
+	mineTestBlock(t, bc, "miner")
+	data, err := bc.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot(): %v", err)
+	}
+
+	// the unspent output of alice is moved to mallory, who never received it on the chain
+	edited := strings.Replace(string(data), `"address":"alice"`, `"address":"mallory"`, 1)
+	if edited == string(data) {
+		t.Fatal("snapshot does not contain the unspent output of alice")
+	}
+	if _, err := Restore([]byte(edited)); !errors.Is(err, ErrInvalidSnapshot) && !errors.Is(err, ErrInvalidChain) {
+		t.Errorf("Restore() error = %v, want ErrInvalidSnapshot or ErrInvalidChain", err)
+	}
+}
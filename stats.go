@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// Height returns the index of the tip block, which is the number of blocks mined on top of the genesis block.
+// A chain holding only the genesis block has height 0. O(1)
+func (bc *Blockchain) Height() int {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return len(bc.Chain) - 1
+}
+
+// CurrentDifficulty returns the difficulty the next block is mined at, which difficulty adjustment may change
+// after every block. O(1)
+func (bc *Blockchain) CurrentDifficulty() int {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return bc.Difficulty
+}
+
+// TotalTransactions counts the confirmed transactions of all blocks, including genesis allocations
+// and coinbase transactions. O(n) in the number of blocks
+func (bc *Blockchain) TotalTransactions() int {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	total := 0
+	for _, block := range bc.Chain {
+		total += len(block.Transactions)
+	}
+
+	return total
+}
+
+// PendingCount returns the number of unconfirmed transactions in the mempool. O(1)
+func (bc *Blockchain) PendingCount() int {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return len(bc.Transactions)
+}
+
+// TotalVolume sums the amounts in base units transferred by the block's transactions. Transactions sent by coinbaseSender,
+// mining rewards and genesis allocations, are left out since they mint new coins rather than move them;
+// fees are not part of the volume either. A pruned block has no volume. O(n) in the number of transactions
+func (b Block) TotalVolume() int64 {
+	volume := int64(0)
+	for _, tx := range b.Transactions {
+		if tx.Sender != coinbaseSender {
+			volume += tx.Amount
+		}
+	}
+
+	return volume
+}
+
+// ChainVolume sums TotalVolume over all blocks of the chain. O(n) in the number of transactions
+func (bc *Blockchain) ChainVolume() int64 {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	volume := int64(0)
+	for _, block := range bc.Chain {
+		volume += block.TotalVolume()
+	}
+
+	return volume
+}
+
+// Confirmations returns the number of blocks mined on top of the block containing the transaction:
+// 0 while it is in the tip block, growing by one with every further block.
+// Returns ErrUnconfirmedTransaction if it is pending in the mempool and ErrTransactionNotFound if it is
+// in neither the chain nor the mempool, which includes transactions of pruned blocks. O(n) in the number of transactions
+func (bc *Blockchain) Confirmations(txid string) (int, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	_, index, err := bc.findTransactionLocked(txid)
+	if err != nil {
+		return 0, err
+	}
+	if index == UnconfirmedBlockIndex {
+		return 0, fmt.Errorf("%w: %s is pending in the mempool", ErrUnconfirmedTransaction, txid)
+	}
+
+	return len(bc.Chain) - 1 - index, nil
+}
+
+// TotalSupply returns the base units in existence: the genesis allocations plus the mining rewards
+// of every block. Transfers between addresses move existing coins and do not count; the fees they pay
+// are collected by a coinbase transaction, so they are subtracted again instead of being counted twice.
+// The supply of pruned blocks is taken from the checkpoint balances. O(n) in the number of transactions
+func (bc *Blockchain) TotalSupply() int64 {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return bc.totalSupplyLocked()
+}
+
+// totalSupplyLocked is TotalSupply for callers already holding the lock
+func (bc *Blockchain) totalSupplyLocked() int64 {
+	supply := int64(0)
+	if bc.Checkpoint != nil {
+		for _, balance := range bc.Checkpoint.Balances {
+			supply += balance
+		}
+	}
+	for _, block := range bc.Chain {
+		if block.Pruned {
+			continue
+		}
+		for _, tx := range block.Transactions {
+			if tx.Sender == coinbaseSender {
+				supply += tx.Amount
+			} else {
+				supply -= tx.Fee
+			}
+		}
+	}
+
+	return supply
+}
+
+// statsBlockWindow is the number of most recent blocks Stats averages the block time and hash rate over
+const statsBlockWindow = 10
+
+// ChainStats is a snapshot of the state of the node, as reported by Stats and GET /stats
+type ChainStats struct {
+	Height      int   `json:"height"`
+	Difficulty  int   `json:"difficulty"`   // difficulty the next block is mined at
+	TotalSupply int64 `json:"total_supply"` // base units in existence
+	MempoolSize int   `json:"mempool_size"`
+	// AverageBlockTime is the mean time between the last statsBlockWindow blocks, or fewer if the chain is shorter;
+	// zero until two blocks were mined. Block timestamps have a resolution of one second, or one millisecond
+	// with MillisecondTimestamps
+	AverageBlockTime time.Duration `json:"average_block_time"`
+	// HashRate estimates the hashes per second of the whole network: the expected number of hashes needed
+	// to mine the blocks of the window at their difficulties (see EstimateMiningTime) divided by the time
+	// they took. Zero while AverageBlockTime is zero
+	HashRate float64 `json:"hash_rate"`
+}
+
+// Stats returns the height, difficulty, supply, mempool size, average block time and estimated
+// network hash rate of the chain in a single consistent snapshot. O(n) in the number of transactions
+func (bc *Blockchain) Stats() ChainStats {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	stats := ChainStats{
+		Height:      len(bc.Chain) - 1,
+		Difficulty:  bc.Difficulty,
+		TotalSupply: bc.totalSupplyLocked(),
+		MempoolSize: len(bc.Transactions),
+	}
+
+	// the genesis timestamp is fixed rather than mined, so the window starts at block 1 at the earliest
+	// and a time span needs two mined blocks
+	if len(bc.Chain) < 3 {
+		return stats
+	}
+	first := max(1, len(bc.Chain)-1-statsBlockWindow)
+	tip := bc.Chain[len(bc.Chain)-1]
+	blocks := tip.Index - bc.Chain[first].Index
+	span := tip.Timestamp - bc.Chain[first].Timestamp
+	if blocks <= 0 || span <= 0 {
+		return stats
+	}
+	stats.AverageBlockTime = time.Duration(span) * bc.timestampUnitLocked() / time.Duration(blocks)
+
+	space := new(big.Float).SetInt(new(big.Int).Lsh(big.NewInt(1), maxDifficulty))
+	work := new(big.Float)
+	for _, block := range bc.Chain[first+1:] {
+		if target := bc.targetLocked(block.Difficulty); target != nil && target.Sign() > 0 {
+			work.Add(work, new(big.Float).Quo(space, new(big.Float).SetInt(target)))
+		}
+	}
+	hashes, _ := work.Float64()
+	stats.HashRate = hashes / (time.Duration(span) * bc.timestampUnitLocked()).Seconds()
+
+	return stats
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// blockchainFile is the on-disk JSON representation of a blockchain: the confirmed chain,
+// the mempool, the difficulty needed to validate the chain after loading and the node settings
+type blockchainFile struct {
+	Chain             []Block       `json:"chain"`
+	Transactions      []Transaction `json:"transactions"`
+	Difficulty        int           `json:"difficulty"`
+	BlockReward       int64         `json:"block_reward"`
+	HalvingInterval   int           `json:"halving_interval"`
+	TreasuryAddress   string        `json:"treasury_address,omitempty"`
+	TreasuryPercent   float64       `json:"treasury_percent,omitempty"`
+	RequireSignatures bool          `json:"require_signatures"`
+	ValidateAddresses bool          `json:"validate_addresses"`
+
+	TargetBlockTime              time.Duration `json:"target_block_time"`
+	DifficultyAdjustmentInterval int           `json:"difficulty_adjustment_interval"`
+	MaxFutureBlockTime           time.Duration `json:"max_future_block_time"`
+	MaxTxPerBlock                int           `json:"max_tx_per_block"`
+	MaxBlockSize                 int           `json:"max_block_size"`
+	UTXO                         bool          `json:"utxo"`
+	MaxMempoolSize               int           `json:"max_mempool_size"`
+	DustThreshold                int64         `json:"dust_threshold,omitempty"`
+	Checkpoint                   *Checkpoint   `json:"checkpoint,omitempty"`
+	Hasher                       string        `json:"hasher,omitempty"` // name of a built-in hasher, see hasherNames
+	PoW                          string        `json:"pow,omitempty"`    // name of a built-in proof-of-work algorithm, see powNames
+	Target                       *big.Int      `json:"target,omitempty"`
+	CoinbaseMaturity             int           `json:"coinbase_maturity"`
+	MillisecondTimestamps        bool          `json:"millisecond_timestamps"`
+}
+
+// SaveToFile marshals the chain and the mempool to JSON and writes them to path.
+// The data is written to a temporary file in the same directory first and then renamed,
+// so an interrupted write never leaves a truncated file behind
+func (bc *Blockchain) SaveToFile(path string) error {
+	bc.mu.RLock()
+	data, err := json.MarshalIndent(bc.fileLocked(), "", "  ")
+	bc.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("marshal blockchain: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once the rename succeeded
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write temporary file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sync temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temporary file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("rename temporary file: %w", err)
+	}
+	return nil
+}
+
+// LoadFromFile reads a blockchain previously written by SaveToFile and validates it with IsChainValid.
+// A file that fails validation is rejected, so the returned blockchain can be trusted. The saved mempool
+// is checked against the loaded chain like transactions passed to ReinjectMempool, dropping the invalid ones
+func LoadFromFile(path string) (*Blockchain, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read blockchain file: %w", err)
+	}
+
+	var file blockchainFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("unmarshal blockchain file: %w", err)
+	}
+
+	if len(file.Chain) == 0 {
+		return nil, fmt.Errorf("%w: blockchain file %s contains no blocks", ErrInvalidChain, path)
+	}
+
+	bc := &Blockchain{}
+	bc.applyFileLocked(file)
+
+	if _, err := bc.IsChainValid(); err != nil {
+		return nil, fmt.Errorf("blockchain file %s is corrupted: %w", path, err)
+	}
+	bc.revalidateMempoolLocked()
+
+	return bc, nil
+}
+
+// fileLocked returns the persisted representation of bc; the caller must hold the lock
+func (bc *Blockchain) fileLocked() blockchainFile {
+	return blockchainFile{
+		Chain:             bc.Chain,
+		Transactions:      bc.Transactions,
+		Difficulty:        bc.Difficulty,
+		BlockReward:       bc.BlockReward,
+		HalvingInterval:   bc.HalvingInterval,
+		TreasuryAddress:   bc.TreasuryAddress,
+		TreasuryPercent:   bc.TreasuryPercent,
+		RequireSignatures: bc.RequireSignatures,
+		ValidateAddresses: bc.ValidateAddresses,
+
+		TargetBlockTime:              bc.TargetBlockTime,
+		DifficultyAdjustmentInterval: bc.DifficultyAdjustmentInterval,
+		MaxFutureBlockTime:           bc.MaxFutureBlockTime,
+		MaxTxPerBlock:                bc.MaxTxPerBlock,
+		MaxBlockSize:                 bc.MaxBlockSize,
+		UTXO:                         bc.useUTXO,
+		MaxMempoolSize:               bc.MaxMempoolSize,
+		DustThreshold:                bc.DustThreshold,
+		Checkpoint:                   bc.Checkpoint,
+		Hasher:                       hasherName(bc.hasher()),
+		PoW:                          powName(powOrDefault(bc.PoW)),
+		Target:                       bc.Target,
+		CoinbaseMaturity:             bc.CoinbaseMaturity,
+		MillisecondTimestamps:        bc.MillisecondTimestamps,
+	}
+}
+
+// applyFileLocked replaces the persisted state and settings of bc with those of file, filling in defaults
+// for missing settings and rebuilding the derived state. Callbacks and peers are kept; the chain is not validated
+func (bc *Blockchain) applyFileLocked(file blockchainFile) {
+	bc.Chain = file.Chain
+	bc.Transactions = file.Transactions
+	bc.Difficulty = file.Difficulty
+	bc.BlockReward = file.BlockReward
+	bc.HalvingInterval = file.HalvingInterval
+	bc.TreasuryAddress = file.TreasuryAddress
+	bc.TreasuryPercent = file.TreasuryPercent
+	bc.RequireSignatures = file.RequireSignatures
+	bc.ValidateAddresses = file.ValidateAddresses
+
+	bc.TargetBlockTime = file.TargetBlockTime
+	bc.DifficultyAdjustmentInterval = file.DifficultyAdjustmentInterval
+	bc.MaxFutureBlockTime = file.MaxFutureBlockTime
+	bc.MaxTxPerBlock = file.MaxTxPerBlock
+	bc.MaxBlockSize = file.MaxBlockSize
+	bc.MaxMempoolSize = file.MaxMempoolSize
+	bc.DustThreshold = file.DustThreshold
+	bc.Checkpoint = file.Checkpoint
+	bc.Hasher = hasherNames[file.Hasher]
+	bc.PoW = powNames[file.PoW]
+	bc.Target = file.Target
+	bc.CoinbaseMaturity = file.CoinbaseMaturity
+	bc.MillisecondTimestamps = file.MillisecondTimestamps
+	bc.useUTXO = file.UTXO
+
+	if bc.Transactions == nil {
+		bc.Transactions = []Transaction{}
+	}
+	if bc.Difficulty == 0 {
+		bc.Difficulty = defaultDifficulty
+	}
+	if bc.DifficultyAdjustmentInterval == 0 {
+		bc.DifficultyAdjustmentInterval = defaultDifficultyAdjustmentInterval
+	}
+	if bc.MaxFutureBlockTime == 0 {
+		bc.MaxFutureBlockTime = defaultMaxFutureBlockTime
+	}
+
+	bc.syncReceivedLocked()
+	bc.rebuildNoncesLocked()
+	bc.rebuildUTXOLocked()
+}
+
+// RebuildConfig holds the mining settings a log of blocks given to RebuildFromBlocks was mined under.
+// A block records the difficulty it claims to be mined at, so the difficulty it must meet has to come from here
+type RebuildConfig struct {
+	// Difficulty is the minimum difficulty of every block without TargetBlockTime; zero means the difficulty of
+	// the genesis block, which its hash commits to
+	Difficulty int
+	// TargetBlockTime enables difficulty adjustment: every block must then have the difficulty recomputed from
+	// the blocks before it (see nextDifficulty), starting from the difficulty of the genesis block
+	TargetBlockTime time.Duration
+	// DifficultyAdjustmentInterval is the number of blocks between two adjustments; zero means defaultDifficultyAdjustmentInterval
+	DifficultyAdjustmentInterval int
+}
+
+// RebuildFromBlocks builds a blockchain from a log of blocks starting at the genesis block, e.g. blocks
+// received one by one or exported with ExportBlock. Unlike LoadFromFile, which validates a complete chain
+// after loading it, the blocks are appended one at a time and each is checked against the chain built so far
+// with the IsChainValid rules (link, hash, Merkle root, balances, difficulty and proof of work) before the next one is read,
+// so the error names the first invalid block. The genesis block is trusted once its hash matches its header
+// under one of the built-in hashers, which becomes the hasher of the chain; likewise the proof-of-work algorithm
+// is the first built-in one under which the proof of work of block 1 is valid, and the timestamps are read
+// as milliseconds if the genesis timestamp is too large for seconds (see maxSecondTimestamp). Every block must be
+// mined at least at the difficulty of cfg or, with cfg.TargetBlockTime, exactly at the recomputed difficulty,
+// so a block cannot lower the proof of work it needs by recording a smaller difficulty. The rebuilt chain continues
+// at that difficulty; all other settings are the defaults of a new blockchain and the mempool is empty
+func RebuildFromBlocks(blocks []Block, cfg RebuildConfig) (*Blockchain, error) {
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("%w: no blocks to rebuild from", ErrInvalidChain)
+	}
+
+	genesis := blocks[0].clone()
+	var hasher Hasher
+	for _, name := range []string{"sha256", "double-sha256"} {
+		if calculateHash(hasherNames[name], genesis.BlockHeader) == genesis.Hash {
+			hasher = hasherNames[name]
+			break
+		}
+	}
+	if hasher == nil {
+		return nil, fmt.Errorf("%w: %w: stored hash does not match calculated hash", ErrInvalidChain, ErrInvalidGenesis)
+	}
+	if err := validateGenesis(hasher, genesis); err != nil {
+		return nil, err
+	}
+
+	difficulty := cfg.Difficulty
+	if difficulty == 0 || cfg.TargetBlockTime > 0 {
+		difficulty = genesis.Difficulty
+	}
+	if err := validateDifficulty(difficulty); err != nil {
+		return nil, err
+	}
+	interval := cfg.DifficultyAdjustmentInterval
+	if interval == 0 {
+		interval = defaultDifficultyAdjustmentInterval
+	}
+
+	pow := PoWAlgorithm(SHA256PoW{})
+	if len(blocks) > 1 {
+		for _, name := range []string{"sha256", "scrypt"} {
+			proof := powNames[name].ProofHash(hasher, blocks[1].serializeForHash())
+			if meetsDifficulty(proof, blocks[1].Difficulty) {
+				pow = powNames[name]
+				break
+			}
+		}
+	}
+
+	bc := &Blockchain{
+		Chain:        []Block{genesis},
+		Transactions: []Transaction{},
+		Difficulty:   difficulty,
+		BlockReward:  defaultBlockReward,
+		Hasher:       hasher,
+		PoW:          pow,
+
+		TargetBlockTime:              cfg.TargetBlockTime,
+		DifficultyAdjustmentInterval: interval,
+		MaxFutureBlockTime:           defaultMaxFutureBlockTime,
+		MillisecondTimestamps:        genesis.Timestamp > maxSecondTimestamp,
+	}
+	replay := bc.newChainReplayLocked(bc.Chain)
+	for i := 1; i < len(blocks); i++ {
+		chain := append(bc.Chain, blocks[i].clone())
+		if err := bc.validateBlockLocked(chain, i, replay); err != nil {
+			return nil, err
+		}
+		bc.Chain = chain
+	}
+
+	bc.adjustDifficulty()
+	bc.syncReceivedLocked()
+	bc.rebuildNoncesLocked()
+	bc.rebuildUTXOLocked()
+
+	return bc, nil
+}
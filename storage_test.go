@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveAndLoadFile(t *testing.T) {
+	for _, utxo := range []bool{false, true} {
+		t.Run(map[bool]string{false: "account", true: "utxo"}[utxo], func(t *testing.T) {
+			bc := createBlockchainWithGenesis(GenesisConfig{
+				Timestamp:  defaultGenesisTimestamp,
+				Difficulty: 1,
+				Balances:   map[string]int64{"alice": 1000},
+				UTXO:       utxo,
+			})
+			if _, err := bc.addTransaction("alice", "bob", 100); err != nil {
+				t.Fatalf("addTransaction(): %v", err)
+			}
+			mineTestBlock(t, bc, "miner")
+			if _, err := bc.addTransaction("alice", "carol", 50); err != nil {
+				t.Fatalf("addTransaction(): %v", err)
+			}
+
+			path := filepath.Join(t.TempDir(), "chain.json")
+			if err := bc.SaveToFile(path); err != nil {
+				t.Fatalf("SaveToFile(): %v", err)
+			}
+			loaded, err := LoadFromFile(path)
+			if err != nil {
+				t.Fatalf("LoadFromFile(): %v", err)
+			}
+
+			if !reflect.DeepEqual(loaded.GetChain(), bc.GetChain()) {
+				t.Errorf("loaded chain differs from the saved one")
+			}
+			if !reflect.DeepEqual(loaded.Mempool(), bc.Mempool()) {
+				t.Errorf("loaded mempool = %+v, want %+v", loaded.Mempool(), bc.Mempool())
+			}
+			for _, address := range []string{"alice", "bob", "carol", "miner"} {
+				if got, want := loaded.GetBalance(address), bc.GetBalance(address); got != want {
+					t.Errorf("loaded GetBalance(%s) = %d, want %d", address, got, want)
+				}
+			}
+			if loaded.CurrentDifficulty() != bc.CurrentDifficulty() {
+				t.Errorf("loaded difficulty = %d, want %d", loaded.CurrentDifficulty(), bc.CurrentDifficulty())
+			}
+			mineTestBlock(t, loaded, "miner")
+		})
+	}
+}
+
+func TestLoadFromFileRejectsInvalidFiles(t *testing.T) {
+	bc := newTestBlockchain(t, map[string]int64{"alice": 1000})
+	mineTestBlock(t, bc, "miner")
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.json")
+	if err := bc.SaveToFile(valid); err != nil {
+		t.Fatalf("SaveToFile(): %v", err)
+	}
+	data, err := os.ReadFile(valid)
+	if err != nil {
+		t.Fatalf("ReadFile(): %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr error
+	}{
+		{"not json", "not json", nil},
+		{"no blocks", `{"chain": []}`, ErrInvalidChain},
+		{"tampered block", strings.Replace(string(data), `"nonce": `, `"nonce": 1`, 1), ErrInvalidChain},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "-")+".json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("WriteFile(): %v", err)
+			}
+			if _, err := LoadFromFile(path); err == nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("LoadFromFile() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := LoadFromFile(filepath.Join(dir, "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadFromFile() of a missing file error = %v, want os.ErrNotExist", err)
+	}
+}
+
+// resealTestBlock searches a new nonce for the header of block after a change and updates its hash
+func resealTestBlock(bc *Blockchain, block Block) Block {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	block.Nonce = 0
+	for !bc.meetsProofLocked(block.BlockHeader) {
+		block.Nonce++
+	}
+	block.Hash = calculateHash(bc.hasher(), block.BlockHeader)
+	return block
+}
+
+func TestRebuildFromBlocks(t *testing.T) {
+	fixed := createBlockchainWithGenesis(GenesisConfig{
+		Timestamp:  defaultGenesisTimestamp,
+		Difficulty: 4,
+		Balances:   map[string]int64{"alice": 100},
+	})
+	for range 3 {
+		if _, err := fixed.addTransaction("alice", "bob", 10); err != nil {
+			t.Fatalf("addTransaction(): %v", err)
+		}
+		mineTestBlock(t, fixed, "miner")
+	}
+
+	adjusted := newTestBlockchain(t, nil)
+	adjusted.TargetBlockTime = time.Hour
+	adjusted.DifficultyAdjustmentInterval = 2
+	for range 5 {
+		mineTestBlock(t, adjusted, "miner")
+	}
+	adjustedCfg := RebuildConfig{TargetBlockTime: time.Hour, DifficultyAdjustmentInterval: 2}
+	if adjusted.GetChain()[5].Difficulty != 2 {
+		t.Fatalf("difficulty of block 5 = %d, want it raised to 2", adjusted.GetChain()[5].Difficulty)
+	}
+
+	// tamper returns the blocks of bc with block i changed by change, resealed at the difficulty it records
+	tamper := func(bc *Blockchain, i int, change func(*Block)) []Block {
+		blocks := bc.GetChain()
+		change(&blocks[i])
+		blocks[i] = resealTestBlock(bc, blocks[i])
+		return blocks
+	}
+
+	tests := []struct {
+		name   string
+		blocks []Block
+		cfg    RebuildConfig
+		// wantErr is a substring of the error, empty for a log which rebuilds
+		wantErr        string
+		wantDifficulty int
+	}{
+		{"good log", fixed.GetChain(), RebuildConfig{}, "", 4},
+		{"good log with configured difficulty", fixed.GetChain(), RebuildConfig{Difficulty: 3}, "", 3},
+		{"good log with adjustment", adjusted.GetChain(), adjustedCfg, "", adjusted.CurrentDifficulty()},
+		{"lowered difficulty", tamper(fixed, 2, func(b *Block) { b.Difficulty = 1 }), RebuildConfig{}, "block 2: difficulty 1 is below required difficulty 4", 0},
+		{"lowered difficulty below the configured one", fixed.GetChain(), RebuildConfig{Difficulty: 5}, "block 1: difficulty 4 is below required difficulty 5", 0},
+		{"adjusted difficulty not applied", tamper(adjusted, 5, func(b *Block) { b.Difficulty = 1 }), adjustedCfg, "block 5: difficulty 1 does not match expected difficulty 2", 0},
+		{"tampered transaction", tamper(fixed, 2, func(b *Block) { b.Transactions[1].Amount = 90 }), RebuildConfig{}, "block 2: transaction", 0},
+		{"broken link", tamper(fixed, 2, func(b *Block) { b.PreviousHash = fixed.GetChain()[0].Hash }), RebuildConfig{}, "block 2: previous hash does not match", 0},
+		{"missing block", append(fixed.GetChain()[:2:2], fixed.GetChain()[3]), RebuildConfig{}, "block 3: index does not follow block 1", 0},
+		{"no blocks", nil, RebuildConfig{}, "no blocks", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc, err := RebuildFromBlocks(tt.blocks, tt.cfg)
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrInvalidChain) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("RebuildFromBlocks() error = %v, want ErrInvalidChain mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RebuildFromBlocks(): %v", err)
+			}
+			if !reflect.DeepEqual(bc.GetChain(), tt.blocks) {
+				t.Error("rebuilt chain differs from the log")
+			}
+			if got := bc.CurrentDifficulty(); got != tt.wantDifficulty {
+				t.Errorf("CurrentDifficulty() = %d, want %d", got, tt.wantDifficulty)
+			}
+			if valid, err := bc.IsChainValid(); !valid {
+				t.Errorf("IsChainValid() = false: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import "math/big"
+
+// TargetFromDifficulty returns the proof-of-work target of a difficulty: the smallest 256-bit integer
+// with difficulty leading zero bits, 2^(256-difficulty). A hash is below the target exactly when it starts
+// with difficulty zero bits. Returns nil if difficulty is outside the allowed range
+func TargetFromDifficulty(difficulty int) *big.Int {
+	if validateDifficulty(difficulty) != nil {
+		return nil
+	}
+	return new(big.Int).Lsh(big.NewInt(1), uint(maxDifficulty-difficulty))
+}
+
+// hashBelowTarget interprets a hex hash as a big-endian unsigned integer and reports whether it is
+// strictly less than target. A nil target or a hash which is not valid hex never qualifies
+func hashBelowTarget(hash string, target *big.Int) bool {
+	if target == nil {
+		return false
+	}
+	hashInt, ok := new(big.Int).SetString(hash, 16)
+	if !ok || hashInt.Sign() < 0 {
+		return false
+	}
+	return hashInt.Cmp(target) < 0
+}
+
+// targetLocked returns the target a block hash with the given difficulty must be below: the target
+// of the difficulty, lowered to bc.Target when that is set and stricter. Nil if difficulty is out of range
+func (bc *Blockchain) targetLocked(difficulty int) *big.Int {
+	target := TargetFromDifficulty(difficulty)
+	if target != nil && bc.Target != nil && bc.Target.Cmp(target) < 0 {
+		return bc.Target
+	}
+	return target
+}
+
+// meetsTargetLocked reports whether a hash is below bc.Target; any hash qualifies when no target is set
+func (bc *Blockchain) meetsTargetLocked(hash string) bool {
+	return bc.Target == nil || hashBelowTarget(hash, bc.Target)
+}
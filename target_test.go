@@ -0,0 +1,75 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestTargetFromDifficulty(t *testing.T) {
+	tests := []struct {
+		difficulty int
+		want       string // hex, empty for nil
+	}{
+		{1, "8000000000000000000000000000000000000000000000000000000000000000"},
+		{8, "100000000000000000000000000000000000000000000000000000000000000"},
+		{20, "100000000000000000000000000000000000000000000000000000000000"},
+		{maxDifficulty, "1"},
+		{minDifficulty - 1, ""},
+		{maxDifficulty + 1, ""},
+	}
+
+	for _, tt := range tests {
+		target := TargetFromDifficulty(tt.difficulty)
+		if tt.want == "" {
+			if target != nil {
+				t.Errorf("TargetFromDifficulty(%d) = %x, want nil", tt.difficulty, target)
+			}
+			continue
+		}
+		if target == nil || target.Text(16) != tt.want {
+			t.Errorf("TargetFromDifficulty(%d) = %x, want %s", tt.difficulty, target, tt.want)
+		}
+	}
+}
+
+func TestHashBelowTargetMatchesLeadingZeroBits(t *testing.T) {
+	// the Bitcoin genesis block hash has 43 leading zero bits
+	const bitcoinGenesis = "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"
+	hashes := []string{bitcoinGenesis}
+
+	bc := createBlockchainWithGenesis(GenesisConfig{Timestamp: defaultGenesisTimestamp, Difficulty: 6})
+	for range 5 {
+		hashes = append(hashes, mineTestBlock(t, bc, "miner").Hash)
+	}
+
+	for _, hash := range hashes {
+		for difficulty := 1; difficulty <= 48; difficulty++ {
+			below := hashBelowTarget(hash, TargetFromDifficulty(difficulty))
+			if zeros := hasLeadingZeroBits(hash, difficulty); below != zeros {
+				t.Errorf("hash %s at difficulty %d: below target = %v, leading zero bits = %v", hash, difficulty, below, zeros)
+			}
+		}
+	}
+	if !hashBelowTarget(bitcoinGenesis, TargetFromDifficulty(43)) || hashBelowTarget(bitcoinGenesis, TargetFromDifficulty(44)) {
+		t.Errorf("the Bitcoin genesis hash does not have exactly 43 leading zero bits")
+	}
+	if hashBelowTarget("not hex", TargetFromDifficulty(1)) || hashBelowTarget(bitcoinGenesis, nil) {
+		t.Errorf("hashBelowTarget() accepted a malformed hash or a nil target")
+	}
+}
+
+func TestMiningMeetsFinerTarget(t *testing.T) {
+	bc := createBlockchainWithGenesis(GenesisConfig{Timestamp: defaultGenesisTimestamp, Difficulty: 2})
+	// a third of the target of difficulty 2, between the targets of difficulty 3 and 4
+	bc.Target = new(big.Int).Div(TargetFromDifficulty(2), big.NewInt(3))
+
+	for range 5 {
+		block := mineTestBlock(t, bc, "miner")
+		if !hashBelowTarget(block.Hash, bc.Target) {
+			t.Errorf("block %d hash %s is not below the target", block.Index, block.Hash)
+		}
+	}
+	if valid, err := bc.IsChainValid(); !valid {
+		t.Errorf("IsChainValid() = false: %v", err)
+	}
+}
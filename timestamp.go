@@ -0,0 +1,49 @@
+package main
+
+import "time"
+
+// maxSecondTimestamp bounds the block timestamps blockTime reads as Unix seconds; larger ones are Unix milliseconds.
+// As seconds it lies after the year 33000 and as milliseconds in 2001, so no real timestamp is ambiguous
+const maxSecondTimestamp int64 = 1_000_000_000_000
+
+// timestampUnitLocked returns the resolution of the block timestamps of the chain, a millisecond when
+// bc.MillisecondTimestamps is set and a second otherwise
+func (bc *Blockchain) timestampUnitLocked() time.Duration {
+	if bc.MillisecondTimestamps {
+		return time.Millisecond
+	}
+	return time.Second
+}
+
+// timestampLocked converts t to a block timestamp of the chain, Unix milliseconds or seconds
+func (bc *Blockchain) timestampLocked(t time.Time) int64 {
+	if bc.MillisecondTimestamps {
+		return t.UnixMilli()
+	}
+	return t.Unix()
+}
+
+// nextBlockTimestampLocked returns the timestamp of a block mined now on top of the tip: the current time, but never
+// earlier than the tip and, with MillisecondTimestamps, at least one millisecond after it, so blocks mined back to back
+// within the same millisecond still get distinct and ordered timestamps (see validateTimestampLocked)
+func (bc *Blockchain) nextBlockTimestampLocked() int64 {
+	now := bc.timestampLocked(time.Now())
+	if len(bc.Chain) == 0 {
+		return now
+	}
+
+	earliest := bc.Chain[len(bc.Chain)-1].Timestamp
+	if bc.MillisecondTimestamps {
+		earliest++
+	}
+	return max(now, earliest)
+}
+
+// blockTime converts a block timestamp to a time for display, telling Unix milliseconds from seconds
+// by their magnitude (see maxSecondTimestamp), as a block does not know the resolution of its chain
+func blockTime(timestamp int64) time.Time {
+	if timestamp > maxSecondTimestamp {
+		return time.UnixMilli(timestamp)
+	}
+	return time.Unix(timestamp, 0)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMillisecondBlocksMinedBackToBackAreOrdered(t *testing.T) {
+	bc := createBlockchainWithGenesis(GenesisConfig{
+		Timestamp:    time.Now().UnixMilli(),
+		Difficulty:   1,
+		Milliseconds: true,
+	})
+	for range 20 {
+		mineTestBlock(t, bc, "miner")
+	}
+
+	chain := bc.GetChain()
+	for i := 1; i < len(chain); i++ {
+		if chain[i].Timestamp <= chain[i-1].Timestamp {
+			t.Errorf("block %d has timestamp %d, not later than %d of block %d", i, chain[i].Timestamp, chain[i-1].Timestamp, i-1)
+		}
+		if chain[i].Timestamp <= maxSecondTimestamp {
+			t.Errorf("block %d has timestamp %d, which is not in milliseconds", i, chain[i].Timestamp)
+		}
+	}
+	if valid, err := bc.IsChainValid(); !valid {
+		t.Errorf("IsChainValid() = false: %v", err)
+	}
+}
+
+func TestSecondBlocksWithinOneSecondStillValidate(t *testing.T) {
+	bc := newTestBlockchain(t, nil)
+	for range 5 {
+		mineTestBlock(t, bc, "miner")
+	}
+
+	chain := bc.GetChain()
+	if chain[len(chain)-1].Timestamp > maxSecondTimestamp {
+		t.Errorf("tip timestamp %d is not in seconds", chain[len(chain)-1].Timestamp)
+	}
+	if valid, err := bc.IsChainValid(); !valid {
+		t.Errorf("IsChainValid() = false: %v", err)
+	}
+}
+
+func TestValidateTimestamp(t *testing.T) {
+	tests := []struct {
+		name         string
+		milliseconds bool
+		offset       int64 // timestamp of the block relative to the previous one
+		wantErr      bool
+	}{
+		{"seconds, later", false, 1, false},
+		{"seconds, equal", false, 0, false},
+		{"seconds, earlier", false, -1, true},
+		{"milliseconds, later", true, 1, false},
+		{"milliseconds, equal", true, 0, true},
+		{"milliseconds, earlier", true, -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := &Blockchain{MillisecondTimestamps: tt.milliseconds, MaxFutureBlockTime: defaultMaxFutureBlockTime}
+			previous := Block{BlockHeader: BlockHeader{Index: 1, Timestamp: bc.timestampLocked(time.Now().Add(-time.Minute))}}
+
+			err := bc.validateTimestampLocked(previous, previous.Timestamp+tt.offset)
+			if tt.wantErr != errors.Is(err, ErrInvalidTimestamp) || (!tt.wantErr && err != nil) {
+				t.Errorf("validateTimestampLocked() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNextBlockTimestampFollowsTip(t *testing.T) {
+	for _, milliseconds := range []bool{false, true} {
+		bc := &Blockchain{MillisecondTimestamps: milliseconds}
+		// a tip ahead of the local clock, as a peer with a fast clock may have mined it
+		tip := bc.timestampLocked(time.Now().Add(time.Hour))
+		bc.Chain = []Block{{BlockHeader: BlockHeader{Timestamp: tip}}}
+
+		want := tip
+		if milliseconds {
+			want++
+		}
+		if got := bc.nextBlockTimestampLocked(); got != want {
+			t.Errorf("milliseconds %v: nextBlockTimestampLocked() = %d, want %d", milliseconds, got, want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// UnitsPerCoin is the number of base units making up one coin. Every amount, fee, balance and reward is
+// an int64 count of base units, so balance arithmetic is exact (0.1 + 0.2 coins is exactly 30,000,000 units)
+const UnitsPerCoin int64 = 100_000_000
+
+// amountPrecision is the number of decimal places of a coin amount, the digits of UnitsPerCoin minus one
+const amountPrecision = 8
+
+// maxCoins is the largest coin amount ToUnits converts without overflowing int64
+const maxCoins = float64(math.MaxInt64 / UnitsPerCoin)
+
+// ToUnits converts a coin amount to base units, rounding to the nearest unit, for callers holding
+// amounts as floats. Amounts beyond ±maxCoins saturate and NaN converts to 0; use parseCoins to reject them
+func ToUnits(coins float64) int64 {
+	switch {
+	case math.IsNaN(coins):
+		return 0
+	case coins >= maxCoins:
+		return math.MaxInt64
+	case coins <= -maxCoins:
+		return math.MinInt64
+	}
+	return int64(math.Round(coins * float64(UnitsPerCoin)))
+}
+
+// FromUnits converts base units to a coin amount. The result is only as exact as a float64,
+// so it is meant for display and for callers holding amounts as floats, never for arithmetic on balances
+func FromUnits(units int64) float64 {
+	return float64(units) / float64(UnitsPerCoin)
+}
+
+// parseCoins converts a coin amount given by a user, on the command line or in a request, to base units.
+// Returns ErrInvalidTransaction if the amount is not finite or too large to be represented
+func parseCoins(coins float64) (int64, error) {
+	if math.IsNaN(coins) || math.IsInf(coins, 0) || math.Abs(coins) >= maxCoins {
+		return 0, fmt.Errorf("%w: amount %g cannot be represented in base units", ErrInvalidTransaction, coins)
+	}
+	return ToUnits(coins), nil
+}
+
+// formatAmount returns the canonical representation of an amount used in every hash input and in the output
+// of the CLI: a plain decimal coin amount with exactly amountPrecision digits after the point
+// (e.g. 30,000,000 units become "0.30000000"). It is computed with integer arithmetic only, so all nodes
+// derive identical hashes
+func formatAmount(units int64) string {
+	sign := ""
+	magnitude := uint64(units)
+	if units < 0 {
+		sign = "-"
+		magnitude = -magnitude
+	}
+
+	whole := strconv.FormatUint(magnitude/uint64(UnitsPerCoin), 10)
+	fraction := strconv.FormatUint(magnitude%uint64(UnitsPerCoin)+uint64(UnitsPerCoin), 10)[1:]
+	return sign + whole + "." + fraction
+}
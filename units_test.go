@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestToUnits(t *testing.T) {
+	tests := []struct {
+		name  string
+		coins float64
+		want  int64
+	}{
+		{"0.1+0.2", 0.1 + 0.2, 30_000_000},
+		{"0.3", 0.3, 30_000_000},
+		{"1.1*3", 1.1 * 3, 330_000_000},
+		{"smallest unit", 0.00000001, 1},
+		{"below half a unit", 0.000000004, 0},
+		{"negative", -2.675, -267_500_000},
+		{"saturates", 1e300, math.MaxInt64},
+		{"NaN", math.NaN(), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToUnits(tt.coins); got != tt.want {
+				t.Errorf("ToUnits(%v) = %d, want %d", tt.coins, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		units int64
+		want  string
+	}{
+		{0, "0.00000000"},
+		{1, "0.00000001"},
+		{ToUnits(0.1 + 0.2), "0.30000000"},
+		{50 * UnitsPerCoin, "50.00000000"},
+		{-123_456_789, "-1.23456789"},
+		{math.MinInt64, "-92233720368.54775808"},
+	}
+
+	for _, tt := range tests {
+		if got := formatAmount(tt.units); got != tt.want {
+			t.Errorf("formatAmount(%d) = %q, want %q", tt.units, got, tt.want)
+		}
+	}
+}
+
+func TestParseCoinsRejectsUnrepresentableAmounts(t *testing.T) {
+	for _, coins := range []float64{math.NaN(), math.Inf(1), math.Inf(-1), maxCoins, -1e300} {
+		if _, err := parseCoins(coins); !errors.Is(err, ErrInvalidTransaction) {
+			t.Errorf("parseCoins(%v) error = %v, want ErrInvalidTransaction", coins, err)
+		}
+	}
+}
+
+// TestFloatAmountsHashStably pins the TXID of a transfer of 0.1+0.2 coins: the float sum is not 0.3,
+// but it converts to the same base units, so it hashes like 0.3 coins on every platform
+func TestFloatAmountsHashStably(t *testing.T) {
+	const want = "99dcae660634537356c5c535fb4b6ffea006662575d45666c2fd26bd3270e669"
+	tx := Transaction{Sender: "alice", Recipient: "bob", Amount: ToUnits(0.1 + 0.2), Nonce: 1}
+	same := Transaction{Sender: "alice", Recipient: "bob", Amount: ToUnits(0.3), Nonce: 1}
+
+	got := generateTransactionID(SHA256Hasher{}, tx)
+	if got != generateTransactionID(SHA256Hasher{}, same) {
+		t.Errorf("0.1+0.2 and 0.3 coins hash differently")
+	}
+	if got != want {
+		t.Errorf("generateTransactionID() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TXInput references an output of an earlier transaction which is spent by the transaction holding the input
+type TXInput struct {
+	TXID   string `json:"txid"`   // transaction which created the spent output
+	Output int    `json:"output"` // index of the spent output in that transaction's outputs
+}
+
+// TXOutput assigns an amount in base units to an address; it can be spent exactly once by a later transaction's input
+type TXOutput struct {
+	Address string `json:"address"`
+	Amount  int64  `json:"amount"`
+}
+
+// outpoint returns the key of an output in the UTXO set
+func outpoint(txid string, index int) string {
+	return fmt.Sprintf("%s:%d", txid, index)
+}
+
+// outputs returns the outputs created by the transaction. Transactions without explicit outputs
+// (coinbase, genesis allocations and account-model transfers) create a single output paying Amount to Recipient
+func (tx Transaction) outputs() []TXOutput {
+	if len(tx.Outputs) > 0 {
+		return tx.Outputs
+	}
+	return []TXOutput{{Address: tx.Recipient, Amount: tx.Amount}}
+}
+
+// payments returns the outputs of the transaction paying addresses other than the sender, which sum up to Amount.
+// The account model credits them; outputs returning change to the sender are left out, as the sender is debited
+// Amount and Fee only
+func (tx Transaction) payments() []TXOutput {
+	payments := []TXOutput{}
+	for _, out := range tx.outputs() {
+		if out.Address != tx.Sender {
+			payments = append(payments, out)
+		}
+	}
+	return payments
+}
+
+// EnableUTXO switches the blockchain to the UTXO model and builds the UTXO set by replaying the whole chain.
+// This is the migration path for chains created with the account model: confirmed transactions without inputs
+// are replayed by spending the sender's unspent outputs in outpoint order and returning the change to the sender
+func (bc *Blockchain) EnableUTXO() {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	bc.useUTXO = true
+	bc.rebuildUTXOLocked()
+}
+
+// rebuildUTXOLocked recalculates the UTXO set from the chain; it is empty while the UTXO model is disabled.
+// A block which does not apply to the set, which chain validation rejects, is skipped without an undo record
+func (bc *Blockchain) rebuildUTXOLocked() {
+	bc.utxo = nil
+	bc.utxoUndo = nil
+	if !bc.useUTXO {
+		return
+	}
+
+	bc.utxo = bc.Checkpoint.clone().UTXO
+
+	for _, block := range bc.Chain {
+		_ = bc.connectBlockUTXOLocked(block)
+	}
+}
+
+// utxoUndo records how a block changed the UTXO set, so Reorg can disconnect the block again
+type utxoUndo struct {
+	spent   map[string]TXOutput // outputs of earlier blocks spent by the block, keyed by outpoint
+	created []string            // outpoints of the outputs created by the block and still unspent after it
+}
+
+// connectBlockUTXOLocked applies a block appended to the chain to the UTXO set and keeps its undo record,
+// forgetting the one of the block maxReorgUndoDepth blocks below. Returns the error of applyBlockToUTXO,
+// leaving the set unchanged
+func (bc *Blockchain) connectBlockUTXOLocked(block Block) error {
+	undo, err := applyBlockToUTXO(bc.utxo, block)
+	if err != nil {
+		return err
+	}
+
+	if bc.utxoUndo == nil {
+		bc.utxoUndo = map[string]utxoUndo{}
+	}
+	bc.utxoUndo[block.Hash] = undo
+
+	if deep := block.Index - maxReorgUndoDepth; deep >= 0 && deep < len(bc.Chain) {
+		delete(bc.utxoUndo, bc.Chain[deep].Hash)
+	}
+
+	return nil
+}
+
+// disconnectBlockUTXOLocked reverts the changes of the tip block to the UTXO set with its undo record.
+// Returns false, leaving the set unchanged, if the block has no undo record
+func (bc *Blockchain) disconnectBlockUTXOLocked(block Block) bool {
+	undo, ok := bc.utxoUndo[block.Hash]
+	if !ok {
+		return false
+	}
+
+	undo.revert(bc.utxo)
+	delete(bc.utxoUndo, block.Hash)
+
+	return true
+}
+
+// revert undoes the changes recorded by undo to the UTXO set utxo
+func (undo utxoUndo) revert(utxo map[string]TXOutput) {
+	for _, key := range undo.created {
+		delete(utxo, key)
+	}
+	for key, out := range undo.spent {
+		utxo[key] = out
+	}
+}
+
+// applyBlockToUTXO removes the outputs spent by the block's transactions from the UTXO set utxo
+// and adds the outputs they create. Returns the undo record of the changes; an output created and spent
+// within the block appears in neither of its lists. Returns an error wrapping ErrInsufficientFunds, and leaves
+// utxo unchanged, if an input spends an output missing from the set or the sender of an account-model
+// transaction does not own enough outputs
+func applyBlockToUTXO(utxo map[string]TXOutput, block Block) (utxoUndo, error) {
+	undo := utxoUndo{spent: map[string]TXOutput{}}
+	created := map[string]bool{}
+	fail := func(err error) (utxoUndo, error) {
+		for key := range created {
+			undo.created = append(undo.created, key)
+		}
+		undo.revert(utxo)
+		return utxoUndo{}, err
+	}
+
+	for _, tx := range block.Transactions {
+		inputs := tx.Inputs
+		outputs := tx.outputs()
+
+		if len(inputs) == 0 && tx.Sender != coinbaseSender {
+			// account-model transaction: spend the sender's outputs and create the change output
+			total, spent := spendableOutputs(utxo, tx.Sender, tx.Amount+tx.Fee, nil)
+			if total < tx.Amount+tx.Fee {
+				return fail(fmt.Errorf("%w: block %d: %s owns outputs of %s, transaction %s spends %s", ErrInsufficientFunds,
+					block.Index, tx.Sender, formatAmount(total), tx.TXID, formatAmount(tx.Amount+tx.Fee)))
+			}
+			inputs = spent
+			if change := total - tx.Amount - tx.Fee; change > 0 {
+				outputs = append(append([]TXOutput{}, outputs...), TXOutput{Address: tx.Sender, Amount: change})
+			}
+		}
+
+		for _, in := range inputs {
+			key := outpoint(in.TXID, in.Output)
+			out, ok := utxo[key]
+			if !ok {
+				return fail(fmt.Errorf("%w: block %d: transaction %s spends missing output %s", ErrInsufficientFunds,
+					block.Index, tx.TXID, key))
+			}
+			if !created[key] {
+				undo.spent[key] = out
+			}
+			delete(created, key)
+			delete(utxo, key)
+		}
+		for i, out := range outputs {
+			key := outpoint(tx.TXID, i)
+			created[key] = true
+			utxo[key] = out
+		}
+	}
+
+	for _, tx := range block.Transactions {
+		for i := 0; i <= len(tx.outputs()); i++ { // one more for the change output of an account-model transaction
+			if key := outpoint(tx.TXID, i); created[key] {
+				undo.created = append(undo.created, key)
+			}
+		}
+	}
+
+	return undo, nil
+}
+
+// FindSpendableOutputs collects unspent outputs owned by the address, in outpoint order, until
+// their total covers amount. Outputs already spent by mempool transactions and coinbase outputs younger
+// than CoinbaseMaturity are skipped, and the outputs created by mempool transactions, such as change,
+// only become spendable once they are mined.
+// Returns the accumulated total, which is less than amount if the address cannot afford it, and the inputs spending them
+func (bc *Blockchain) FindSpendableOutputs(address string, amount int64) (int64, []TXInput) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return spendableOutputs(bc.utxo, address, amount, bc.unspendableOutputsLocked())
+}
+
+// spendableOutputs is FindSpendableOutputs on the UTXO set utxo, skipping the outpoints in exclude instead of
+// the mempool spends and immature coinbase outputs
+func spendableOutputs(utxo map[string]TXOutput, address string, amount int64, exclude map[string]bool) (int64, []TXInput) {
+	keys := make([]string, 0, len(utxo))
+	for key, out := range utxo {
+		if out.Address == address && !exclude[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	total := int64(0)
+	inputs := []TXInput{}
+	for _, key := range keys {
+		if total >= amount {
+			break
+		}
+
+		inputs = append(inputs, parseOutpoint(key))
+		total += utxo[key].Amount
+	}
+
+	return total, inputs
+}
+
+// parseOutpoint splits a UTXO set key created by outpoint back into the input referencing it
+func parseOutpoint(key string) TXInput {
+	separator := strings.LastIndex(key, ":")
+	index, _ := strconv.Atoi(key[separator+1:])
+	return TXInput{TXID: key[:separator], Output: index}
+}
+
+// mempoolSpentLocked returns the outpoints spent by the inputs of mempool transactions
+func (bc *Blockchain) mempoolSpentLocked() map[string]bool {
+	spent := map[string]bool{}
+	for _, tx := range bc.Transactions {
+		for _, in := range tx.Inputs {
+			spent[outpoint(in.TXID, in.Output)] = true
+		}
+	}
+
+	return spent
+}
+
+// fillUTXOTransactionLocked selects spendable outputs of the sender covering the amount and fee of an
+// account-style transaction and sets its inputs and outputs: the payments to the recipients and, if any,
+// the change returned to the sender. Returns ErrInsufficientFunds if the sender cannot afford it
+func (bc *Blockchain) fillUTXOTransactionLocked(tx *Transaction) error {
+	needed := tx.Amount + tx.Fee
+	total, inputs := spendableOutputs(bc.utxo, tx.Sender, needed, bc.unspendableOutputsLocked())
+	if total < needed {
+		return fmt.Errorf("%w: %s has %s spendable, needs %s", ErrInsufficientFunds, tx.Sender, formatAmount(total), formatAmount(needed))
+	}
+
+	tx.Inputs = inputs
+	tx.Outputs = tx.payments()
+	if change := total - needed; change > 0 {
+		tx.Outputs = append(tx.Outputs, TXOutput{Address: tx.Sender, Amount: change})
+	}
+
+	return nil
+}
+
+// validateUTXOInputsLocked checks that every input of the transaction spends a distinct unspent output
+// owned by the sender which no mempool transaction spends yet and which is not an immature coinbase output,
+// and that the inputs cover the outputs plus the fee
+func (bc *Blockchain) validateUTXOInputsLocked(tx Transaction) error {
+	if len(tx.Inputs) == 0 {
+		return fmt.Errorf("%w: transaction %s has no inputs", ErrInsufficientFunds, tx.TXID)
+	}
+
+	mempoolSpent := bc.mempoolSpentLocked()
+	immature := bc.immatureOutputsLocked()
+	seen := map[string]bool{}
+	inputTotal := int64(0)
+	for _, in := range tx.Inputs {
+		key := outpoint(in.TXID, in.Output)
+		out, ok := bc.utxo[key]
+		if !ok || mempoolSpent[key] || seen[key] {
+			return fmt.Errorf("%w: output %s is not spendable", ErrInsufficientFunds, key)
+		}
+		if out.Address != tx.Sender {
+			return fmt.Errorf("%w: output %s is not owned by %s", ErrInvalidTransaction, key, tx.Sender)
+		}
+		if immature[key] {
+			return fmt.Errorf("%w: coinbase output %s needs %d confirmations to be spent", ErrInsufficientFunds, key, bc.CoinbaseMaturity)
+		}
+
+		seen[key] = true
+		inputTotal += out.Amount
+	}
+
+	outputTotal := int64(0)
+	for _, out := range tx.outputs() {
+		outputTotal += out.Amount
+	}
+
+	if inputTotal < outputTotal+tx.Fee {
+		return fmt.Errorf("%w: inputs of %s do not cover outputs of %s plus fee %s", ErrInsufficientFunds,
+			formatAmount(inputTotal), formatAmount(outputTotal), formatAmount(tx.Fee))
+	}
+
+	return nil
+}
+
+// inputsUnspentLocked reports whether every input of the transaction spends an output in the UTXO set
+// which is not in spent, not an immature coinbase output and not spent twice by the transaction itself
+func (bc *Blockchain) inputsUnspentLocked(tx Transaction, spent map[string]bool) bool {
+	immature := bc.immatureOutputsLocked()
+	seen := map[string]bool{}
+	for _, in := range tx.Inputs {
+		key := outpoint(in.TXID, in.Output)
+		if _, ok := bc.utxo[key]; !ok || spent[key] || immature[key] || seen[key] {
+			return false
+		}
+		seen[key] = true
+	}
+
+	return true
+}
+
+// utxoBalanceLocked sums the unspent outputs owned by the address, leaving out immature coinbase outputs
+func (bc *Blockchain) utxoBalanceLocked(address string) int64 {
+	immature := bc.immatureOutputsLocked()
+	balance := int64(0)
+	for key, out := range bc.utxo {
+		if out.Address == address && !immature[key] {
+			balance += out.Amount
+		}
+	}
+
+	return balance
+}
+
+// replayBlockUTXOLocked checks the inputs of the block's transactions against the unspent outputs replayed
+// up to the block before it and applies the block to them. Every input must spend a distinct replayed output,
+// owned by the sender and not an immature coinbase output, and the inputs of a transaction must cover its
+// outputs plus the fee, like validateUTXOInputsLocked requires of mempool transactions.
+// Returns an error wrapping ErrInvalidChain otherwise
+func (bc *Blockchain) replayBlockUTXOLocked(block Block, replay *chainReplay) error {
+	spent := map[string]bool{}
+	for _, tx := range block.Transactions {
+		inputTotal := int64(0)
+		for _, in := range tx.Inputs {
+			key := outpoint(in.TXID, in.Output)
+			out, ok := replay.utxo[key]
+			switch {
+			case !ok || spent[key]:
+				return fmt.Errorf("%w: block %d: transaction %s spends output %s which is not unspent", ErrInvalidChain, block.Index, tx.TXID, key)
+			case out.Address != tx.Sender:
+				return fmt.Errorf("%w: block %d: transaction %s spends output %s not owned by %s", ErrInvalidChain, block.Index, tx.TXID, key, tx.Sender)
+			}
+			if index, ok := replay.coinbase[key]; ok && !bc.coinbaseMatureAtLocked(index, block.Index-1) {
+				return fmt.Errorf("%w: block %d: transaction %s spends coinbase output %s of block %d before it matures", ErrInvalidChain,
+					block.Index, tx.TXID, key, index)
+			}
+			if out.Amount > math.MaxInt64-inputTotal {
+				return fmt.Errorf("%w: block %d: inputs of transaction %s overflow", ErrInvalidChain, block.Index, tx.TXID)
+			}
+
+			spent[key] = true
+			inputTotal += out.Amount
+		}
+
+		if len(tx.Inputs) > 0 {
+			outputTotal := tx.Fee
+			for _, out := range tx.outputs() {
+				if out.Amount > math.MaxInt64-outputTotal {
+					return fmt.Errorf("%w: block %d: outputs of transaction %s overflow", ErrInvalidChain, block.Index, tx.TXID)
+				}
+				outputTotal += out.Amount
+			}
+			if inputTotal < outputTotal {
+				return fmt.Errorf("%w: block %d: inputs of transaction %s of %s do not cover outputs plus fee of %s", ErrInvalidChain,
+					block.Index, tx.TXID, formatAmount(inputTotal), formatAmount(outputTotal))
+			}
+		}
+	}
+
+	if _, err := applyBlockToUTXO(replay.utxo, block); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidChain, err)
+	}
+	for _, tx := range block.Transactions {
+		if tx.Sender == coinbaseSender {
+			for j := range tx.outputs() {
+				replay.coinbase[outpoint(tx.TXID, j)] = block.Index
+			}
+		}
+	}
+
+	return nil
+}
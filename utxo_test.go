@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// utxoTestTransaction returns a transaction from sender spending inputs, paying amount to bob and the change
+// to the sender
+func utxoTestTransaction(bc *Blockchain, sender string, inputs []TXInput, amount, change, fee int64) Transaction {
+	tx := Transaction{Sender: sender, Recipient: "bob", Amount: amount, Fee: fee, Nonce: 1, Inputs: inputs,
+		Outputs: []TXOutput{{Address: "bob", Amount: amount}}}
+	if change > 0 {
+		tx.Outputs = append(tx.Outputs, TXOutput{Address: sender, Amount: change})
+	}
+	tx.TXID = generateTransactionID(bc.hasher(), tx)
+	return tx
+}
+
+// genesisOutput returns the input spending the genesis allocation of address
+func genesisOutput(t *testing.T, bc *Blockchain, address string) TXInput {
+	t.Helper()
+	for _, tx := range bc.Chain[0].Transactions {
+		if tx.Recipient == address {
+			return TXInput{TXID: tx.TXID, Output: 0}
+		}
+	}
+	t.Fatalf("no genesis allocation for %s", address)
+	return TXInput{}
+}
+
+func TestBlockInputsAreValidated(t *testing.T) {
+	tests := []struct {
+		name    string
+		txs     func(bc *Blockchain) []Transaction
+		wantErr bool
+	}{
+		{"valid spend", func(bc *Blockchain) []Transaction {
+			return []Transaction{utxoTestTransaction(bc, "alice", []TXInput{genesisOutput(t, bc, "alice")}, 60, 39, 1)}
+		}, false},
+		{"missing output", func(bc *Blockchain) []Transaction {
+			return []Transaction{utxoTestTransaction(bc, "alice", []TXInput{{TXID: "missing", Output: 0}}, 60, 0, 0)}
+		}, true},
+		{"output of another address", func(bc *Blockchain) []Transaction {
+			return []Transaction{utxoTestTransaction(bc, "alice", []TXInput{genesisOutput(t, bc, "carol")}, 60, 0, 0)}
+		}, true},
+		{"output spent twice in a transaction", func(bc *Blockchain) []Transaction {
+			in := genesisOutput(t, bc, "alice")
+			return []Transaction{utxoTestTransaction(bc, "alice", []TXInput{in, in}, 90, 110, 0)}
+		}, true},
+		{"output spent twice in a block", func(bc *Blockchain) []Transaction {
+			in := genesisOutput(t, bc, "alice")
+			first := utxoTestTransaction(bc, "alice", []TXInput{in}, 50, 50, 0)
+			second := utxoTestTransaction(bc, "alice", []TXInput{in}, 40, 60, 0)
+			second.Nonce = 2
+			second.TXID = generateTransactionID(bc.hasher(), second)
+			return []Transaction{first, second}
+		}, true},
+		{"inputs below outputs plus fee", func(bc *Blockchain) []Transaction {
+			return []Transaction{utxoTestTransaction(bc, "alice", []TXInput{genesisOutput(t, bc, "alice")}, 60, 40, 1)}
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := newTestBlockchain(t, map[string]int64{"alice": 100, "carol": 100})
+			bc.EnableUTXO()
+
+			transactions := append([]Transaction{testCoinbase(bc, "miner", bc.BlockReward)}, tt.txs(bc)...)
+			err := bc.SubmitMinedBlock(sealTestBlock(t, bc, transactions))
+			if tt.wantErr && !errors.Is(err, ErrInvalidChain) {
+				t.Fatalf("SubmitMinedBlock() error = %v, want ErrInvalidChain", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("SubmitMinedBlock() error = %v", err)
+			}
+			if want := int64(100); tt.wantErr && bc.GetBalance("alice") != want {
+				t.Errorf("alice has %d units after a rejected block, want %d", bc.GetBalance("alice"), want)
+			}
+		})
+	}
+}
+
+func TestBlockSpendingImmatureCoinbaseIsRejected(t *testing.T) {
+	// alice can afford the spend in the account model, only her coinbase output is immature
+	bc := newTestBlockchain(t, map[string]int64{"alice": defaultBlockReward})
+	bc.CoinbaseMaturity = 2
+	bc.EnableUTXO()
+	mined := mineTestBlock(t, bc, "alice")
+
+	coinbase := TXInput{TXID: mined.Transactions[0].TXID, Output: 0}
+	spend := utxoTestTransaction(bc, "alice", []TXInput{coinbase}, bc.BlockReward, 0, 0)
+	block := sealTestBlock(t, bc, []Transaction{testCoinbase(bc, "miner", bc.BlockReward), spend})
+	if err := bc.SubmitMinedBlock(block); !errors.Is(err, ErrInvalidChain) {
+		t.Fatalf("SubmitMinedBlock() error = %v, want ErrInvalidChain", err)
+	}
+
+	mineTestBlock(t, bc, "miner")
+	mineTestBlock(t, bc, "miner")
+	block = sealTestBlock(t, bc, []Transaction{testCoinbase(bc, "miner", bc.BlockReward), spend})
+	if err := bc.SubmitMinedBlock(block); err != nil {
+		t.Fatalf("SubmitMinedBlock() of a mature spend: %v", err)
+	}
+}
+
+func TestApplyBlockToUTXOLeavesSetUnchangedOnError(t *testing.T) {
+	utxo := map[string]TXOutput{"a:0": {Address: "alice", Amount: 10}}
+	block := Block{Transactions: []Transaction{
+		{TXID: "b", Sender: "alice", Recipient: "bob", Amount: 10, Inputs: []TXInput{{TXID: "a", Output: 0}}},
+		{TXID: "c", Sender: "alice", Recipient: "bob", Amount: 10, Inputs: []TXInput{{TXID: "a", Output: 0}}},
+	}}
+
+	if _, err := applyBlockToUTXO(utxo, block); !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("applyBlockToUTXO() error = %v, want ErrInsufficientFunds", err)
+	}
+	if len(utxo) != 1 || utxo["a:0"].Amount != 10 {
+		t.Errorf("UTXO set = %v, want the unchanged set", utxo)
+	}
+}
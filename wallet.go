@@ -0,0 +1,114 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+)
+
+// addressLength is the number of bytes of the public key hash used as an address
+const addressLength = 20
+
+// Wallet wraps an ECDSA key pair on the P-256 curve which owns the address derived from its public key
+// and signs the transactions sent from that address
+type Wallet struct {
+	PrivateKey *ecdsa.PrivateKey
+	PublicKey  []byte // DER encoded public key
+	Hasher     Hasher // computes the signed TXIDs and must match the chain's hasher; nil means SHA256Hasher
+}
+
+// NewWallet generates a new random key pair and returns the wallet holding it
+func NewWallet() (*Wallet, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate key: %w", err)
+	}
+
+	publicKey, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("marshal public key: %w", err)
+	}
+
+	return &Wallet{
+		PrivateKey: privateKey,
+		PublicKey:  publicKey,
+	}, nil
+}
+
+// Address returns the address owned by the wallet, derived from its public key
+func (w *Wallet) Address() string {
+	return addressFromPublicKey(w.PublicKey)
+}
+
+// Sign sets the transaction's TXID from its contents, attaches the wallet's public key
+// and signs the TXID with the wallet's private key. The nonce is covered by the signature,
+// so it must already be set to the sender's next nonce (see Blockchain.NextNonce).
+// Returns an error if the transaction is not sent from the wallet's address or has no nonce
+func (w *Wallet) Sign(tx *Transaction) error {
+	if tx.Sender != w.Address() {
+		return fmt.Errorf("%w: sender %s does not match wallet address %s", ErrInvalidTransaction, tx.Sender, w.Address())
+	}
+	if tx.Nonce == 0 {
+		return fmt.Errorf("%w: nonce must be set before signing", ErrInvalidTransaction)
+	}
+
+	tx.TXID = generateTransactionID(hasherOrDefault(w.Hasher), *tx)
+	digest, err := hex.DecodeString(tx.TXID)
+	if err != nil {
+		return fmt.Errorf("decode transaction ID: %w", err)
+	}
+
+	signature, err := ecdsa.SignASN1(rand.Reader, w.PrivateKey, digest)
+	if err != nil {
+		return fmt.Errorf("sign transaction: %w", err)
+	}
+
+	tx.PublicKey = w.PublicKey
+	tx.Signature = signature
+	return nil
+}
+
+// VerifyTransaction reports whether the transaction is signed with the key owning its sender address
+// and whether the signature is valid for its TXID, which must match the transaction's contents.
+// It assumes TXIDs computed with the default SHA256Hasher
+func VerifyTransaction(tx Transaction) bool {
+	return verifyTransaction(SHA256Hasher{}, tx)
+}
+
+// verifyTransaction is VerifyTransaction for TXIDs computed with the hasher h
+func verifyTransaction(h Hasher, tx Transaction) bool {
+	if len(tx.Signature) == 0 || len(tx.PublicKey) == 0 {
+		return false
+	}
+
+	if tx.TXID != generateTransactionID(h, tx) || tx.Sender != addressFromPublicKey(tx.PublicKey) {
+		return false
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(tx.PublicKey)
+	if err != nil {
+		return false
+	}
+	publicKey, ok := parsed.(*ecdsa.PublicKey)
+	if !ok {
+		return false
+	}
+
+	digest, err := hex.DecodeString(tx.TXID)
+	if err != nil {
+		return false
+	}
+
+	return ecdsa.VerifyASN1(publicKey, digest, tx.Signature)
+}
+
+// addressFromPublicKey derives an address as the Base58Check encoding (see EncodeAddress) of the first bytes
+// of the SHA-256 hash of a DER encoded public key
+func addressFromPublicKey(publicKey []byte) string {
+	hash := sha256.Sum256(publicKey)
+	return EncodeAddress(hash[:addressLength])
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// signedTestTransaction returns a transaction from a new wallet to bob signed by the wallet, along with the wallet
+func signedTestTransaction(t *testing.T, amount int64) (Transaction, *Wallet) {
+	t.Helper()
+	wallet, err := NewWallet()
+	if err != nil {
+		t.Fatalf("NewWallet(): %v", err)
+	}
+
+	tx := Transaction{Sender: wallet.Address(), Recipient: "bob", Amount: amount, Nonce: 1}
+	if err := wallet.Sign(&tx); err != nil {
+		t.Fatalf("Sign(): %v", err)
+	}
+	return tx, wallet
+}
+
+func TestVerifyTransaction(t *testing.T) {
+	signed, _ := signedTestTransaction(t, 10)
+	other, _ := signedTestTransaction(t, 10)
+
+	tests := []struct {
+		name   string
+		tamper func(tx *Transaction)
+		want   bool
+	}{
+		{"signed", func(tx *Transaction) {}, true},
+		{"unsigned", func(tx *Transaction) { tx.Signature = nil }, false},
+		{"amount changed", func(tx *Transaction) { tx.Amount++ }, false},
+		{"amount changed with new TXID", func(tx *Transaction) {
+			tx.Amount++
+			tx.TXID = generateTransactionID(SHA256Hasher{}, *tx)
+		}, false},
+		{"signature of another transaction", func(tx *Transaction) { tx.Signature = other.Signature }, false},
+		{"key of another address", func(tx *Transaction) { tx.PublicKey = other.PublicKey }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := signed.clone()
+			tt.tamper(&tx)
+			if got := VerifyTransaction(tx); got != tt.want {
+				t.Errorf("VerifyTransaction() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlockSignaturesAreVerified(t *testing.T) {
+	tests := []struct {
+		name              string
+		requireSignatures bool
+		tx                func(t *testing.T) (Transaction, string)
+		wantErr           bool
+	}{
+		{"signed", false, func(t *testing.T) (Transaction, string) {
+			tx, wallet := signedTestTransaction(t, 10)
+			return tx, wallet.Address()
+		}, false},
+		{"forged signature", false, func(t *testing.T) (Transaction, string) {
+			tx, wallet := signedTestTransaction(t, 10)
+			tx.Signature[len(tx.Signature)-1] ^= 1
+			return tx, wallet.Address()
+		}, true},
+		{"unsigned", false, func(t *testing.T) (Transaction, string) {
+			tx := Transaction{Sender: "alice", Recipient: "bob", Amount: 10, Nonce: 1}
+			tx.TXID = generateTransactionID(SHA256Hasher{}, tx)
+			return tx, "alice"
+		}, false},
+		{"unsigned with RequireSignatures", true, func(t *testing.T) (Transaction, string) {
+			tx := Transaction{Sender: "alice", Recipient: "bob", Amount: 10, Nonce: 1}
+			tx.TXID = generateTransactionID(SHA256Hasher{}, tx)
+			return tx, "alice"
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, sender := tt.tx(t)
+			bc := newTestBlockchain(t, map[string]int64{sender: 100})
+			bc.RequireSignatures = tt.requireSignatures
+
+			err := bc.SubmitMinedBlock(sealTestBlock(t, bc, []Transaction{testCoinbase(bc, "miner", bc.BlockReward), tx}))
+			if tt.wantErr && (!errors.Is(err, ErrInvalidChain) || !errors.Is(err, ErrInvalidSignature)) {
+				t.Fatalf("SubmitMinedBlock() error = %v, want ErrInvalidChain and ErrInvalidSignature", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("SubmitMinedBlock() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// webSocketBuffer is the number of new blocks queued for a WebSocket client; blocks arriving while
+// the queue of a slow client is full are dropped for that client, so the feed never blocks mining
+const webSocketBuffer = 16
+
+// webSocketWriteTimeout limits how long writing a single message to a client may take
+const webSocketWriteTimeout = 10 * time.Second
+
+// maxWebSocketMessageSize bounds a message read from a client; the feed ignores client messages,
+// so anything larger than a control frame or a short message closes the connection
+const maxWebSocketMessageSize = 1 << 16
+
+// webSocketUpgrader performs the opening handshake of the feed. The feed is public and read-only, so pages
+// of any origin may subscribe; a rejected handshake is answered with the JSON error of the API
+var webSocketUpgrader = websocket.Upgrader{
+	CheckOrigin: func(*http.Request) bool { return true },
+	Error: func(w http.ResponseWriter, _ *http.Request, status int, reason error) {
+		writeError(w, status, reason)
+	},
+}
+
+// blockFeed fans the blocks reported by OnNewBlock out to the subscribed WebSocket clients
+type blockFeed struct {
+	mu          sync.Mutex
+	subscribers map[chan Block]bool
+}
+
+// newBlockFeed creates a feed receiving every block added to bc
+func newBlockFeed(bc *Blockchain) *blockFeed {
+	feed := &blockFeed{subscribers: map[chan Block]bool{}}
+	bc.OnNewBlock(feed.publish)
+	return feed
+}
+
+// publish queues the block for every subscriber whose queue has room and drops it for the others
+func (f *blockFeed) publish(block Block) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for ch := range f.subscribers {
+		select {
+		case ch <- block:
+		default:
+		}
+	}
+}
+
+// subscribe returns a new buffered channel receiving the published blocks
+func (f *blockFeed) subscribe() chan Block {
+	ch := make(chan Block, webSocketBuffer)
+
+	f.mu.Lock()
+	f.subscribers[ch] = true
+	f.mu.Unlock()
+
+	return ch
+}
+
+// unsubscribe stops publishing to ch
+func (f *blockFeed) unsubscribe(ch chan Block) {
+	f.mu.Lock()
+	delete(f.subscribers, ch)
+	f.mu.Unlock()
+}
+
+// handleWebSocket upgrades the request to a WebSocket connection and sends every new block as a JSON
+// text message until the client disconnects, starting with the first block added once the handshake completed.
+// Messages sent by the client are ignored, pings are answered
+func handleWebSocket(feed *blockFeed) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// subscribed before the handshake response, so no block added after it is missed
+		blocks := feed.subscribe()
+		defer feed.unsubscribe(blocks)
+
+		conn, err := webSocketUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return // the upgrader has responded with the error
+		}
+		defer conn.Close()
+
+		// reading processes the control frames: pings are answered and a close frame is echoed by the
+		// default handlers, after which NextReader fails
+		conn.SetReadLimit(maxWebSocketMessageSize)
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			for {
+				if _, _, err := conn.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+
+		for {
+			select {
+			case <-closed:
+				return
+			case block := <-blocks:
+				data, err := json.Marshal(block)
+				if err != nil {
+					log.Printf("encoding block %d for websocket failed: %v", block.Index, err)
+					continue
+				}
+				conn.SetWriteDeadline(time.Now().Add(webSocketWriteTimeout))
+				if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWebSocketHandshake(t *testing.T) {
+	server := httptest.NewServer(newRouter(newTestBlockchain(t, nil)))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		header     http.Header
+		wantStatus int
+	}{
+		{"plain request", http.Header{}, http.StatusBadRequest},
+		{"unsupported version", http.Header{"Connection": {"Upgrade"}, "Upgrade": {"websocket"},
+			"Sec-Websocket-Version": {"8"}, "Sec-Websocket-Key": {"dGhlIHNhbXBsZSBub25jZQ=="}}, http.StatusBadRequest},
+		{"valid", http.Header{"Connection": {"Upgrade"}, "Upgrade": {"websocket"},
+			"Sec-Websocket-Version": {"13"}, "Sec-Websocket-Key": {"dGhlIHNhbXBsZSBub25jZQ=="}}, http.StatusSwitchingProtocols},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, server.URL+"/ws", nil)
+			req.Header = tt.header
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("GET /ws: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusSwitchingProtocols {
+				// RFC 6455 section 1.3 derives this accept value from the sample key
+				if got := resp.Header.Get("Sec-Websocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+					t.Errorf("Sec-WebSocket-Accept = %q", got)
+				}
+			}
+		})
+	}
+}
+
+func TestWebSocketBroadcastsNewBlocks(t *testing.T) {
+	bc := newTestBlockchain(t, nil)
+	server := httptest.NewServer(newRouter(bc))
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+	clients := make([]*websocket.Conn, 2)
+	for i := range clients {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("Dial(): %v", err)
+		}
+		defer conn.Close()
+		clients[i] = conn
+	}
+
+	mined := mineTestBlock(t, bc, "miner")
+	for i, conn := range clients {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		kind, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("client %d: ReadMessage(): %v", i, err)
+		}
+
+		var block Block
+		if err := json.Unmarshal(data, &block); err != nil {
+			t.Fatalf("client %d: decode block: %v", i, err)
+		}
+		if kind != websocket.TextMessage || !reflect.DeepEqual(block, mined) {
+			t.Errorf("client %d received %+v, want %+v", i, block, mined)
+		}
+	}
+}